@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+// applyCmd applies the haproxy config of a loadbalancer once, e.g. to recover a haproxy
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "renders the haproxy config for a loadbalancer and applies it once with the dataplaneapi",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindFlags(viper.GetViper(), cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return apply(cmd.Context(), viper.GetViper())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
+
+func apply(ctx context.Context, v *viper.Viper) error {
+	if v.GetString("loadbalancerapi.url") == "" {
+		return ErrLBAPIURLRequired
+	}
+
+	if err := validateBaseConfig(v); err != nil {
+		return err
+	}
+
+	if v.GetString("loadbalancer.id") == "" {
+		return ErrLBIDRequired
+	}
+
+	lbID, err := gidx.Parse(v.GetString("loadbalancer.id"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLBIDInvalid, err)
+	}
+
+	settings, err := loadSettings(v)
+	if err != nil {
+		return err
+	}
+
+	configTemplate, err := loadConfigTemplate(v)
+	if err != nil {
+		return err
+	}
+
+	lbClient, err := newLBAPIClient(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	// the manager doesn't manage a loadbalancer, it only applies the requested one
+	mgr := &manager.Manager{
+		Context:                 ctx,
+		Logger:                  logger,
+		LBClient:                lbClient,
+		BaseCfgPath:             v.GetString("haproxy.config.base"),
+		BaseCfgDir:              v.GetString("haproxy.config.base-dir"),
+		BaseCfgProfiles:         v.GetStringMapString("haproxy.config.profiles"),
+		BaseCfgProfileAttribute: manager.ProfileAttribute(v.GetString("haproxy.config.profile-attribute")),
+		Settings:                settings,
+		ConfigTemplate:          configTemplate,
+		CanonicalConfig:         v.GetBool("haproxy.canonical-config"),
+		AnnotateSections:        v.GetBool("haproxy.annotate-sections"),
+	}
+
+	if err := setDataPlaneClient(mgr, v); err != nil {
+		return err
+	}
+
+	return mgr.ApplyForLB(ctx, lbID)
+}
@@ -34,9 +34,12 @@ func init() {
 	checkDataplaneCmd.PersistentFlags().String("dataplane-user-pwd", "adminpwd", "DataplaneAPI user password")
 	viperx.MustBindFlag(viper.GetViper(), "dataplane.user.pwd", checkDataplaneCmd.PersistentFlags().Lookup("dataplane-user-pwd"))
 
-	checkDataplaneCmd.PersistentFlags().String("dataplane-url", "http://127.0.0.1:5555/v2/", "DataplaneAPI base url")
+	checkDataplaneCmd.PersistentFlags().StringSlice("dataplane-url", []string{"http://127.0.0.1:5555/v2/"}, "DataplaneAPI base url, repeat for each haproxy of a group")
 	viperx.MustBindFlag(viper.GetViper(), "dataplane.url", checkDataplaneCmd.PersistentFlags().Lookup("dataplane-url"))
 
+	checkDataplaneCmd.PersistentFlags().Bool("dataplane-insecure-skip-verify", false, "skip verifying the DataplaneAPI tls certificate, e.g. a self-signed one in development, insecure")
+	viperx.MustBindFlag(viper.GetViper(), "dataplane.insecure-skip-verify", checkDataplaneCmd.PersistentFlags().Lookup("dataplane-insecure-skip-verify"))
+
 	checkDataplaneCmd.PersistentFlags().Int("retries", defaultRetryLimit, "Number of attempts to verify connection to DataplaneAPI")
 	viperx.MustBindFlag(viper.GetViper(), "retries", checkDataplaneCmd.PersistentFlags().Lookup("retries"))
 
@@ -45,14 +48,21 @@ func init() {
 }
 
 func checkDataPlane(ctx context.Context, viper *viper.Viper) error {
-	client := dataplaneapi.NewClient(viper.GetString("dataplane.url"))
+	if err := validateDataPlaneURLs(viper); err != nil {
+		return err
+	}
+
+	client := dataplaneapi.NewGroup(viper.GetStringSlice("dataplane.url"),
+		dataplaneapi.WithGroupInsecureSkipVerify(dataPlaneInsecureSkipVerify(viper)),
+	)
 
 	if err := client.WaitForDataPlaneReady(
 		ctx,
 		viper.GetInt("retries"),
 		viper.GetDuration("retry-interval"),
 	); err != nil {
-		logger.Fatalw("dataplane api is not ready", "error", err)
+		logger.Errorw("dataplane api is not ready", "error", err)
+		return err
 	}
 
 	return nil
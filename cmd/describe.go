@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/viperx"
+
+	"go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+// describeCmd summarizes the desired state of a loadbalancer
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "summarizes the ports, pools and origins of a loadbalancer without generating a haproxy config",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viperx.MustBindFlag(viper.GetViper(), "loadbalancerapi.url", cmd.Flags().Lookup("loadbalancerapi-url"))
+		viperx.MustBindFlag(viper.GetViper(), "loadbalancer.id", cmd.Flags().Lookup("loadbalancer-id"))
+		viperx.MustBindFlag(viper.GetViper(), "output", cmd.Flags().Lookup("output"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v := viper.GetViper()
+
+		if v.GetString("loadbalancerapi.url") == "" {
+			return ErrLBAPIURLRequired
+		}
+
+		client, err := newLBAPIClient(cmd.Context(), v)
+		if err != nil {
+			return err
+		}
+
+		return describe(cmd.Context(), cmd.OutOrStdout(), client, v.GetString("loadbalancer.id"), v.GetString("output"))
+	},
+}
+
+// loadBalancerGetter requests a loadbalancer from lbapi
+type loadBalancerGetter interface {
+	GetLoadBalancer(ctx context.Context, id string) (*client.LoadBalancer, error)
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+
+	describeCmd.Flags().String("loadbalancerapi-url", "", "LoadbalancerAPI url")
+	describeCmd.Flags().String("loadbalancer-id", "", "Loadbalancer ID to describe")
+	describeCmd.Flags().String("output", outputText, "Output format (text|json)")
+}
+
+func describe(ctx context.Context, w io.Writer, lbClient loadBalancerGetter, id, format string) error {
+	if format != outputText && format != outputJSON {
+		return ErrOutputFormatInvalid
+	}
+
+	if id == "" {
+		return ErrLBIDRequired
+	}
+
+	lbID, err := gidx.Parse(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLBIDInvalid, err)
+	}
+
+	lb, err := lbClient.GetLoadBalancer(ctx, lbID.String())
+	if err != nil {
+		return err
+	}
+
+	return writeDescription(w, format, manager.Describe(lb))
+}
+
+// writeDescription writes the summary in the requested format, text output being an indented
+// outline of the ports and their pools
+func writeDescription(w io.Writer, format string, summary manager.LoadBalancerSummary) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(summary)
+	}
+
+	if _, err := fmt.Fprintf(w, "loadbalancer %s %q\n", summary.ID, summary.Name); err != nil {
+		return err
+	}
+
+	if summary.Location != "" {
+		if _, err := fmt.Fprintf(w, "  location %s\n", summary.Location); err != nil {
+			return err
+		}
+	}
+
+	for _, port := range summary.Ports {
+		if _, err := fmt.Fprintf(w, "  port %s %q :%d, %d pools\n", port.ID, port.Name, port.Number, len(port.Pools)); err != nil {
+			return err
+		}
+
+		for _, pool := range port.Pools {
+			if _, err := fmt.Fprintf(w, "    pool %s %q %s: %d origins, %d active, %d inactive\n",
+				pool.ID, pool.Name, pool.Protocol, pool.Origins, pool.Active, pool.Inactive); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
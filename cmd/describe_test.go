@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+var describeTestLB = client.LoadBalancer{
+	ID:       "loadbal-test",
+	Name:     "test",
+	Location: client.LocationNode{ID: "lctnloc-test"},
+	Ports: client.Ports{
+		Edges: []client.PortEdges{
+			{
+				Node: client.PortNode{
+					ID:     "loadprt-test",
+					Name:   "ssh-service",
+					Number: 22,
+					Pools: []client.Pool{
+						{
+							ID:       "loadpol-test",
+							Name:     "ssh-service-a",
+							Protocol: "tcp",
+							Origins: client.Origins{
+								Edges: []client.OriginEdges{
+									{Node: client.OriginNode{ID: "loadogn-test1", Target: "1.2.3.4", PortNumber: 2222, Active: true}},
+									{Node: client.OriginNode{ID: "loadogn-test2", Target: "1.2.3.4", PortNumber: 222, Active: true}},
+									{Node: client.OriginNode{ID: "loadogn-test3", Target: "4.3.2.1", PortNumber: 2222, Active: false}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestDescribe(t *testing.T) {
+	lbClient := mock.LBAPIClient{
+		DoGetLoadBalancer: func(ctx context.Context, id string) (*client.LoadBalancer, error) {
+			return &describeTestLB, nil
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		require.NoError(t, describe(context.Background(), buf, lbClient, "loadbal-test", outputText))
+
+		expected := `loadbalancer loadbal-test "test"
+  location lctnloc-test
+  port loadprt-test "ssh-service" :22, 1 pools
+    pool loadpol-test "ssh-service-a" tcp: 3 origins, 2 active, 1 inactive
+`
+
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		require.NoError(t, describe(context.Background(), buf, lbClient, "loadbal-test", outputJSON))
+
+		decoded := manager.LoadBalancerSummary{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+
+		require.Len(t, decoded.Ports, 1)
+		require.Len(t, decoded.Ports[0].Pools, 1)
+		assert.Equal(t, manager.PoolSummary{
+			ID:       "loadpol-test",
+			Name:     "ssh-service-a",
+			Protocol: "tcp",
+			Origins:  3,
+			Active:   2,
+			Inactive: 1,
+		}, decoded.Ports[0].Pools[0])
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		err := describe(context.Background(), &bytes.Buffer{}, lbClient, "loadbal-test", "yaml")
+		assert.ErrorIs(t, err, ErrOutputFormatInvalid)
+	})
+
+	t.Run("invalid loadbalancer id", func(t *testing.T) {
+		err := describe(context.Background(), &bytes.Buffer{}, lbClient, "test", outputText)
+		assert.ErrorIs(t, err, ErrLBIDInvalid)
+	})
+
+	t.Run("lbapi failure", func(t *testing.T) {
+		// nolint:goerr113
+		errLBAPI := errors.New("lbapi unavailable")
+
+		failing := mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*client.LoadBalancer, error) {
+				return nil, errLBAPI
+			},
+		}
+
+		err := describe(context.Background(), &bytes.Buffer{}, failing, "loadbal-test", outputText)
+		assert.ErrorIs(t, err, errLBAPI)
+	})
+}
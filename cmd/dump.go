@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"go.uber.org/zap"
+)
+
+// configDumper is the manager state logged on a dump signal
+type configDumper interface {
+	CurrentConfig() string
+}
+
+// startConfigDump logs the applied config and the subscribed topics each time the process receives
+// a dump signal, until the context is done. Platforms without a dump signal don't dump.
+func startConfigDump(ctx context.Context, logger *zap.SugaredLogger, mgr configDumper, topics func() []string) {
+	// notifying without signals would relay all of them
+	if len(dumpSignals) == 0 {
+		return
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, dumpSignals...)
+
+	go func() {
+		defer signal.Stop(c)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c:
+				logger.Infow("config dump", "topics", topics(), "config", mgr.CurrentConfig())
+			}
+		}
+	}()
+}
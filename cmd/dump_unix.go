@@ -0,0 +1,11 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals make the manager log its applied config
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type staticConfig string
+
+func (c staticConfig) CurrentConfig() string {
+	return string(c)
+}
+
+func TestStartConfigDump(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topics := func() []string { return []string{"load-balancer"} }
+
+	startConfigDump(ctx, zap.New(core).Sugar(), staticConfig("frontend loadprt-test"), topics)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+	require.Eventually(t, func() bool {
+		return logs.FilterMessage("config dump").Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	fields := logs.FilterMessage("config dump").All()[0].ContextMap()
+
+	assert.Equal(t, "frontend loadprt-test", fields["config"])
+	assert.Equal(t, []interface{}{"load-balancer"}, fields["topics"])
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package cmd
+
+import "os"
+
+// dumpSignals is empty, as windows has no SIGUSR1
+var dumpSignals []os.Signal
@@ -9,7 +9,13 @@ var (
 	ErrNATSAuthRequired = errors.New("env LOADBALANCER_MANAGER_HAPROXY_EVENTS_SUBSCRIBER_NATS_CREDSFILE is required and cannot be empty")
 
 	// ErrHAProxyBaseConfigRequired is returned when the base HAProxy config is missing
-	ErrHAProxyBaseConfigRequired = errors.New("base-haproxy-config is required and cannot be empty")
+	ErrHAProxyBaseConfigRequired = errors.New("base-haproxy-config or base-haproxy-config-dir is required")
+
+	// ErrHAProxyBaseConfigConflict is returned when both a base HAProxy config and a directory of fragments are set
+	ErrHAProxyBaseConfigConflict = errors.New("base-haproxy-config and base-haproxy-config-dir cannot both be set")
+
+	// ErrProfileAttributeInvalid is returned when base config profiles are selected by an unsupported attribute
+	ErrProfileAttributeInvalid = errors.New("base-haproxy-config-profile-attribute must be one of: location, owner, name")
 
 	// ErrLBAPIURLRequired is returned when the LB API url is missing
 	ErrLBAPIURLRequired = errors.New("loadbalancer-api-url is required and cannot be empty")
@@ -19,4 +25,38 @@ var (
 
 	// ErrLBIDInvalid is returned when the loadbalancer gidx is invalid
 	ErrLBIDInvalid = errors.New("loadbalancer-id (gidx) is invalid")
+
+	// ErrOAuth2TokenSource is returned when the oauth2 token source of the loadbalancer api client cannot be created
+	ErrOAuth2TokenSource = errors.New("failed to create oauth2 token source")
+
+	// ErrPollIntervalInvalid is returned when polling without a positive poll interval
+	ErrPollIntervalInvalid = errors.New("poll-interval must be positive with poll-only")
+
+	// ErrAdminAddressRequired is returned when the address of the admin server to query is missing
+	ErrAdminAddressRequired = errors.New("admin-listen is required and cannot be empty")
+
+	// ErrApplyStrategyInvalid is returned when an unsupported apply strategy is requested
+	ErrApplyStrategyInvalid = errors.New("apply-strategy must be one of: raw, runtime")
+
+	// ErrDataPlaneURLRequired is returned when there is no DataplaneAPI url, or one of them is empty
+	ErrDataPlaneURLRequired = errors.New("dataplane-url is required and cannot be empty")
+
+	// ErrDataPlaneQuorumInvalid is returned when the quorum is negative or more DataplaneAPIs than there are urls
+	ErrDataPlaneQuorumInvalid = errors.New("dataplane-quorum must be between 0 and the number of dataplane-url")
+
+	// ErrCrtListsWithGroup is returned when crt-lists are managed for a group of DataplaneAPIs, as
+	// they are only written to the manager's filesystem
+	ErrCrtListsWithGroup = errors.New("managed crt-lists require a single dataplane-url")
+
+	// ErrOutputFormatInvalid is returned when an unsupported output format is requested
+	ErrOutputFormatInvalid = errors.New("output must be one of: text, json")
+
+	// ErrConfigInvalid is returned when the rendered config fails validation
+	ErrConfigInvalid = errors.New("rendered config is invalid")
+
+	// ErrConfigTemplateUnreadable is returned when the config template file cannot be read
+	ErrConfigTemplateUnreadable = errors.New("config-template cannot be read")
+
+	// ErrHAProxySettingsInvalid is returned when the haproxy settings cannot be decoded
+	ErrHAProxySettingsInvalid = errors.New("haproxy.settings is invalid")
 )
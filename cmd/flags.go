@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// flagKeys are the viper keys of the flags shared by the commands generating configs
+var flagKeys = []struct {
+	name string
+	key  string
+}{
+	{"loadbalancerapi-url", "loadbalancerapi.url"},
+	{"loadbalancerapi-failure-threshold", "loadbalancerapi.failure-threshold"},
+	{"loadbalancerapi-failure-cooldown", "loadbalancerapi.failure-cooldown"},
+	{"loadbalancerapi-lenient", "loadbalancerapi.lenient"},
+	{"loadbalancer-id", "loadbalancer.id"},
+	{"base-haproxy-config", "haproxy.config.base"},
+	{"base-haproxy-config-dir", "haproxy.config.base-dir"},
+	{"base-haproxy-config-profile", "haproxy.config.profiles"},
+	{"base-haproxy-config-profile-attribute", "haproxy.config.profile-attribute"},
+	{"config-template", "haproxy.config.template"},
+	{"allow-raw-directives", "haproxy.allow-raw-directives"},
+	{"descriptions", "haproxy.descriptions"},
+	{"defaults-mode", "haproxy.defaults-mode"},
+	{"overwrite-base-sections", "haproxy.overwrite-base-sections"},
+	{"dontlognull", "haproxy.dontlognull"},
+	{"log-format", "haproxy.log-format"},
+	{"log-format-sd", "haproxy.log-format-sd"},
+	{"omit-single-server-balance", "haproxy.omit-single-server-balance"},
+	{"placeholder-backends", "haproxy.placeholder-backends"},
+	{"dual-stack", "haproxy.dual-stack"},
+	{"metrics-port", "haproxy.metrics-port"},
+	{"region", "haproxy.region"},
+	{"tls-cert-dir", "haproxy.tls.cert-dir"},
+	{"nbthread", "haproxy.global.nbthread"},
+	{"nbproc", "haproxy.global.nbproc"},
+	{"tune-bufsize", "haproxy.global.tune-bufsize"},
+	{"tune-ssl-cachesize", "haproxy.global.tune-ssl-cachesize"},
+	{"tune-ssl-default-dh-param", "haproxy.global.tune-ssl-default-dh-param"},
+	{"stats-socket", "haproxy.global.stats-socket"},
+	{"canonical-config", "haproxy.canonical-config"},
+	{"annotate-sections", "haproxy.annotate-sections"},
+	{"dataplane-user-name", "dataplane.user.name"},
+	{"dataplane-user-pwd", "dataplane.user.pwd"},
+	{"dataplane-url", "dataplane.url"},
+	{"dataplane-insecure-skip-verify", "dataplane.insecure-skip-verify"},
+	{"dataplane-quorum", "dataplane.quorum"},
+	{"output", "output"},
+}
+
+// addConfigFlags adds the flags of the loadbalancer api, the base config and the settings read
+// by loadSettings, which are needed to generate a loadbalancer's config
+func addConfigFlags(flags *pflag.FlagSet) {
+	flags.String("loadbalancerapi-url", "", "LoadbalancerAPI url")
+	flags.Int("loadbalancerapi-failure-threshold", defaultLBAPIFailureThreshold, "LoadbalancerAPI consecutive failures before failing fast (0 disables)")
+	flags.Duration("loadbalancerapi-failure-cooldown", defaultLBAPIFailureCooldown, "LoadbalancerAPI fail fast period after reaching the failure threshold")
+	flags.Bool("loadbalancerapi-lenient", false, "Use loadbalancers the LoadbalancerAPI resolved only partially, logging the errors as warnings")
+	flags.String("loadbalancer-id", "", "Loadbalancer ID to generate the config for")
+	flags.String("base-haproxy-config", "", "Base config for haproxy")
+	flags.String("base-haproxy-config-dir", "", "Directory of base config fragments for haproxy, concatenated in sorted order")
+	flags.StringToString("base-haproxy-config-profile", map[string]string{}, "base config for haproxy by profile, as value=path, used for loadbalancers whose profile attribute has the value")
+	flags.String("base-haproxy-config-profile-attribute", string(manager.ProfileAttributeLocation), "loadbalancer attribute selecting its base config profile: location, owner or name")
+	flags.String("config-template", "", "go template appended to the generated haproxy config, rendered with the loadbalancer")
+	flags.Bool("allow-raw-directives", false, "allow raw haproxy directives in port and pool settings, which bypass validation")
+	flags.Bool("descriptions", false, "describe generated frontends and backends with their port and pool names")
+	flags.String("defaults-mode", "", "manage the mode of the base config's defaults: tcp, http, or predominant for the mode of most ports (empty leaves it untouched)")
+	flags.Bool("overwrite-base-sections", false, "replace frontends and backends of the base config with the labels of generated ones, instead of failing")
+	flags.Bool("dontlognull", false, "set option dontlognull on generated frontends, so connections without data aren't logged")
+	flags.String("log-format", "", "log-format of generated frontends, rendered quoted (empty keeps the defaults)")
+	flags.String("log-format-sd", "", "log-format-sd of generated frontends, rendered quoted (empty keeps the defaults)")
+	flags.Bool("omit-single-server-balance", false, "leave the balance directive out of backends with a single active server, where it has no effect")
+	flags.Bool("placeholder-backends", false, "reject connections to backends without pools, with a 503 in http mode, instead of leaving clients waiting")
+	flags.Bool("dual-stack", false, "bind ports without an address family on both ipv4 and ipv6")
+	flags.Int64("metrics-port", 0, "generate a frontend serving haproxy's prometheus metrics at /metrics on the port (0 disables)")
+	flags.String("region", "", "only add servers for origins in the region, a location ID (empty includes all origins)")
+	flags.String("tls-cert-dir", "", "directory of the certificates of managed crt-lists given as relative paths")
+	flags.Int64("nbthread", 0, "haproxy nbthread, set in the global section of the base config (0 keeps the base config)")
+	flags.Int64("nbproc", 0, "haproxy nbproc for legacy multi-process builds, added to the global section (0 runs a single process)")
+	flags.Int64("tune-bufsize", 0, "haproxy tune.bufsize, set in the global section of the base config (0 keeps the base config)")
+	flags.Int64("tune-ssl-cachesize", 0, "haproxy tune.ssl.cachesize, set in the global section of the base config (0 keeps the base config)")
+	flags.Int64("tune-ssl-default-dh-param", 0, "haproxy tune.ssl.default-dh-param, set in the global section of the base config (0 keeps the base config)")
+	flags.Bool("stats-socket", false, "ensure an admin level stats socket at "+haproxyconfig.StatsSocketPath+" in the global section, for the runtime api")
+	flags.Bool("canonical-config", false, "normalize generated configs to a canonical form with stable section and server order, for minimal diffs")
+	flags.Bool("annotate-sections", false, "prefix generated frontends and backends with a comment naming the port or pool they were generated for")
+}
+
+// addDataPlaneFlags adds the flags of the dataplaneapis configs are checked or applied with
+func addDataPlaneFlags(flags *pflag.FlagSet) {
+	flags.String("dataplane-user-name", "haproxy", "DataplaneAPI user name")
+	flags.String("dataplane-user-pwd", "adminpwd", "DataplaneAPI user password")
+	flags.StringSlice("dataplane-url", []string{"http://127.0.0.1:5555/v2/"}, "DataplaneAPI base url, repeat for each haproxy of a group")
+	flags.Bool("dataplane-insecure-skip-verify", false, "skip verifying the DataplaneAPI tls certificate, e.g. a self-signed one in development, insecure")
+	flags.Int("dataplane-quorum", 0, "number of DataplaneAPIs which must accept a config when there are several (0 requires all)")
+}
+
+// bindFlags binds the shared flags of the set to their viper keys, skipping the ones it doesn't
+// have. The keys are shared by several commands, so commands other than run bind in PreRun,
+// without one command's flags overriding another's.
+func bindFlags(v *viper.Viper, flags *pflag.FlagSet) {
+	for _, f := range flagKeys {
+		if flag := flags.Lookup(f.name); flag != nil {
+			viperx.MustBindFlag(v, f.key, flag)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/viperx"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/admin"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+// historyCmd prints the recent config applies of a running manager
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "prints the recent config applies of a running manager, with their diffs, from its admin server",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viperx.MustBindFlag(viper.GetViper(), "admin.listen", cmd.Flags().Lookup("admin-listen"))
+		viperx.MustBindFlag(viper.GetViper(), "admin.token", cmd.Flags().Lookup("admin-token"))
+		viperx.MustBindFlag(viper.GetViper(), "output", cmd.Flags().Lookup("output"))
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v := viper.GetViper()
+
+		if v.GetString("admin.listen") == "" {
+			return ErrAdminAddressRequired
+		}
+
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return err
+		}
+
+		client := admin.NewClient(v.GetString("admin.listen"), v.GetString("admin.token"))
+
+		return history(cmd.Context(), cmd.OutOrStdout(), client, limit, v.GetString("output"))
+	},
+}
+
+// historyGetter requests the recent config applies of a manager
+type historyGetter interface {
+	History(ctx context.Context, limit int) ([]manager.ApplyRecord, error)
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("admin-listen", "", "address of the manager's admin http server, e.g. 127.0.0.1:8090 or unix:/run/lbm/admin.sock")
+	historyCmd.Flags().String("admin-token", "", "bearer token of the admin http server")
+	historyCmd.Flags().Int("limit", 0, "number of most recent applies to print (0 prints all kept)")
+	historyCmd.Flags().String("output", outputText, "Output format (text|json)")
+}
+
+func history(ctx context.Context, w io.Writer, client historyGetter, limit int, format string) error {
+	if format != outputText && format != outputJSON {
+		return ErrOutputFormatInvalid
+	}
+
+	records, err := client.History(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	return writeHistory(w, format, records)
+}
+
+// writeHistory writes the applies in the requested format, text output being a line per apply
+// followed by its indented diff
+func writeHistory(w io.Writer, format string, records []manager.ApplyRecord) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(records)
+	}
+
+	for _, r := range records {
+		result := "applied"
+		if r.Error != "" {
+			result = "failed: " + r.Error
+		}
+
+		line := fmt.Sprintf("%s %s %s", r.Time.Format(time.RFC3339), r.LoadBalancerID, result)
+
+		if r.CorrelationID != "" {
+			line += " (correlation id " + r.CorrelationID + ")"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		if r.Diff == "" {
+			continue
+		}
+
+		for _, d := range strings.Split(r.Diff, "\n") {
+			if _, err := fmt.Fprintln(w, "  "+d); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
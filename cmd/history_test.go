@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+type historyFunc func(ctx context.Context, limit int) ([]manager.ApplyRecord, error)
+
+func (f historyFunc) History(ctx context.Context, limit int) ([]manager.ApplyRecord, error) {
+	return f(ctx, limit)
+}
+
+func TestHistory(t *testing.T) {
+	client := historyFunc(func(ctx context.Context, limit int) ([]manager.ApplyRecord, error) {
+		assert.Equal(t, 2, limit)
+
+		return []manager.ApplyRecord{
+			{
+				Time:           time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC),
+				LoadBalancerID: "loadbal-test",
+				CorrelationID:  "abc",
+				Diff:           "-  server loadogn-test1 1.2.3.4:2222 check port 2222\n+  server loadogn-test1 1.2.3.4:2222 check port 2222 disabled",
+			},
+			{
+				Time:           time.Date(2023, 9, 1, 10, 5, 0, 0, time.UTC),
+				LoadBalancerID: "loadbal-test",
+				Error:          "post failed",
+			},
+		}, nil
+	})
+
+	buf := &bytes.Buffer{}
+
+	require.NoError(t, history(context.Background(), buf, client, 2, outputText))
+
+	expected := `2023-09-01T10:00:00Z loadbal-test applied (correlation id abc)
+  -  server loadogn-test1 1.2.3.4:2222 check port 2222
+  +  server loadogn-test1 1.2.3.4:2222 check port 2222 disabled
+2023-09-01T10:05:00Z loadbal-test failed: post failed
+`
+
+	assert.Equal(t, expected, buf.String())
+
+	assert.ErrorIs(t, history(context.Background(), buf, client, 2, "yaml"), ErrOutputFormatInvalid)
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// renderCmd renders the haproxy config for a loadbalancer
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "renders the haproxy config for a loadbalancer without applying it",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindFlags(viper.GetViper(), cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return render(cmd.Context(), cmd.OutOrStdout(), viper.GetViper(), false)
+	},
+}
+
+// validateCmd renders the haproxy config for a loadbalancer and validates it with the dataplaneapi
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "renders the haproxy config for a loadbalancer and validates it with the dataplaneapi",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		bindFlags(viper.GetViper(), cmd.Flags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return render(cmd.Context(), cmd.OutOrStdout(), viper.GetViper(), true)
+	},
+}
+
+// renderResult is the machine-readable result of the render and validate commands
+type renderResult struct {
+	LoadBalancerID string       `json:"loadbalancerID"`
+	Config         string       `json:"config"`
+	Valid          bool         `json:"valid"`
+	Diagnostics    []diagnostic `json:"diagnostics"`
+}
+
+// diagnostic describes a problem found in a rendered config
+type diagnostic struct {
+	Section string `json:"section,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	rootCmd.AddCommand(validateCmd)
+
+	for _, cmd := range []*cobra.Command{renderCmd, validateCmd, applyCmd} {
+		addConfigFlags(cmd.Flags())
+	}
+
+	for _, cmd := range []*cobra.Command{renderCmd, validateCmd} {
+		cmd.Flags().String("output", outputText, "Output format (text|json)")
+	}
+
+	for _, cmd := range []*cobra.Command{validateCmd, applyCmd} {
+		addDataPlaneFlags(cmd.Flags())
+	}
+}
+
+func render(ctx context.Context, w io.Writer, v *viper.Viper, validate bool) error {
+	format := v.GetString("output")
+	if format != outputText && format != outputJSON {
+		return ErrOutputFormatInvalid
+	}
+
+	if v.GetString("loadbalancerapi.url") == "" {
+		return ErrLBAPIURLRequired
+	}
+
+	if err := validateBaseConfig(v); err != nil {
+		return err
+	}
+
+	if v.GetString("loadbalancer.id") == "" {
+		return ErrLBIDRequired
+	}
+
+	lbID, err := gidx.Parse(v.GetString("loadbalancer.id"))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLBIDInvalid, err)
+	}
+
+	settings, err := loadSettings(v)
+	if err != nil {
+		return err
+	}
+
+	configTemplate, err := loadConfigTemplate(v)
+	if err != nil {
+		return err
+	}
+
+	lbClient, err := newLBAPIClient(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	mgr := &manager.Manager{
+		Context:                 ctx,
+		Logger:                  logger,
+		LBClient:                lbClient,
+		ManagedLBID:             lbID,
+		BaseCfgPath:             v.GetString("haproxy.config.base"),
+		BaseCfgDir:              v.GetString("haproxy.config.base-dir"),
+		BaseCfgProfiles:         v.GetStringMapString("haproxy.config.profiles"),
+		BaseCfgProfileAttribute: manager.ProfileAttribute(v.GetString("haproxy.config.profile-attribute")),
+		Settings:                settings,
+		ConfigTemplate:          configTemplate,
+		CanonicalConfig:         v.GetBool("haproxy.canonical-config"),
+		AnnotateSections:        v.GetBool("haproxy.annotate-sections"),
+	}
+
+	cfg, err := mgr.RenderConfig()
+	if err != nil {
+		return err
+	}
+
+	result := renderResult{
+		LoadBalancerID: lbID.String(),
+		Config:         cfg,
+		Valid:          true,
+		Diagnostics:    lintConfig(cfg),
+	}
+
+	if validate {
+		if err := setDataPlaneClient(mgr, v); err != nil {
+			return err
+		}
+
+		if err := mgr.DataPlaneClient.CheckConfig(ctx, cfg); err != nil {
+			result.Valid = false
+			result.Diagnostics = append(result.Diagnostics, checkDiagnostics(cfg, err)...)
+		}
+	}
+
+	if err := writeRenderResult(w, format, result); err != nil {
+		return err
+	}
+
+	if !result.Valid {
+		return ErrConfigInvalid
+	}
+
+	return nil
+}
+
+// writeRenderResult writes the result in the requested format. Text output is the config
+// followed by the diagnostics as comments, so the output remains a loadable haproxy config.
+func writeRenderResult(w io.Writer, format string, result renderResult) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(result)
+	}
+
+	if _, err := fmt.Fprintln(w, strings.TrimSpace(result.Config)); err != nil {
+		return err
+	}
+
+	for _, d := range result.Diagnostics {
+		// messages from haproxy span several lines, each is commented
+		if _, err := fmt.Fprintf(w, "# %s\n", strings.ReplaceAll(strings.TrimSpace(d.String()), "\n", "\n# ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String formats the diagnostic for text output
+func (d diagnostic) String() string {
+	if d.Section == "" && d.Line > 0 {
+		return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+	}
+
+	if d.Section == "" {
+		return d.Message
+	}
+
+	return fmt.Sprintf("%s (line %d): %s", d.Section, d.Line, d.Message)
+}
+
+// checkDiagnostics returns the diagnostics for a config the dataplaneapi rejected. Each error
+// haproxy reported at a line of the config is at that line and its section, the rest of haproxy's
+// output is kept in a diagnostic without a line.
+func checkDiagnostics(cfg string, err error) []diagnostic {
+	var validationErr *dataplaneapi.DataPlaneValidationError
+
+	if !errors.As(err, &validationErr) {
+		return []diagnostic{{Message: err.Error()}}
+	}
+
+	diags := []diagnostic{}
+	unlocated := []string{}
+
+	for _, text := range strings.Split(validationErr.Message, "\n") {
+		text = strings.TrimSpace(text)
+
+		line := dataplaneapi.ConfigLine(text)
+
+		switch {
+		case text == "":
+			continue
+		case line > 0:
+			diags = append(diags, diagnostic{Section: sectionAt(cfg, line), Line: line, Message: text})
+		default:
+			unlocated = append(unlocated, text)
+		}
+	}
+
+	if len(unlocated) > 0 {
+		diags = append(diags, diagnostic{Message: strings.Join(unlocated, "\n")})
+	}
+
+	if len(diags) == 0 {
+		return []diagnostic{{Message: err.Error()}}
+	}
+
+	return diags
+}
+
+// sectionAt returns the header of the config section the line is in, or "" when it's before the
+// first section
+func sectionAt(cfg string, line int) string {
+	section := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(cfg))
+
+	for n := 1; n <= line && scanner.Scan(); n++ {
+		text := scanner.Text()
+
+		if strings.TrimSpace(text) != "" && !strings.HasPrefix(text, " ") && !strings.HasPrefix(text, "\t") && !strings.HasPrefix(text, "#") {
+			section = strings.TrimSpace(text)
+		}
+	}
+
+	return section
+}
+
+// lintConfig reports problems in a rendered config which haproxy accepts but are likely mistakes
+func lintConfig(cfg string) []diagnostic {
+	diags := []diagnostic{}
+
+	var (
+		section     string
+		sectionLine int
+		servers     int
+	)
+
+	checkBackend := func() {
+		if strings.HasPrefix(section, "backend ") && servers == 0 {
+			diags = append(diags, diagnostic{Section: section, Line: sectionLine, Message: "backend has no servers"})
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(cfg))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+
+		switch {
+		case strings.TrimSpace(text) == "":
+			continue
+		case !strings.HasPrefix(text, " ") && !strings.HasPrefix(text, "\t"):
+			checkBackend()
+
+			section, sectionLine, servers = strings.TrimSpace(text), line, 0
+		case strings.HasPrefix(strings.TrimSpace(text), "server "):
+			servers++
+		}
+	}
+
+	checkBackend()
+
+	return diags
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+)
+
+const testRenderedCfg = `frontend loadprt-test
+  bind ipv4@:22
+  use_backend loadprt-test
+
+backend loadprt-test
+  server loadogn-test1 1.2.3.4:2222 check port 2222
+
+frontend loadprt-empty
+  bind ipv4@:80
+  use_backend loadprt-empty
+
+backend loadprt-empty
+`
+
+func TestLintConfig(t *testing.T) {
+	diags := lintConfig(testRenderedCfg)
+
+	require.Len(t, diags, 1)
+	assert.Equal(t, diagnostic{Section: "backend loadprt-empty", Line: 12, Message: "backend has no servers"}, diags[0])
+}
+
+func TestWriteRenderResult(t *testing.T) {
+	result := renderResult{
+		LoadBalancerID: "loadbal-test",
+		Config:         testRenderedCfg,
+		Valid:          true,
+		Diagnostics:    lintConfig(testRenderedCfg),
+	}
+
+	t.Run("text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		require.NoError(t, writeRenderResult(buf, outputText, result))
+
+		assert.Contains(t, buf.String(), "backend loadprt-test\n  server loadogn-test1")
+		assert.Contains(t, buf.String(), "# backend loadprt-empty (line 12): backend has no servers\n")
+	})
+
+	t.Run("text with a multi-line message", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		invalid := result
+		invalid.Diagnostics = []diagnostic{{Message: "unknown keyword 'bnd'\nFatal errors found in configuration."}}
+
+		require.NoError(t, writeRenderResult(buf, outputText, invalid))
+
+		assert.Contains(t, buf.String(), "\n# unknown keyword 'bnd'\n# Fatal errors found in configuration.\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+
+		require.NoError(t, writeRenderResult(buf, outputJSON, result))
+
+		decoded := renderResult{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+
+		assert.Equal(t, result, decoded)
+	})
+}
+
+func TestCheckDiagnostics(t *testing.T) {
+	t.Run("haproxy errors", func(t *testing.T) {
+		err := &dataplaneapi.DataPlaneValidationError{
+			Code: 400,
+			Message: "[NOTICE]   (1) : haproxy version is 2.8.3\n" +
+				"[ALERT]    (1) : config : parsing [/etc/haproxy/transactions/haproxy.cfg.1:2] : unknown keyword 'bnd' in 'frontend' section; did you mean 'bind' maybe ?\n" +
+				"[ALERT]    (1) : config : parsing [/etc/haproxy/transactions/haproxy.cfg.1:6] : 'server loadogn-test1' : invalid address: '1.2.3.4:2222x'\n" +
+				"[ALERT]    (1) : config : Fatal errors found in configuration.\n",
+			Line: 2,
+		}
+
+		diags := checkDiagnostics(testRenderedCfg, err)
+
+		assert.Equal(t, []diagnostic{
+			{
+				Section: "frontend loadprt-test",
+				Line:    2,
+				Message: "[ALERT]    (1) : config : parsing [/etc/haproxy/transactions/haproxy.cfg.1:2] : unknown keyword 'bnd' in 'frontend' section; did you mean 'bind' maybe ?",
+			},
+			{
+				Section: "backend loadprt-test",
+				Line:    6,
+				Message: "[ALERT]    (1) : config : parsing [/etc/haproxy/transactions/haproxy.cfg.1:6] : 'server loadogn-test1' : invalid address: '1.2.3.4:2222x'",
+			},
+			{
+				Message: "[NOTICE]   (1) : haproxy version is 2.8.3\n[ALERT]    (1) : config : Fatal errors found in configuration.",
+			},
+		}, diags)
+	})
+
+	t.Run("message without a line", func(t *testing.T) {
+		err := &dataplaneapi.DataPlaneValidationError{Code: 400, Message: "config is invalid"}
+
+		assert.Equal(t, []diagnostic{{Message: "config is invalid"}}, checkDiagnostics(testRenderedCfg, err))
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		err := dataplaneapi.ErrDataPlaneConfigInvalid
+
+		assert.Equal(t, []diagnostic{{Message: err.Error()}}, checkDiagnostics(testRenderedCfg, err))
+	})
+}
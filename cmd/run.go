@@ -7,7 +7,10 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"text/template"
 	"time"
 
 	"go.infratographer.com/x/events"
@@ -17,12 +20,13 @@ import (
 
 	"go.infratographer.com/x/oauth2x"
 
-	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
-
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/admin"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/config"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/pubsub"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/lbapi"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,6 +35,14 @@ import (
 const (
 	defaultDataplaneConnRetries       = 30
 	defaultDataplaneConnRetryInterval = 1 * time.Second
+	defaultLBAPIFailureThreshold      = 5
+	defaultLBAPIFailureCooldown       = 30 * time.Second
+	defaultMsgHandlerTimeout          = 2 * time.Minute
+	defaultEventsConnectRetries       = 5
+	defaultEventsConnectBackoff       = 1 * time.Second
+	defaultEventsMaxConnectBackoff    = 30 * time.Second
+	defaultPollInterval               = 30 * time.Second
+	defaultLBAPIFetchConcurrency      = 4
 )
 
 // runCmd starts loadbalancer-manager-haproxy service
@@ -45,17 +57,18 @@ var runCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(runCmd)
 
+	addConfigFlags(runCmd.PersistentFlags())
+	addDataPlaneFlags(runCmd.PersistentFlags())
+	bindFlags(viper.GetViper(), runCmd.PersistentFlags())
+
 	runCmd.PersistentFlags().StringSlice("change-topics", []string{}, "event change topics to subscribe to")
 	viperx.MustBindFlag(viper.GetViper(), "change-topics", runCmd.PersistentFlags().Lookup("change-topics"))
 
-	runCmd.PersistentFlags().String("dataplane-user-name", "haproxy", "DataplaneAPI user name")
-	viperx.MustBindFlag(viper.GetViper(), "dataplane.user.name", runCmd.PersistentFlags().Lookup("dataplane-user-name"))
-
-	runCmd.PersistentFlags().String("dataplane-user-pwd", "adminpwd", "DataplaneAPI user password")
-	viperx.MustBindFlag(viper.GetViper(), "dataplane.user.pwd", runCmd.PersistentFlags().Lookup("dataplane-user-pwd"))
+	runCmd.PersistentFlags().Bool("strict-targeting", false, "only act on change events whose subject is the loadbalancer, ignoring ones listing it as an additional subject")
+	viperx.MustBindFlag(viper.GetViper(), "strict-targeting", runCmd.PersistentFlags().Lookup("strict-targeting"))
 
-	runCmd.PersistentFlags().String("dataplane-url", "http://127.0.0.1:5555/v2/", "DataplaneAPI base url")
-	viperx.MustBindFlag(viper.GetViper(), "dataplane.url", runCmd.PersistentFlags().Lookup("dataplane-url"))
+	runCmd.PersistentFlags().Bool("strict-version-check", false, "fail startup when the haproxy version doesn't support the configured features, instead of logging a warning")
+	viperx.MustBindFlag(viper.GetViper(), "strict-version-check", runCmd.PersistentFlags().Lookup("strict-version-check"))
 
 	runCmd.PersistentFlags().Int("dataplane-connect-retries", defaultDataplaneConnRetries, "DataplaneAPI connection retry attempts")
 	viperx.MustBindFlag(viper.GetViper(), "dataplane-connect-retries", runCmd.PersistentFlags().Lookup("dataplane-connect-retries"))
@@ -63,18 +76,67 @@ func init() {
 	runCmd.PersistentFlags().Duration("dataplane-connect-retry-interval", defaultDataplaneConnRetryInterval, "DataplaneAPI connection retry interval")
 	viperx.MustBindFlag(viper.GetViper(), "dataplane-connect-retry-interval", runCmd.PersistentFlags().Lookup("dataplane-connect-retry-interval"))
 
-	runCmd.PersistentFlags().String("base-haproxy-config", "", "Base config for haproxy")
-	viperx.MustBindFlag(viper.GetViper(), "haproxy.config.base", runCmd.PersistentFlags().Lookup("base-haproxy-config"))
+	runCmd.PersistentFlags().Duration("post-ready-grace", 0, "delay between the DataplaneAPI becoming ready and the first config applied (0 disables)")
+	viperx.MustBindFlag(viper.GetViper(), "post-ready-grace", runCmd.PersistentFlags().Lookup("post-ready-grace"))
+
+	runCmd.PersistentFlags().Bool("skip-check", false, "post configs without validating them with the dataplaneapi first, only for pre-validated configs as an invalid config fails on reload")
+	viperx.MustBindFlag(viper.GetViper(), "dataplane.skip-check", runCmd.PersistentFlags().Lookup("skip-check"))
+
+	runCmd.PersistentFlags().Bool("once", false, "apply the loadbalancer config once and exit without subscribing to events")
+	viperx.MustBindFlag(viper.GetViper(), "once", runCmd.PersistentFlags().Lookup("once"))
+
+	runCmd.PersistentFlags().Bool("poll-only", false, "poll the LoadbalancerAPI for changes every poll-interval instead of subscribing to events, e.g. without NATS")
+	viperx.MustBindFlag(viper.GetViper(), "poll-only", runCmd.PersistentFlags().Lookup("poll-only"))
+
+	runCmd.PersistentFlags().Duration("poll-interval", defaultPollInterval, "how often the LoadbalancerAPI is polled for changes with poll-only")
+	viperx.MustBindFlag(viper.GetViper(), "poll-interval", runCmd.PersistentFlags().Lookup("poll-interval"))
+
+	runCmd.PersistentFlags().Int("fetch-concurrency", defaultLBAPIFetchConcurrency, "how many loadbalancers are requested from the LoadbalancerAPI at once")
+	viperx.MustBindFlag(viper.GetViper(), "loadbalancerapi.fetch-concurrency", runCmd.PersistentFlags().Lookup("fetch-concurrency"))
+
+	runCmd.PersistentFlags().Bool("rollback-on-failure", false, "re-apply the previous config when frontends are not up after applying a new one")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.rollback-on-failure", runCmd.PersistentFlags().Lookup("rollback-on-failure"))
+
+	runCmd.PersistentFlags().String("apply-strategy", string(manager.ApplyStrategyRaw), "how configs are applied to haproxy: raw posts them in full with a reload, runtime applies server-only changes through the runtime api without one")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.apply-strategy", runCmd.PersistentFlags().Lookup("apply-strategy"))
 
-	runCmd.PersistentFlags().String("loadbalancerapi-url", "", "LoadbalancerAPI url")
-	viperx.MustBindFlag(viper.GetViper(), "loadbalancerapi.url", runCmd.PersistentFlags().Lookup("loadbalancerapi-url"))
+	runCmd.PersistentFlags().Bool("runtime-server-updates", false, "apply changes to servers only through the runtime api, without reloading haproxy")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.runtime-server-updates", runCmd.PersistentFlags().Lookup("runtime-server-updates"))
+	_ = runCmd.PersistentFlags().MarkDeprecated("runtime-server-updates", "use --apply-strategy=runtime instead")
 
-	runCmd.PersistentFlags().String("loadbalancer-id", "", "Loadbalancer ID to act on event changes")
-	viperx.MustBindFlag(viper.GetViper(), "loadbalancer.id", runCmd.PersistentFlags().Lookup("loadbalancer-id"))
+	runCmd.PersistentFlags().Duration("min-reload-interval", 0, "minimum time between haproxy config reloads, updates arriving sooner are coalesced (0 disables)")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.min-reload-interval", runCmd.PersistentFlags().Lookup("min-reload-interval"))
+
+	runCmd.PersistentFlags().Int("max-config-bytes", 0, "largest rendered haproxy config applied, larger ones fail instead of reloading haproxy (0 is unlimited)")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.max-config-bytes", runCmd.PersistentFlags().Lookup("max-config-bytes"))
+
+	runCmd.PersistentFlags().Duration("drift-check-interval", 0, "how often the config haproxy runs with is compared to the last applied config, logging drift (0 disables)")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.drift-check-interval", runCmd.PersistentFlags().Lookup("drift-check-interval"))
+
+	runCmd.PersistentFlags().Bool("reapply-on-drift", false, "re-apply the last applied config when the config haproxy runs with has drifted from it")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.reapply-on-drift", runCmd.PersistentFlags().Lookup("reapply-on-drift"))
+
+	runCmd.PersistentFlags().Int("history-size", 0, "number of recent config applies kept for the history command, 20 when 0 (negative keeps none)")
+	viperx.MustBindFlag(viper.GetViper(), "haproxy.history-size", runCmd.PersistentFlags().Lookup("history-size"))
 
 	runCmd.PersistentFlags().Uint64("max-msg-process-attempts", 0, "maxiumum number of attempts at processing an event message")
 	viperx.MustBindFlag(viper.GetViper(), "max-msg-process-attempts", runCmd.PersistentFlags().Lookup("max-msg-process-attempts"))
 
+	runCmd.PersistentFlags().Duration("msg-handler-timeout", defaultMsgHandlerTimeout, "maximum time to process an event message before it is retried (0 disables)")
+	viperx.MustBindFlag(viper.GetViper(), "msg-handler-timeout", runCmd.PersistentFlags().Lookup("msg-handler-timeout"))
+
+	runCmd.PersistentFlags().Int("events-connect-retries", defaultEventsConnectRetries, "events broker connection retry attempts at startup")
+	viperx.MustBindFlag(viper.GetViper(), "events-connect-retries", runCmd.PersistentFlags().Lookup("events-connect-retries"))
+
+	runCmd.PersistentFlags().Duration("events-connect-backoff", defaultEventsConnectBackoff, "wait before the first events broker connection retry, doubling with each retry")
+	viperx.MustBindFlag(viper.GetViper(), "events-connect-backoff", runCmd.PersistentFlags().Lookup("events-connect-backoff"))
+
+	runCmd.PersistentFlags().String("admin-listen", "", "address of the admin http server, e.g. 127.0.0.1:8090 or unix:/run/lbm/admin.sock, which requires admin-token beyond localhost (empty disables)")
+	viperx.MustBindFlag(viper.GetViper(), "admin.listen", runCmd.PersistentFlags().Lookup("admin-listen"))
+
+	runCmd.PersistentFlags().String("admin-token", "", "bearer token required by the admin http server")
+	viperx.MustBindFlag(viper.GetViper(), "admin.token", runCmd.PersistentFlags().Lookup("admin-token"))
+
 	events.MustViperFlags(viper.GetViper(), runCmd.PersistentFlags(), appName)
 	oauth2x.MustViperFlags(viper.GetViper(), runCmd.Flags())
 }
@@ -96,35 +158,79 @@ func run(cmdCtx context.Context, v *viper.Viper) error {
 
 	managedLBID, err := gidx.Parse(viper.GetString("loadbalancer.id"))
 	if err != nil {
-		logger.Fatalw("failed to parse loadbalancer.id gidx: %w", err, "loadbalancerID", viper.GetString("loadbalancer.id"))
+		return fmt.Errorf("%w: %v", ErrLBIDInvalid, err)
+	}
+
+	settings, err := loadSettings(v)
+	if err != nil {
+		return err
+	}
+
+	configTemplate, err := loadConfigTemplate(v)
+	if err != nil {
+		return err
+	}
+
+	lbClient, err := newLBAPIClient(ctx, v)
+	if err != nil {
+		return err
 	}
 
 	mgr := &manager.Manager{
 		Context:                       ctx,
 		Logger:                        logger,
-		DataPlaneClient:               dataplaneapi.NewClient(viper.GetString("dataplane.url"), dataplaneapi.WithLogger(logger)),
 		DataPlaneConnectRetries:       viper.GetInt("dataplane-connect-retries"),
 		DataPlaneConnectRetryInterval: viper.GetDuration("dataplane-connect-retry-interval"),
-		LBClient:                      lbapi.NewClient(viper.GetString("loadbalancerapi.url")),
+		PostReadyGrace:                viper.GetDuration("post-ready-grace"),
+		PollInterval:                  viper.GetDuration("poll-interval"),
+		FetchConcurrency:              viper.GetInt("loadbalancerapi.fetch-concurrency"),
+		LBClient:                      lbClient,
 		ManagedLBID:                   managedLBID,
 		BaseCfgPath:                   viper.GetString("haproxy.config.base"),
+		BaseCfgDir:                    viper.GetString("haproxy.config.base-dir"),
+		BaseCfgProfiles:               viper.GetStringMapString("haproxy.config.profiles"),
+		BaseCfgProfileAttribute:       manager.ProfileAttribute(viper.GetString("haproxy.config.profile-attribute")),
+		Settings:                      settings,
+		ConfigTemplate:                configTemplate,
+		RollbackOnFailure:             viper.GetBool("haproxy.rollback-on-failure"),
+		SkipCheck:                     viper.GetBool("dataplane.skip-check"),
+		ApplyStrategy:                 applyStrategy(v),
+		MinReloadInterval:             viper.GetDuration("haproxy.min-reload-interval"),
+		CanonicalConfig:               viper.GetBool("haproxy.canonical-config"),
+		AnnotateSections:              viper.GetBool("haproxy.annotate-sections"),
+		StrictTargeting:               viper.GetBool("strict-targeting"),
+		StrictVersionCheck:            viper.GetBool("strict-version-check"),
+		MaxConfigBytes:                viper.GetInt("haproxy.max-config-bytes"),
+		DriftCheckInterval:            viper.GetDuration("haproxy.drift-check-interval"),
+		ReapplyOnDrift:                viper.GetBool("haproxy.reapply-on-drift"),
+		HistorySize:                   viper.GetInt("haproxy.history-size"),
+	}
+
+	if err := setDataPlaneClient(mgr, v); err != nil {
+		return err
 	}
 
 	logger.Infow("Initializing...", zap.String("loadbalancerID", viper.GetString("loadbalancer.id")))
 
-	// init lbapi client
-	if config.AppConfig.OIDC.Client.Issuer != "" {
-		oidcTS, err := oauth2x.NewClientCredentialsTokenSrc(ctx, config.AppConfig.OIDC.Client)
-		if err != nil {
-			logger.Fatalw("failed to create oauth2 token source", "error", err)
+	// apply the config a single time, without an events connection
+	if viper.GetBool("once") {
+		return mgr.RunOnce()
+	}
+
+	if err := startAdminServer(ctx, mgr, v); err != nil {
+		return err
+	}
+
+	// poll lbapi for changes, without an events connection
+	if viper.GetBool("poll-only") {
+		startConfigDump(ctx, logger, mgr, func() []string { return nil })
+
+		if err := mgr.RunPolling(); err != nil {
+			logger.Errorw("failed starting manager", "error", err)
+			return err
 		}
 
-		oauthHTTPClient := oauth2x.NewClient(ctx, oidcTS)
-		mgr.LBClient = lbapi.NewClient(viper.GetString("loadbalancerapi.url"),
-			lbapi.WithHTTPClient(oauthHTTPClient),
-		)
-	} else {
-		mgr.LBClient = lbapi.NewClient(viper.GetString("loadbalancerapi.url"))
+		return nil
 	}
 
 	// generate a random queuegroup name
@@ -132,9 +238,16 @@ func run(cmdCtx context.Context, v *viper.Viper) error {
 	// and processing it
 	config.AppConfig.Events.NATS.QueueGroup = generateQueueGroupName()
 
-	events, err := events.NewConnection(config.AppConfig.Events, events.WithLogger(logger))
+	events, err := pubsub.Connect(
+		ctx,
+		config.AppConfig.Events,
+		pubsub.WithConnectLogger(logger),
+		pubsub.WithConnectRetries(viper.GetInt("events-connect-retries")),
+		pubsub.WithConnectBackoff(viper.GetDuration("events-connect-backoff"), defaultEventsMaxConnectBackoff),
+	)
 	if err != nil {
-		logger.Fatalw("failed to create events connection", "error", err)
+		logger.Errorw("failed to create events connection", "error", err)
+		return err
 	}
 
 	// init events subscriber
@@ -144,6 +257,7 @@ func run(cmdCtx context.Context, v *viper.Viper) error {
 		pubsub.WithMsgHandler(mgr.ProcessMsg),
 		pubsub.WithLogger(logger),
 		pubsub.WithMaxMsgProcessAttempts(viper.GetUint64("max-msg-process-attempts")),
+		pubsub.WithHandlerTimeout(viper.GetDuration("msg-handler-timeout")),
 	)
 
 	mgr.Subscriber = subscriber
@@ -155,27 +269,61 @@ func run(cmdCtx context.Context, v *viper.Viper) error {
 		}
 	}
 
+	startConfigDump(ctx, logger, mgr, subscriber.Topics)
+
 	defer func() {
 		_ = events.Shutdown(ctx)
 	}()
 
 	if err := mgr.Run(); err != nil {
-		logger.Fatalw("failed starting manager", "error", err)
+		logger.Errorw("failed starting manager", "error", err)
+		return err
 	}
 
 	return nil
 }
 
+// startAdminServer serves the admin endpoints in the background until the context is done, when
+// an address is configured
+func startAdminServer(ctx context.Context, mgr *manager.Manager, v *viper.Viper) error {
+	addr := v.GetString("admin.listen")
+	if addr == "" {
+		return nil
+	}
+
+	srv, err := admin.NewServer(addr, mgr,
+		admin.WithLogger(logger),
+		admin.WithToken(v.GetString("admin.token")),
+		admin.WithHistory(mgr),
+		admin.WithState(mgr),
+	)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := srv.Run(ctx); err != nil {
+			logger.Errorw("admin server failed", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
 // validateMandatoryFlags collects the mandatory flag validation
 func validateMandatoryFlags() error {
 	errs := []error{}
 
-	if len(viper.GetStringSlice("change-topics")) < 1 {
+	if !viper.GetBool("once") && !viper.GetBool("poll-only") && len(viper.GetStringSlice("change-topics")) < 1 {
 		errs = append(errs, ErrSubscriberTopicsRequired)
 	}
 
-	if viper.GetString("haproxy.config.base") == "" {
-		errs = append(errs, ErrHAProxyBaseConfigRequired)
+	if viper.GetBool("poll-only") && viper.GetDuration("poll-interval") <= 0 {
+		errs = append(errs, ErrPollIntervalInvalid)
+	}
+
+	if err := validateBaseConfig(viper.GetViper()); err != nil {
+		errs = append(errs, err)
 	}
 
 	if viper.GetString("loadbalancerapi.url") == "" {
@@ -186,6 +334,10 @@ func validateMandatoryFlags() error {
 		errs = append(errs, ErrLBIDRequired)
 	}
 
+	if strategy := applyStrategy(viper.GetViper()); !strategy.Valid() {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrApplyStrategyInvalid, strategy))
+	}
+
 	if len(errs) == 0 {
 		return nil
 	}
@@ -193,6 +345,192 @@ func validateMandatoryFlags() error {
 	return errors.Join(errs...) //nolint:goerr113
 }
 
+// validateBaseConfig checks exactly one of the base config file and directory is set, as the
+// default for loadbalancers without a profile, and the profile attribute is supported
+func validateBaseConfig(v *viper.Viper) error {
+	base, baseDir := v.GetString("haproxy.config.base"), v.GetString("haproxy.config.base-dir")
+
+	switch {
+	case base == "" && baseDir == "":
+		return ErrHAProxyBaseConfigRequired
+	case base != "" && baseDir != "":
+		return ErrHAProxyBaseConfigConflict
+	}
+
+	attribute := manager.ProfileAttribute(v.GetString("haproxy.config.profile-attribute"))
+	if len(v.GetStringMapString("haproxy.config.profiles")) > 0 && !attribute.Valid() {
+		return fmt.Errorf("%w: %q", ErrProfileAttributeInvalid, attribute)
+	}
+
+	return nil
+}
+
+// applyStrategy returns the apply strategy of the manager. The deprecated runtime-server-updates
+// flag selects the runtime strategy over the default raw one.
+func applyStrategy(v *viper.Viper) manager.ApplyStrategy {
+	strategy := manager.ApplyStrategy(v.GetString("haproxy.apply-strategy"))
+
+	if strategy == manager.ApplyStrategyRaw && v.GetBool("haproxy.runtime-server-updates") {
+		return manager.ApplyStrategyRuntime
+	}
+
+	return strategy
+}
+
+// loadSettings reads the haproxy port and pool settings from the config file
+func loadSettings(v *viper.Viper) (haproxyconfig.Settings, error) {
+	settings := haproxyconfig.Settings{}
+
+	if err := v.UnmarshalKey("haproxy.settings", &settings); err != nil {
+		return settings, fmt.Errorf("%w: %v", ErrHAProxySettingsInvalid, err)
+	}
+
+	settings.AllowRawDirectives = v.GetBool("haproxy.allow-raw-directives")
+	settings.Descriptions = v.GetBool("haproxy.descriptions")
+	settings.OverwriteBaseSections = v.GetBool("haproxy.overwrite-base-sections")
+	settings.DontLogNull = v.GetBool("haproxy.dontlognull")
+	settings.LogFormat = v.GetString("haproxy.log-format")
+	settings.LogFormatSD = v.GetString("haproxy.log-format-sd")
+	settings.Region = v.GetString("haproxy.region")
+	settings.CertDir = v.GetString("haproxy.tls.cert-dir")
+	settings.MetricsPort = v.GetInt64("haproxy.metrics-port")
+	settings.PlaceholderBackends = v.GetBool("haproxy.placeholder-backends")
+	settings.DualStack = v.GetBool("haproxy.dual-stack")
+	settings.OmitSingleServerBalance = v.GetBool("haproxy.omit-single-server-balance")
+
+	if mode := v.GetString("haproxy.defaults-mode"); mode != "" {
+		settings.DefaultsMode = mode
+	}
+
+	// global tuning flags override the settings file
+	globalFlags := []struct {
+		key   string
+		value *int64
+	}{
+		{"haproxy.global.nbthread", &settings.Global.NbThread},
+		{"haproxy.global.nbproc", &settings.Global.NbProc},
+		{"haproxy.global.tune-bufsize", &settings.Global.TuneBufSize},
+		{"haproxy.global.tune-ssl-cachesize", &settings.Global.TuneSSLCacheSize},
+		{"haproxy.global.tune-ssl-default-dh-param", &settings.Global.TuneSSLDefaultDHParam},
+	}
+
+	for _, f := range globalFlags {
+		if value := v.GetInt64(f.key); value > 0 {
+			*f.value = value
+		}
+	}
+
+	if v.GetBool("haproxy.global.stats-socket") {
+		settings.Global.StatsSocket = true
+	}
+
+	return settings, nil
+}
+
+// loadConfigTemplate reads and parses the config template, returning nil when there is none
+func loadConfigTemplate(v *viper.Viper) (*template.Template, error) {
+	path := v.GetString("haproxy.config.template")
+	if path == "" {
+		return nil, nil
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConfigTemplateUnreadable, err)
+	}
+
+	return haproxyconfig.ParseTemplate(filepath.Base(path), string(text))
+}
+
+// setDataPlaneClient sets the manager's dataplaneapi client, or a group of them when there are several urls.
+// The manager's settings must be set, as a group can't be used with managed crt-lists.
+func setDataPlaneClient(mgr *manager.Manager, v *viper.Viper) error {
+	if err := validateDataPlaneURLs(v); err != nil {
+		return err
+	}
+
+	urls := v.GetStringSlice("dataplane.url")
+
+	skipVerify := dataPlaneInsecureSkipVerify(v)
+
+	if len(urls) == 1 {
+		mgr.DataPlaneClient = dataplaneapi.NewClient(urls[0],
+			dataplaneapi.WithLogger(logger),
+			dataplaneapi.WithInsecureSkipVerify(skipVerify),
+		)
+
+		return nil
+	}
+
+	// crt-lists are written where the manager runs, which only a single haproxy can share
+	if mgr.Settings.ManagesCrtLists() {
+		return ErrCrtListsWithGroup
+	}
+
+	mgr.DataPlaneClient = dataplaneapi.NewGroup(urls,
+		dataplaneapi.WithGroupLogger(logger),
+		dataplaneapi.WithQuorum(v.GetInt("dataplane.quorum")),
+		dataplaneapi.WithGroupInsecureSkipVerify(skipVerify),
+	)
+
+	return nil
+}
+
+// validateDataPlaneURLs checks there is at least one dataplaneapi url, none of them empty, and the
+// quorum can be reached with them, as a group of no dataplaneapis would never contact a haproxy
+func validateDataPlaneURLs(v *viper.Viper) error {
+	urls := v.GetStringSlice("dataplane.url")
+
+	if len(urls) == 0 {
+		return ErrDataPlaneURLRequired
+	}
+
+	for _, url := range urls {
+		if strings.TrimSpace(url) == "" {
+			return ErrDataPlaneURLRequired
+		}
+	}
+
+	if quorum := v.GetInt("dataplane.quorum"); quorum < 0 || quorum > len(urls) {
+		return fmt.Errorf("%w: %d with %d urls", ErrDataPlaneQuorumInvalid, quorum, len(urls))
+	}
+
+	return nil
+}
+
+// dataPlaneInsecureSkipVerify returns whether the dataplaneapi certificates are not verified,
+// warning when they aren't
+func dataPlaneInsecureSkipVerify(v *viper.Viper) bool {
+	skip := v.GetBool("dataplane.insecure-skip-verify")
+
+	if skip {
+		logger.Warn("dataplaneapi tls certificate verification is disabled, do not use in production")
+	}
+
+	return skip
+}
+
+// newLBAPIClient returns a loadbalancer api client, authenticated with oauth2 client credentials when configured
+func newLBAPIClient(ctx context.Context, v *viper.Viper) (*lbapi.Client, error) {
+	opts := []lbapi.Option{
+		lbapi.WithLogger(logger),
+		lbapi.WithFailureThreshold(v.GetInt("loadbalancerapi.failure-threshold")),
+		lbapi.WithCooldown(v.GetDuration("loadbalancerapi.failure-cooldown")),
+		lbapi.WithLenient(v.GetBool("loadbalancerapi.lenient")),
+	}
+
+	if config.AppConfig.OIDC.Client.Issuer != "" {
+		oidcTS, err := oauth2x.NewClientCredentialsTokenSrc(ctx, config.AppConfig.OIDC.Client)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrOAuth2TokenSource, err)
+		}
+
+		opts = append(opts, lbapi.WithHTTPClient(oauth2x.NewClient(ctx, oidcTS)))
+	}
+
+	return lbapi.NewClient(v.GetString("loadbalancerapi.url"), opts...), nil
+}
+
 // generateQueueGroupName generates a random queue group name with prefix lbmanager-haproxy-
 func generateQueueGroupName() string {
 	const rlen = 10
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+func TestApplyStrategy(t *testing.T) {
+	tests := []struct {
+		name           string
+		strategy       string
+		runtimeUpdates bool
+		expStrategy    manager.ApplyStrategy
+	}{
+		{"raw", "raw", false, manager.ApplyStrategyRaw},
+		{"runtime", "runtime", false, manager.ApplyStrategyRuntime},
+		{"deprecated runtime server updates", "raw", true, manager.ApplyStrategyRuntime},
+		{"unsupported", "transaction", false, manager.ApplyStrategy("transaction")},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := viper.New()
+			v.Set("haproxy.apply-strategy", tt.strategy)
+			v.Set("haproxy.runtime-server-updates", tt.runtimeUpdates)
+
+			assert.Equal(t, tt.expStrategy, applyStrategy(v))
+		})
+	}
+}
+
+func TestValidateBaseConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		baseDir   string
+		profiles  map[string]string
+		attribute string
+		expErr    error
+	}{
+		{"base", "haproxy.cfg", "", nil, "location", nil},
+		{"base dir", "", "haproxy.d", nil, "location", nil},
+		{"missing", "", "", nil, "location", ErrHAProxyBaseConfigRequired},
+		{"conflict", "haproxy.cfg", "haproxy.d", nil, "location", ErrHAProxyBaseConfigConflict},
+		{"profiles", "haproxy.cfg", "", map[string]string{"lctnloc-a": "a.cfg"}, "location", nil},
+		{"profiles by owner", "haproxy.cfg", "", map[string]string{"tnntten-a": "a.cfg"}, "owner", nil},
+		{"profiles by unsupported attribute", "haproxy.cfg", "", map[string]string{"a": "a.cfg"}, "label", ErrProfileAttributeInvalid},
+		{"unsupported attribute without profiles", "haproxy.cfg", "", nil, "label", nil},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := viper.New()
+			v.Set("haproxy.config.base", tt.base)
+			v.Set("haproxy.config.base-dir", tt.baseDir)
+			v.Set("haproxy.config.profiles", tt.profiles)
+			v.Set("haproxy.config.profile-attribute", tt.attribute)
+
+			err := validateBaseConfig(v)
+
+			if tt.expErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.expErr)
+		})
+	}
+}
+
+func TestValidateDataPlaneURLs(t *testing.T) {
+	tests := []struct {
+		name   string
+		urls   []string
+		quorum int
+		expErr error
+	}{
+		{"single", []string{"http://127.0.0.1:5555/v2/"}, 0, nil},
+		{"group", []string{"http://10.0.0.1:5555/v2/", "http://10.0.0.2:5555/v2/"}, 0, nil},
+		{"group with quorum", []string{"http://10.0.0.1:5555/v2/", "http://10.0.0.2:5555/v2/"}, 1, nil},
+		{"quorum of all", []string{"http://10.0.0.1:5555/v2/", "http://10.0.0.2:5555/v2/"}, 2, nil},
+		{"no urls", []string{}, 0, ErrDataPlaneURLRequired},
+		{"empty url", []string{""}, 0, ErrDataPlaneURLRequired},
+		{"empty url in a group", []string{"http://10.0.0.1:5555/v2/", " "}, 0, ErrDataPlaneURLRequired},
+		{"quorum above the urls", []string{"http://10.0.0.1:5555/v2/", "http://10.0.0.2:5555/v2/"}, 3, ErrDataPlaneQuorumInvalid},
+		{"negative quorum", []string{"http://127.0.0.1:5555/v2/"}, -1, ErrDataPlaneQuorumInvalid},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := viper.New()
+			v.Set("dataplane.url", tt.urls)
+			v.Set("dataplane.quorum", tt.quorum)
+
+			err := validateDataPlaneURLs(v)
+
+			if tt.expErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.expErr)
+		})
+	}
+
+	t.Run("empty url flag", func(t *testing.T) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		addDataPlaneFlags(flags)
+
+		require.NoError(t, flags.Parse([]string{"--dataplane-url="}))
+
+		v := viper.New()
+		bindFlags(v, flags)
+
+		assert.ErrorIs(t, validateDataPlaneURLs(v), ErrDataPlaneURLRequired)
+	})
+}
+
+func TestSetDataPlaneClientCrtLists(t *testing.T) {
+	settings := haproxyconfig.Settings{
+		Ports: []haproxyconfig.PortSettings{{
+			ID:      "loadprt-test",
+			CrtList: "/etc/haproxy/crt-list.txt",
+			Certs:   []haproxyconfig.CertRef{{Cert: "www.pem"}},
+		}},
+	}
+
+	t.Run("single dataplaneapi", func(t *testing.T) {
+		v := viper.New()
+		v.Set("dataplane.url", []string{"http://127.0.0.1:5555/v2/"})
+
+		mgr := &manager.Manager{Settings: settings}
+
+		require.NoError(t, setDataPlaneClient(mgr, v))
+		assert.NotNil(t, mgr.DataPlaneClient)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		v := viper.New()
+		v.Set("dataplane.url", []string{"http://10.0.0.1:5555/v2/", "http://10.0.0.2:5555/v2/"})
+
+		mgr := &manager.Manager{Settings: settings}
+
+		assert.ErrorIs(t, setDataPlaneClient(mgr, v), ErrCrtListsWithGroup)
+		assert.Nil(t, mgr.DataPlaneClient)
+	})
+}
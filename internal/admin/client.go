@@ -0,0 +1,83 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+// Client requests the admin endpoints of a running manager
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of the admin server listening on addr, a host and port or a unix
+// socket path prefixed with unix:, presenting the token when it is set
+func NewClient(addr, token string) *Client {
+	c := &Client{
+		baseURL:    "http://" + addr,
+		token:      token,
+		httpClient: &http.Client{},
+	}
+
+	if socket, ok := strings.CutPrefix(addr, unixAddrPrefix); ok {
+		// the host is ignored, requests are dialed to the socket
+		c.baseURL = "http://admin"
+		c.httpClient.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+	}
+
+	return c
+}
+
+// History returns the most recent config applies of the manager, oldest first, limited to the
+// last limit applies when it is positive
+func (c *Client) History(ctx context.Context, limit int) ([]manager.ApplyRecord, error) {
+	query := url.Values{}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/history?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrRequestFailed, resp.Status)
+	}
+
+	records := []manager.ApplyRecord{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,2 @@
+// Package admin provides the admin http server, with endpoints for operating the manager
+package admin
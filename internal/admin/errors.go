@@ -0,0 +1,11 @@
+package admin
+
+import "errors"
+
+var (
+	// ErrTokenRequired is returned when the server listens beyond localhost without a token
+	ErrTokenRequired = errors.New("admin token is required when not listening on localhost")
+
+	// ErrRequestFailed is returned when the admin server doesn't respond with a success
+	ErrRequestFailed = errors.New("admin request failed")
+)
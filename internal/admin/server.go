@@ -0,0 +1,295 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+)
+
+const (
+	defaultShutdownTimeout = 5 * time.Second
+	readHeaderTimeout      = 10 * time.Second
+
+	// unixAddrPrefix is the prefix of addresses which are unix socket paths, e.g. unix:/run/lbm/admin.sock
+	unixAddrPrefix = "unix:"
+)
+
+// Reconciler immediately applies the latest config
+type Reconciler interface {
+	Reconcile(ctx context.Context) error
+}
+
+// HistoryProvider returns the most recent config applies, oldest first
+type HistoryProvider interface {
+	History() []manager.ApplyRecord
+}
+
+// StateProvider returns the config last successfully applied, and false when none has been
+type StateProvider interface {
+	AppliedState() (manager.AppliedState, bool)
+}
+
+// Server is the admin http server
+type Server struct {
+	addr       string
+	token      string
+	reconciler Reconciler
+	history    HistoryProvider
+	state      StateProvider
+	logger     *zap.SugaredLogger
+}
+
+// Option configures a server option.
+type Option func(s *Server)
+
+// reconcileResult is the response of the reconcile endpoint
+type reconcileResult struct {
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewServer returns an admin server listening on addr, a host and port or a unix socket path
+// prefixed with unix:. Listening beyond localhost requires a token, unix sockets are protected by
+// their file permissions instead.
+func NewServer(addr string, reconciler Reconciler, options ...Option) (*Server, error) {
+	s := &Server{
+		addr:       addr,
+		reconciler: reconciler,
+		logger:     zap.NewNop().Sugar(),
+	}
+
+	for _, opt := range options {
+		opt(s)
+	}
+
+	if s.token == "" && !loopback(addr) {
+		return nil, ErrTokenRequired
+	}
+
+	return s, nil
+}
+
+// WithLogger sets the logger for the server
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithToken requires requests to present the token as a bearer token
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithHistory serves the config applies of the provider at /history
+func WithHistory(history HistoryProvider) Option {
+	return func(s *Server) {
+		s.history = history
+	}
+}
+
+// WithState serves the config last applied by the provider at /state
+func WithState(state StateProvider) Option {
+	return func(s *Server) {
+		s.state = state
+	}
+}
+
+// Handler returns the handler serving the admin endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/reconcile", s.handleReconcile)
+
+	if s.history != nil {
+		mux.HandleFunc("/history", s.handleHistory)
+	}
+
+	if s.state != nil {
+		mux.HandleFunc("/state", s.handleState)
+	}
+
+	return s.authorize(mux)
+}
+
+// Run serves the admin endpoints until the context is done. A unix socket is removed when the
+// server stops.
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	if socket, ok := strings.CutPrefix(s.addr, unixAddrPrefix); ok {
+		defer removeSocket(socket)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancel()
+
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Infow("starting admin server", "address", s.addr)
+
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// listen listens on the address of the server. A socket left behind by a server which didn't stop
+// cleanly is removed first, so it doesn't prevent listening.
+func (s *Server) listen() (net.Listener, error) {
+	socket, ok := strings.CutPrefix(s.addr, unixAddrPrefix)
+	if !ok {
+		return net.Listen("tcp", s.addr)
+	}
+
+	removeSocket(socket)
+
+	return net.Listen("unix", socket)
+}
+
+// removeSocket removes the unix socket at path, leaving anything which isn't a socket in place
+func removeSocket(path string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+}
+
+// handleReconcile applies the latest config, responding with the result once it is applied
+func (s *Server) handleReconcile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	s.logger.Info("reconcile requested")
+
+	result := reconcileResult{Applied: true}
+	status := http.StatusOK
+
+	if err := s.reconciler.Reconcile(r.Context()); err != nil {
+		s.logger.Errorw("reconcile failed", zap.Error(err))
+
+		result = reconcileResult{Error: err.Error()}
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// handleHistory responds with the most recent config applies, oldest first, limited to the last
+// limit applies when the query sets one
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	records := s.history.History()
+
+	if param := r.URL.Query().Get("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+
+			return
+		}
+
+		if limit < len(records) {
+			records = records[len(records)-limit:]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(records)
+}
+
+// handleState responds with the config last applied, its hash, loadbalancer and apply time, so
+// external systems can verify haproxy converged to the desired state. Nothing applied yet is a 404.
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	state, ok := s.state.AppliedState()
+	if !ok {
+		http.Error(w, "no config applied", http.StatusNotFound)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// authorize rejects requests without the bearer token, when one is required
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopback returns true when the address only listens on localhost, which unix sockets do
+func loopback(addr string) bool {
+	if strings.HasPrefix(addr, unixAddrPrefix) {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
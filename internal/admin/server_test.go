@@ -0,0 +1,331 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+const testBaseCfgPath = "../../.devcontainer/config/haproxy.cfg"
+
+type reconcilerFunc func(ctx context.Context) error
+
+func (f reconcilerFunc) Reconcile(ctx context.Context) error {
+	return f(ctx)
+}
+
+type stateFunc func() (manager.AppliedState, bool)
+
+func (f stateFunc) AppliedState() (manager.AppliedState, bool) {
+	return f()
+}
+
+type historyFunc func() []manager.ApplyRecord
+
+func (f historyFunc) History() []manager.ApplyRecord {
+	return f()
+}
+
+func TestReconcile(t *testing.T) {
+	posted := ""
+
+	mgr := &manager.Manager{
+		Context: context.Background(),
+		Logger:  zap.NewNop().Sugar(),
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return &lbapi.LoadBalancer{ID: id}, nil
+			},
+		},
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				posted = config
+				return nil
+			},
+		},
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+		BaseCfgPath: testBaseCfgPath,
+	}
+
+	srv, err := NewServer("127.0.0.1:0", mgr)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reconcile", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	result := reconcileResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.True(t, result.Applied)
+
+	contents, err := os.ReadFile(testBaseCfgPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.TrimSpace(string(contents)), strings.TrimSpace(posted))
+}
+
+func TestReconcileFailure(t *testing.T) {
+	srv, err := NewServer("localhost:8090", reconcilerFunc(func(ctx context.Context) error {
+		return errors.New("dataplaneapi unavailable") // nolint:goerr113
+	}))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reconcile", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	result := reconcileResult{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.False(t, result.Applied)
+	assert.Equal(t, "dataplaneapi unavailable", result.Error)
+}
+
+func TestReconcileMethod(t *testing.T) {
+	srv, err := NewServer("127.0.0.1:8090", reconcilerFunc(func(ctx context.Context) error {
+		t.Fatal("reconciled on a GET")
+		return nil
+	}))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reconcile", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestToken(t *testing.T) {
+	reconciled := 0
+
+	reconciler := reconcilerFunc(func(ctx context.Context) error {
+		reconciled++
+		return nil
+	})
+
+	t.Run("required beyond localhost", func(t *testing.T) {
+		_, err := NewServer(":8090", reconciler)
+		assert.ErrorIs(t, err, ErrTokenRequired)
+
+		_, err = NewServer("10.0.0.1:8090", reconciler)
+		assert.ErrorIs(t, err, ErrTokenRequired)
+	})
+
+	srv, err := NewServer(":8090", reconciler, WithToken("secret"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"token without bearer", "secret", http.StatusUnauthorized},
+		{"valid token", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/reconcile", nil)
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+
+	assert.Equal(t, 1, reconciled)
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+
+	reconciled := 0
+
+	// unix sockets don't require a token
+	srv, err := NewServer("unix:"+socket, reconcilerFunc(func(ctx context.Context) error {
+		reconciled++
+		return nil
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- srv.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	resp, err := client.Post("http://admin/reconcile", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, reconciled)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err), "socket left behind after shutdown")
+}
+
+func TestHistory(t *testing.T) {
+	records := []manager.ApplyRecord{
+		{Time: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), LoadBalancerID: "loadbal-test", Diff: "+  server loadogn-test1 1.2.3.4:2222"},
+		{Time: time.Date(2023, 9, 1, 10, 5, 0, 0, time.UTC), LoadBalancerID: "loadbal-test", Diff: "-  server loadogn-test1 1.2.3.4:2222"},
+		{Time: time.Date(2023, 9, 1, 10, 9, 0, 0, time.UTC), LoadBalancerID: "loadbal-test", Error: "post failed"},
+	}
+
+	reconciler := reconcilerFunc(func(ctx context.Context) error {
+		return nil
+	})
+
+	t.Run("not served without a provider", func(t *testing.T) {
+		srv, err := NewServer("127.0.0.1:8090", reconciler)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	srv, err := NewServer("127.0.0.1:8090", reconciler, WithToken("secret"), WithHistory(historyFunc(func() []manager.ApplyRecord {
+		return records
+	})))
+	require.NoError(t, err)
+
+	api := httptest.NewServer(srv.Handler())
+	defer api.Close()
+
+	addr := strings.TrimPrefix(api.URL, "http://")
+
+	tests := []struct {
+		name  string
+		limit int
+		exp   []manager.ApplyRecord
+	}{
+		{"all", 0, records},
+		{"limited to the most recent", 2, records[1:]},
+		{"limit beyond the history", 5, records},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := NewClient(addr, "secret").History(context.Background(), tt.limit)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.exp, history)
+		})
+	}
+
+	t.Run("wrong token", func(t *testing.T) {
+		_, err := NewClient(addr, "wrong").History(context.Background(), 0)
+		assert.ErrorIs(t, err, ErrRequestFailed)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/history?limit=-1", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestState(t *testing.T) {
+	reconciler := reconcilerFunc(func(ctx context.Context) error {
+		return nil
+	})
+
+	t.Run("not served without a provider", func(t *testing.T) {
+		srv, err := NewServer("127.0.0.1:8090", reconciler)
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/state", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	state := manager.AppliedState{
+		LoadBalancerID: "loadbal-test",
+		Config:         "global\n  maxconn 200\n",
+		Hash:           "3f1a",
+		AppliedAt:      time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		applied bool
+		status  int
+		body    string
+	}{
+		{"applied", http.MethodGet, true, http.StatusOK,
+			`{"loadbalancerID":"loadbal-test","config":"global\n  maxconn 200\n","hash":"3f1a","appliedAt":"2023-09-01T10:00:00Z"}`},
+		{"nothing applied", http.MethodGet, false, http.StatusNotFound, ""},
+		{"wrong method", http.MethodPost, true, http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := NewServer("127.0.0.1:8090", reconciler, WithState(stateFunc(func() (manager.AppliedState, bool) {
+				return state, tt.applied
+			})))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, "/state", nil))
+
+			require.Equal(t, tt.status, rec.Code)
+
+			if tt.body != "" {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				assert.JSONEq(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
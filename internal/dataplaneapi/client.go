@@ -3,7 +3,11 @@ package dataplaneapi
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/spf13/viper"
@@ -12,11 +16,24 @@ import (
 
 var dataPlaneClientTimeout = 2 * time.Second
 
+const (
+	defaultBusyRetries = 3
+	defaultBusyBackoff = 500 * time.Millisecond
+
+	// maxBusyWait bounds the wait before retrying a busy dataplaneapi, including Retry-After
+	maxBusyWait = 5 * time.Second
+)
+
 // Client is the http client for Data Plane API
 type Client struct {
 	client  *http.Client
 	baseURL string
 	logger  *zap.SugaredLogger
+
+	// busyRetries is the number of times a config is retried while the dataplaneapi is busy,
+	// waiting busyBackoff before the first retry and doubling it for each next one
+	busyRetries int
+	busyBackoff time.Duration
 }
 
 // Option configures a connection option.
@@ -28,8 +45,10 @@ func NewClient(url string, options ...Option) *Client {
 		client: &http.Client{
 			Timeout: dataPlaneClientTimeout,
 		},
-		baseURL: url,
-		logger:  zap.NewNop().Sugar(),
+		baseURL:     url,
+		logger:      zap.NewNop().Sugar(),
+		busyRetries: defaultBusyRetries,
+		busyBackoff: defaultBusyBackoff,
 	}
 
 	for _, opt := range options {
@@ -46,10 +65,43 @@ func WithLogger(logger *zap.SugaredLogger) Option {
 	}
 }
 
+// WithBusyRetries sets how configs are retried while the dataplaneapi is busy, e.g. reloading
+// haproxy: up to retries times, waiting backoff before the first retry and doubling it for each
+// next one, unless the dataplaneapi asks for a wait with Retry-After. Zero retries doesn't retry.
+func WithBusyRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.busyRetries = retries
+		c.busyBackoff = backoff
+	}
+}
+
+// WithInsecureSkipVerify skips verifying the certificate of a dataplaneapi served over https, e.g.
+// a self-signed one in development or staging. It must not be used in production.
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(c *Client) {
+		if !skip {
+			return
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // explicitly requested for self-signed certificates
+
+		c.client.Transport = transport
+	}
+}
+
+// Close closes the idle connections of the client, e.g. on shutdown, so they aren't leaked by
+// custom transports. The client stays usable, opening new connections as needed, and Close may be
+// called more than once.
+func (c *Client) Close() {
+	c.client.CloseIdleConnections()
+}
+
 // APIIsReady returns true when a 200 is returned for a GET request to the Data Plane API
 func (c *Client) APIIsReady(ctx context.Context) bool {
 	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
 	req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
+	setRequestID(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -62,64 +114,257 @@ func (c *Client) APIIsReady(ctx context.Context) bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-// CheckConfig validates the proposed config without applying it
+// CheckConfig validates the proposed config without applying it. A rejected config is returned as
+// a *DataPlaneValidationError when the dataplaneapi describes the problem.
 func (c Client) CheckConfig(ctx context.Context, config string) error {
 	url := c.baseURL + "/services/haproxy/configuration/raw?only_validate=true"
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(config))
+	return c.sendConfig(ctx, url, config, func(resp *http.Response) error {
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			return nil
+		case http.StatusUnauthorized:
+			return ErrDataPlaneHTTPUnauthorized
+		case http.StatusBadRequest:
+			return validationError(resp.StatusCode, resp.Body)
+		default:
+			return ErrDataPlaneHTTPError
+		}
+	})
+}
+
+// PostConfig pushes a new haproxy config in plain text using basic auth
+func (c *Client) PostConfig(ctx context.Context, config string) error {
+	return c.postConfig(ctx, c.baseURL+"/services/haproxy/configuration/raw?skip_version=true", config)
+}
+
+// PostConfigWithoutReload pushes a new haproxy config without reloading haproxy, for configs whose
+// changes were already applied through the runtime api
+func (c *Client) PostConfigWithoutReload(ctx context.Context, config string) error {
+	return c.postConfig(ctx, c.baseURL+"/services/haproxy/configuration/raw?skip_version=true&skip_reload=true", config)
+}
+
+func (c *Client) postConfig(ctx context.Context, url, config string) error {
+	return c.sendConfig(ctx, url, config, func(resp *http.Response) error {
+		switch resp.StatusCode {
+		case http.StatusAccepted:
+			return nil
+		case http.StatusUnauthorized:
+			return ErrDataPlaneHTTPUnauthorized
+		default:
+			return ErrDataPlaneHTTPError
+		}
+	})
+}
+
+// sendConfig posts a config in plain text using basic auth and handles the response, retrying
+// while the dataplaneapi responds with a 429 or 503, as it does while reloading haproxy.
+// ErrDataPlaneBusy is returned when it is still busy once the retries are exhausted, distinct from
+// errors retrying won't resolve.
+func (c *Client) sendConfig(ctx context.Context, url, config string, handle func(resp *http.Response) error) error {
+	backoff := c.busyBackoff
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(config))
+		if err != nil {
+			return err
+		}
+
+		req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
+		setRequestID(req)
+		req.Header.Add("Content-Type", "text/plain")
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			defer resp.Body.Close()
+
+			return handle(resp)
+		}
+
+		wait := retryAfter(resp, backoff)
+
+		resp.Body.Close()
+
+		if attempt >= c.busyRetries {
+			return ErrDataPlaneBusy
+		}
+
+		c.logger.Warnw("dataplaneapi is busy, retrying", "status", resp.StatusCode, "attempt", attempt+1, "wait", wait)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(ErrDataPlaneBusy, ctx.Err())
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+	}
+}
+
+// retryAfter returns the wait before retrying a busy response, the Retry-After seconds when the
+// dataplaneapi sets them and backoff otherwise, bounded by maxBusyWait
+func retryAfter(resp *http.Response, backoff time.Duration) time.Duration {
+	wait := backoff
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	if wait > maxBusyWait {
+		return maxBusyWait
+	}
+
+	return wait
+}
+
+// rawConfig is the response of the raw configuration endpoint
+type rawConfig struct {
+	Version int64  `json:"_version"`
+	Data    string `json:"data"`
+}
+
+// GetConfig returns the config stored by the dataplaneapi, which haproxy runs with
+func (c *Client) GetConfig(ctx context.Context) (string, error) {
+	url := c.baseURL + "/services/haproxy/configuration/raw"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
-	req.Header.Add("Content-Type", "text/plain")
+	setRequestID(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
-	case http.StatusAccepted:
-		return nil
+	case http.StatusOK:
 	case http.StatusUnauthorized:
-		return ErrDataPlaneHTTPUnauthorized
-	case http.StatusBadRequest:
-		return ErrDataPlaneConfigInvalid
+		return "", ErrDataPlaneHTTPUnauthorized
 	default:
-		return ErrDataPlaneHTTPError
+		return "", ErrDataPlaneHTTPError
+	}
+
+	config := rawConfig{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return "", err
 	}
+
+	return config.Data, nil
 }
 
-// PostConfig pushes a new haproxy config in plain text using basic auth
-func (c *Client) PostConfig(ctx context.Context, config string) error {
-	url := c.baseURL + "/services/haproxy/configuration/raw?skip_version=true"
+// processInfo is the response of the runtime info endpoint, one entry per runtime api
+type processInfo []struct {
+	Info struct {
+		Version string `json:"version"`
+	} `json:"info"`
+}
+
+// HAProxyVersion returns the version of the running haproxy as reported by the runtime api, e.g.
+// 2.6.6-274d1a4
+func (c *Client) HAProxyVersion(ctx context.Context) (string, error) {
+	url := c.baseURL + "/services/haproxy/runtime/info"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
+	setRequestID(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return "", ErrDataPlaneHTTPUnauthorized
+	default:
+		return "", ErrDataPlaneHTTPError
+	}
+
+	info := processInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+
+	for _, p := range info {
+		if p.Info.Version != "" {
+			return p.Info.Version, nil
+		}
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(config))
+	return "", ErrDataPlaneVersionUnknown
+}
+
+// nativeStats is the response of the native stats endpoint, one collection per runtime api
+type nativeStats []struct {
+	Stats []struct {
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		Stats struct {
+			Status string `json:"status"`
+		} `json:"stats"`
+	} `json:"stats"`
+}
+
+// FrontendStatus returns the status of each frontend, e.g. OPEN or STOP, as reported by the runtime api
+func (c *Client) FrontendStatus(ctx context.Context) (map[string]string, error) {
+	url := c.baseURL + "/services/haproxy/stats/native?type=frontend"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
-	req.Header.Add("Content-Type", "text/plain")
+	setRequestID(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
-	case http.StatusAccepted:
-		return nil
+	case http.StatusOK:
 	case http.StatusUnauthorized:
-		return ErrDataPlaneHTTPUnauthorized
+		return nil, ErrDataPlaneHTTPUnauthorized
 	default:
-		return ErrDataPlaneHTTPError
+		return nil, ErrDataPlaneHTTPError
+	}
+
+	stats := nativeStats{}
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
 	}
+
+	status := map[string]string{}
+
+	for _, collection := range stats {
+		for _, stat := range collection.Stats {
+			if stat.Type == "frontend" {
+				status[stat.Name] = stat.Stats.Status
+			}
+		}
+	}
+
+	return status, nil
 }
 
 // WaitForDataPlaneReady waits for the DataPlane API to be ready
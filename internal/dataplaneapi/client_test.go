@@ -2,12 +2,17 @@ package dataplaneapi
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 type RoundTripFunc func(req *http.Request) *http.Response
@@ -45,15 +50,97 @@ func TestPostConfig(t *testing.T) {
 	_ = dc.PostConfig(context.TODO(), "cfg")
 }
 
+func TestPostConfigBusy(t *testing.T) {
+	newClient := func(statuses ...int) (*Client, *int) {
+		requests := 0
+
+		tc := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+			body, _ := io.ReadAll(req.Body)
+			assert.Equal(t, "cfg", string(body), "config not resent on retry")
+
+			status := statuses[len(statuses)-1]
+			if requests < len(statuses) {
+				status = statuses[requests]
+			}
+
+			requests++
+
+			return &http.Response{StatusCode: status, Header: http.Header{}}
+		})}
+
+		return &Client{
+			client:      tc,
+			baseURL:     "http://localhost:5555/v2",
+			logger:      zap.NewNop().Sugar(),
+			busyRetries: 2,
+			busyBackoff: time.Millisecond,
+		}, &requests
+	}
+
+	t.Run("busy then accepted", func(t *testing.T) {
+		dc, requests := newClient(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusAccepted)
+
+		require.NoError(t, dc.PostConfig(context.Background(), "cfg"))
+		assert.Equal(t, 3, *requests)
+	})
+
+	t.Run("busy beyond the retries", func(t *testing.T) {
+		dc, requests := newClient(http.StatusServiceUnavailable)
+
+		assert.ErrorIs(t, dc.PostConfig(context.Background(), "cfg"), ErrDataPlaneBusy)
+		assert.Equal(t, 3, *requests)
+	})
+
+	t.Run("other errors aren't retried", func(t *testing.T) {
+		dc, requests := newClient(http.StatusInternalServerError)
+
+		assert.ErrorIs(t, dc.PostConfig(context.Background(), "cfg"), ErrDataPlaneHTTPError)
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("check retried", func(t *testing.T) {
+		dc, requests := newClient(http.StatusServiceUnavailable, http.StatusAccepted)
+
+		require.NoError(t, dc.CheckConfig(context.Background(), "cfg"))
+		assert.Equal(t, 2, *requests)
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		dc, _ := newClient(http.StatusServiceUnavailable)
+		dc.busyBackoff = time.Hour
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := dc.PostConfig(ctx, "cfg")
+		assert.ErrorIs(t, err, ErrDataPlaneBusy)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+
+	assert.Equal(t, time.Second, retryAfter(resp, time.Second))
+
+	resp.Header.Set("Retry-After", "2")
+	assert.Equal(t, 2*time.Second, retryAfter(resp, time.Second))
+
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, maxBusyWait, retryAfter(resp, time.Second))
+}
+
 func TestCheckConfig(t *testing.T) {
 	tests := []struct {
 		name           string
 		cfg            string
 		respStatusCode int
+		respBody       string
 		errMsg         string
 	}{
-		{"valid config", "cfg", http.StatusAccepted, ""},
-		{"invalid config", "cfg🍔", http.StatusBadRequest, "config is invalid"},
+		{"valid config", "cfg", http.StatusAccepted, "", ""},
+		{"invalid config", "cfg🍔", http.StatusBadRequest, "", "config is invalid"},
+		{"invalid config with an error body", "cfg🍔", http.StatusBadRequest, checkConfigErrorBody, "line 23: "},
 	}
 
 	for _, tt := range tests {
@@ -77,6 +164,7 @@ func TestCheckConfig(t *testing.T) {
 
 				return &http.Response{
 					StatusCode: tt.respStatusCode,
+					Body:       io.NopCloser(strings.NewReader(tt.respBody)),
 				}
 			})}
 
@@ -96,6 +184,52 @@ func TestCheckConfig(t *testing.T) {
 	}
 }
 
+// checkConfigErrorBody is the response of the dataplaneapi to a config with an unknown keyword
+const checkConfigErrorBody = `{"code":400,"message":"err transactionId=86a7b9c1 ` +
+	`msg: [NOTICE]   (1234) : haproxy version is 2.8.3\n[ALERT]    (1234) : config : parsing ` +
+	`[/tmp/haproxy/haproxy.cfg.86a7b9c1:23] : unknown keyword 'balanse' in 'backend' section; did you mean 'balance' maybe ?\n` +
+	`[ALERT]    (1234) : config : Error(s) found in configuration file : /tmp/haproxy/haproxy.cfg.86a7b9c1\n` +
+	`[ALERT]    (1234) : config : Fatal errors found in configuration."}`
+
+func TestValidationError(t *testing.T) {
+	t.Run("error body", func(t *testing.T) {
+		err := validationError(http.StatusBadRequest, strings.NewReader(checkConfigErrorBody))
+		require.ErrorIs(t, err, ErrDataPlaneConfigInvalid)
+
+		var validationErr *DataPlaneValidationError
+
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, 400, validationErr.Code)
+		assert.Equal(t, 23, validationErr.Line)
+		assert.Contains(t, validationErr.Message, "unknown keyword 'balanse' in 'backend' section")
+	})
+
+	t.Run("message without a line", func(t *testing.T) {
+		err := validationError(http.StatusBadRequest, strings.NewReader(`{"code":400,"message":"config is empty"}`))
+
+		var validationErr *DataPlaneValidationError
+
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, 0, validationErr.Line)
+		assert.EqualError(t, err, "dataplaneapi config is invalid: config is empty")
+	})
+
+	t.Run("body which isn't json", func(t *testing.T) {
+		err := validationError(http.StatusBadRequest, strings.NewReader("bad request\n"))
+
+		var validationErr *DataPlaneValidationError
+
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, http.StatusBadRequest, validationErr.Code)
+		assert.Equal(t, "bad request", validationErr.Message)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		err := validationError(http.StatusBadRequest, strings.NewReader(""))
+		assert.Equal(t, ErrDataPlaneConfigInvalid, err)
+	})
+}
+
 func TestAPIIsReady(t *testing.T) {
 	// test 200 response
 	tcReady := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
@@ -139,3 +273,309 @@ func TestAPIIsReady(t *testing.T) {
 		t.Error("expected dataplane api readiness to be false, got:", ready)
 	}
 }
+
+func TestFrontendStatus(t *testing.T) {
+	body := `[{"runtimeAPI":"/var/run/haproxy/haproxy.sock","stats":[
+		{"name":"loadprt-test","type":"frontend","stats":{"status":"OPEN"}},
+		{"name":"stats","type":"frontend","stats":{"status":"STOP"}},
+		{"name":"loadprt-test","type":"backend","stats":{"status":"DOWN"}}
+	]}]`
+
+	tc := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+		_, _, ok := req.BasicAuth()
+		if !ok {
+			t.Error("expected Basic Auth to be set, got", ok)
+		}
+		if !strings.Contains(req.URL.String(), "services/haproxy/stats/native") {
+			t.Error("expected request to contain /services/haproxy/stats/native, got", req.URL.String())
+		}
+		if req.Method != "GET" {
+			t.Error("expected request method to be GET, got", req.Method)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	})}
+
+	dc := Client{
+		client:  tc,
+		baseURL: "http://localhost:5555/v2",
+	}
+
+	status, err := dc.FrontendStatus(context.TODO())
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"loadprt-test": "OPEN", "stats": "STOP"}, status)
+}
+
+func TestGetConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expConfig  string
+		expErr     error
+	}{
+		{"ok", http.StatusOK, `{"_version":3,"data":"# _version=3\nglobal\n  maxconn 200\n"}`, "# _version=3\nglobal\n  maxconn 200\n", nil},
+		{"unauthorized", http.StatusUnauthorized, "", "", ErrDataPlaneHTTPUnauthorized},
+		{"error", http.StatusInternalServerError, "", "", ErrDataPlaneHTTPError},
+	}
+
+	for _, tt := range tests {
+		tt := tt // linter
+
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				assert.True(t, strings.HasSuffix(req.URL.Path, "/services/haproxy/configuration/raw"))
+				assert.Equal(t, http.MethodGet, req.Method)
+
+				return &http.Response{
+					StatusCode: tt.statusCode,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+				}
+			})}
+
+			dc := Client{
+				client:  tc,
+				baseURL: "http://localhost:5555/v2",
+			}
+
+			config, err := dc.GetConfig(context.TODO())
+
+			if tt.expErr != nil {
+				assert.ErrorIs(t, err, tt.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expConfig, config)
+		})
+	}
+}
+
+func TestHAProxyVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expVersion string
+		expErr     error
+	}{
+		{"ok", http.StatusOK, `[{"info":{"version":"2.6.6-274d1a4","release_date":"2022/09/22"},"runtimeAPI":"/var/run/haproxy/haproxy.sock"}]`, "2.6.6-274d1a4", nil},
+		{"no version", http.StatusOK, `[{"info":{},"runtimeAPI":"/var/run/haproxy/haproxy.sock"}]`, "", ErrDataPlaneVersionUnknown},
+		{"unauthorized", http.StatusUnauthorized, "", "", ErrDataPlaneHTTPUnauthorized},
+		{"error", http.StatusInternalServerError, "", "", ErrDataPlaneHTTPError},
+	}
+
+	for _, tt := range tests {
+		tt := tt // linter
+
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				assert.True(t, strings.HasSuffix(req.URL.Path, "/services/haproxy/runtime/info"))
+				assert.Equal(t, http.MethodGet, req.Method)
+
+				return &http.Response{
+					StatusCode: tt.statusCode,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+				}
+			})}
+
+			dc := Client{
+				client:  tc,
+				baseURL: "http://localhost:5555/v2",
+			}
+
+			version, err := dc.HAProxyVersion(context.TODO())
+
+			if tt.expErr != nil {
+				assert.ErrorIs(t, err, tt.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expVersion, version)
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	dc := NewClient("http://localhost:5555/v2")
+
+	assert.NotPanics(t, func() {
+		dc.Close()
+		dc.Close()
+	})
+
+	// the client stays usable after closing its idle connections
+	dc.client.Transport = RoundTripFunc(func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	})
+
+	assert.True(t, dc.APIIsReady(context.TODO()))
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	t.Run("verified by default", func(t *testing.T) {
+		dc := NewClient(srv.URL)
+
+		assert.Nil(t, dc.client.Transport)
+		assert.False(t, dc.APIIsReady(context.TODO()))
+	})
+
+	t.Run("skipped when set", func(t *testing.T) {
+		dc := NewClient(srv.URL, WithInsecureSkipVerify(true))
+
+		transport, ok := dc.client.Transport.(*http.Transport)
+		require.True(t, ok)
+		require.NotNil(t, transport.TLSClientConfig)
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+
+		assert.True(t, dc.APIIsReady(context.TODO()))
+	})
+
+	t.Run("group", func(t *testing.T) {
+		g := NewGroup([]string{srv.URL}, WithGroupInsecureSkipVerify(true))
+
+		transport, ok := g.clients[0].client.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.True(t, transport.TLSClientConfig.InsecureSkipVerify)
+	})
+}
+
+func TestRuntimeServers(t *testing.T) {
+	tests := []struct {
+		name   string
+		call   func(dc Client) error
+		method string
+		path   string
+		body   string
+	}{
+		{
+			"add server",
+			func(dc Client) error {
+				return dc.AddRuntimeServer(context.TODO(), "loadprt-test", RuntimeServer{
+					Name:            "loadogn-test1",
+					Address:         "1.2.3.4",
+					Port:            2222,
+					Check:           true,
+					HealthCheckPort: 2222,
+				})
+			},
+			http.MethodPost,
+			"/v2/services/haproxy/runtime/servers?backend=loadprt-test",
+			`{"name":"loadogn-test1","address":"1.2.3.4","port":2222,"check":"enabled","health_check_port":2222}`,
+		},
+		{
+			"delete server",
+			func(dc Client) error {
+				return dc.DeleteRuntimeServer(context.TODO(), "loadprt-test", "loadogn-test1")
+			},
+			http.MethodDelete,
+			"/v2/services/haproxy/runtime/servers/loadogn-test1?backend=loadprt-test",
+			"",
+		},
+		{
+			"set server state",
+			func(dc Client) error {
+				return dc.SetRuntimeServerState(context.TODO(), "loadprt-test", "loadogn-test1", ServerStateMaint)
+			},
+			http.MethodPut,
+			"/v2/services/haproxy/runtime/servers/loadogn-test1?backend=loadprt-test",
+			`{"admin_state":"maint"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // linter
+
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				_, _, ok := req.BasicAuth()
+				if !ok {
+					t.Error("expected Basic Auth to be set, got", ok)
+				}
+
+				assert.Equal(t, tt.method, req.Method)
+				assert.Equal(t, tt.path, req.URL.RequestURI())
+
+				body, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.body, strings.TrimSpace(string(body)))
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+			})}
+
+			dc := Client{
+				client:  tc,
+				baseURL: "http://localhost:5555/v2",
+			}
+
+			require.NoError(t, tt.call(dc))
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	mu := sync.Mutex{}
+	requests := map[string]string{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests[r.Method+" "+r.URL.RequestURI()] = r.Header.Get(headerRequestID)
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+
+	call := func(ctx context.Context) {
+		_ = c.APIIsReady(ctx)
+		_ = c.CheckConfig(ctx, "cfg")
+		_ = c.PostConfig(ctx, "cfg")
+		_, _ = c.GetConfig(ctx)
+		_, _ = c.FrontendStatus(ctx)
+		_, _ = c.HAProxyVersion(ctx)
+		_ = c.AddRuntimeServer(ctx, "loadprt-test", RuntimeServer{Name: "loadogn-test", Address: "1.2.3.4", Port: 2222})
+		_ = c.SetRuntimeServerState(ctx, "loadprt-test", "loadogn-test", ServerStateMaint)
+		_ = c.DeleteRuntimeServer(ctx, "loadprt-test", "loadogn-test")
+	}
+
+	t.Run("sent from the context", func(t *testing.T) {
+		call(ContextWithRequestID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736"))
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		assert.Len(t, requests, 9)
+
+		for request, id := range requests {
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", id, request)
+		}
+	})
+
+	t.Run("not sent without one", func(t *testing.T) {
+		call(context.Background())
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for request, id := range requests {
+			assert.Empty(t, id, request)
+		}
+	})
+}
@@ -12,6 +12,25 @@ var (
 	// ErrDataPlaneHTTPError is returned when the http response is an error
 	ErrDataPlaneHTTPError = errors.New("dataplaneapi http error")
 
+	// ErrDataPlaneBusy is returned when the dataplaneapi is still overloaded, e.g. reloading haproxy,
+	// after retrying
+	ErrDataPlaneBusy = errors.New("dataplaneapi is busy")
+
 	// ErrDataPlaneConfigInvalid is returned when the config is invalid
 	ErrDataPlaneConfigInvalid = errors.New("dataplaneapi config is invalid")
+
+	// ErrDataPlaneConfigsDiverged is returned when the dataplaneapis of a group store different configs
+	ErrDataPlaneConfigsDiverged = errors.New("dataplaneapi configs diverged")
+
+	// ErrDataPlaneVersionsDiverged is returned when the dataplaneapis of a group run different haproxy versions
+	ErrDataPlaneVersionsDiverged = errors.New("dataplaneapi haproxy versions diverged")
+
+	// ErrDataPlaneVersionUnknown is returned when the runtime api doesn't report the haproxy version
+	ErrDataPlaneVersionUnknown = errors.New("dataplaneapi haproxy version unknown")
+
+	// ErrDataPlaneQuorumNotReached is returned when too few dataplaneapis of a group succeed
+	ErrDataPlaneQuorumNotReached = errors.New("dataplaneapi quorum not reached")
+
+	// ErrDataPlaneGroupEmpty is returned by the calls of a group without dataplaneapis
+	ErrDataPlaneGroupEmpty = errors.New("dataplaneapi group has no dataplaneapis")
 )
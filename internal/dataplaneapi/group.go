@@ -0,0 +1,313 @@
+package dataplaneapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const frontendStatusOpen = "OPEN"
+
+// Group sends each call to the dataplaneapi of every haproxy in a group, such as an active/active
+// pair, succeeding when a quorum of them succeed
+type Group struct {
+	clients            []*Client
+	quorum             int
+	logger             *zap.SugaredLogger
+	insecureSkipVerify bool
+}
+
+// GroupOption configures a group option.
+type GroupOption func(g *Group)
+
+// NewGroup returns a group calling the dataplaneapi at each of the urls. By default every
+// dataplaneapi must succeed.
+func NewGroup(urls []string, options ...GroupOption) *Group {
+	g := &Group{
+		logger: zap.NewNop().Sugar(),
+	}
+
+	for _, opt := range options {
+		opt(g)
+	}
+
+	for _, url := range urls {
+		g.clients = append(g.clients, NewClient(url, WithLogger(g.logger), WithInsecureSkipVerify(g.insecureSkipVerify)))
+	}
+
+	return g
+}
+
+// WithGroupLogger sets the logger for the group and its clients
+func WithGroupLogger(logger *zap.SugaredLogger) GroupOption {
+	return func(g *Group) {
+		g.logger = logger
+	}
+}
+
+// WithQuorum sets the number of dataplaneapis which must succeed for a call to succeed. A quorum
+// of 0, or more than the number of dataplaneapis, requires all of them to succeed.
+func WithQuorum(quorum int) GroupOption {
+	return func(g *Group) {
+		g.quorum = quorum
+	}
+}
+
+// WithGroupInsecureSkipVerify skips verifying the certificates of the dataplaneapis of the group,
+// as WithInsecureSkipVerify does for a client
+func WithGroupInsecureSkipVerify(skip bool) GroupOption {
+	return func(g *Group) {
+		g.insecureSkipVerify = skip
+	}
+}
+
+// Close closes the idle connections of every client in the group
+func (g *Group) Close() {
+	for _, c := range g.clients {
+		c.Close()
+	}
+}
+
+// required returns the number of clients which must succeed
+func (g *Group) required() int {
+	if g.quorum <= 0 || g.quorum > len(g.clients) {
+		return len(g.clients)
+	}
+
+	return g.quorum
+}
+
+// each calls fn for every client concurrently, returning an error joining the failures when fewer
+// than the quorum succeed
+func (g *Group) each(ctx context.Context, fn func(ctx context.Context, c *Client) error) error {
+	// nothing succeeding can't reach a quorum, though none is required
+	if len(g.clients) == 0 {
+		return ErrDataPlaneGroupEmpty
+	}
+
+	errs := make([]error, len(g.clients))
+
+	wg := &sync.WaitGroup{}
+
+	for i, c := range g.clients {
+		wg.Add(1)
+
+		go func(i int, c *Client) {
+			defer wg.Done()
+
+			if err := fn(ctx, c); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", c.baseURL, err)
+			}
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	failed := []error{}
+
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	if len(g.clients)-len(failed) >= g.required() {
+		if len(failed) > 0 {
+			g.logger.Warnw("dataplaneapi quorum reached with failures", "error", errors.Join(failed...))
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrDataPlaneQuorumNotReached, errors.Join(failed...))
+}
+
+// APIIsReady returns true when a quorum of the dataplaneapis are ready
+func (g *Group) APIIsReady(ctx context.Context) bool {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		if !c.APIIsReady(ctx) {
+			return ErrDataPlaneNotReady
+		}
+
+		return nil
+	}) == nil
+}
+
+// CheckConfig validates the proposed config with each dataplaneapi
+func (g *Group) CheckConfig(ctx context.Context, config string) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.CheckConfig(ctx, config)
+	})
+}
+
+// PostConfig pushes a new haproxy config to each dataplaneapi
+func (g *Group) PostConfig(ctx context.Context, config string) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.PostConfig(ctx, config)
+	})
+}
+
+// PostConfigWithoutReload pushes a new haproxy config to each dataplaneapi without reloading haproxy
+func (g *Group) PostConfigWithoutReload(ctx context.Context, config string) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.PostConfigWithoutReload(ctx, config)
+	})
+}
+
+// AddRuntimeServer adds a server to a backend of each running haproxy
+func (g *Group) AddRuntimeServer(ctx context.Context, backend string, server RuntimeServer) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.AddRuntimeServer(ctx, backend, server)
+	})
+}
+
+// DeleteRuntimeServer removes a server from a backend of each running haproxy
+func (g *Group) DeleteRuntimeServer(ctx context.Context, backend, name string) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.DeleteRuntimeServer(ctx, backend, name)
+	})
+}
+
+// SetRuntimeServerState sets the admin state of a server of each running haproxy
+func (g *Group) SetRuntimeServerState(ctx context.Context, backend, name, state string) error {
+	return g.each(ctx, func(ctx context.Context, c *Client) error {
+		return c.SetRuntimeServerState(ctx, backend, name, state)
+	})
+}
+
+// FrontendStatus returns the status of each frontend across the dataplaneapis which responded. A
+// frontend is only reported open when it is open on all of them.
+func (g *Group) FrontendStatus(ctx context.Context) (map[string]string, error) {
+	mu := sync.Mutex{}
+	status := map[string]string{}
+
+	err := g.each(ctx, func(ctx context.Context, c *Client) error {
+		s, err := c.FrontendStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for name, st := range s {
+			if current, ok := status[name]; !ok || current == frontendStatusOpen {
+				status[name] = st
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// GetConfig returns the config stored by the dataplaneapis which responded.
+// ErrDataPlaneConfigsDiverged is returned when their configs differ.
+func (g *Group) GetConfig(ctx context.Context) (string, error) {
+	mu := sync.Mutex{}
+	configs := map[string]string{}
+
+	err := g.each(ctx, func(ctx context.Context, c *Client) error {
+		config, err := c.GetConfig(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		configs[c.baseURL] = config
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	config, url := "", ""
+
+	for _, c := range g.clients {
+		live, ok := configs[c.baseURL]
+		if !ok {
+			continue
+		}
+
+		if url != "" && live != config {
+			return "", fmt.Errorf("%w: %s and %s", ErrDataPlaneConfigsDiverged, url, c.baseURL)
+		}
+
+		config, url = live, c.baseURL
+	}
+
+	return config, nil
+}
+
+// HAProxyVersion returns the haproxy version run behind the dataplaneapis which responded.
+// ErrDataPlaneVersionsDiverged is returned when their versions differ.
+func (g *Group) HAProxyVersion(ctx context.Context) (string, error) {
+	mu := sync.Mutex{}
+	versions := map[string]string{}
+
+	err := g.each(ctx, func(ctx context.Context, c *Client) error {
+		version, err := c.HAProxyVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		versions[c.baseURL] = version
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	version, url := "", ""
+
+	for _, c := range g.clients {
+		v, ok := versions[c.baseURL]
+		if !ok {
+			continue
+		}
+
+		if url != "" && v != version {
+			return "", fmt.Errorf("%w: %s and %s", ErrDataPlaneVersionsDiverged, url, c.baseURL)
+		}
+
+		version, url = v, c.baseURL
+	}
+
+	return version, nil
+}
+
+// WaitForDataPlaneReady waits for a quorum of the dataplaneapis to be ready
+func (g *Group) WaitForDataPlaneReady(ctx context.Context, retries int, sleep time.Duration) error {
+	for i := 0; i < retries; i++ {
+		select {
+		case <-ctx.Done():
+			g.logger.Info("context done")
+			return nil
+		default:
+			if g.APIIsReady(ctx) {
+				g.logger.Info("dataplaneapis are ready")
+				return nil
+			}
+
+			g.logger.Info("waiting for dataplaneapis to become ready")
+			time.Sleep(sleep)
+		}
+	}
+
+	return ErrDataPlaneNotReady
+}
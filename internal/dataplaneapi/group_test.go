@@ -0,0 +1,171 @@
+package dataplaneapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestGroup returns a group with a client responding with each status code
+func newTestGroup(quorum int, statusCodes ...int) *Group {
+	g := &Group{quorum: quorum, logger: zap.NewNop().Sugar()}
+
+	for _, code := range statusCodes {
+		code := code
+
+		g.clients = append(g.clients, &Client{
+			client: &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				return &http.Response{StatusCode: code}
+			})},
+			baseURL: "http://localhost:5555/v2",
+			logger:  zap.NewNop().Sugar(),
+		})
+	}
+
+	return g
+}
+
+func TestGroupPostConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		quorum      int
+		statusCodes []int
+		expErr      error
+	}{
+		{"all succeed", 0, []int{http.StatusAccepted, http.StatusAccepted}, nil},
+		{"partial failure", 0, []int{http.StatusAccepted, http.StatusInternalServerError}, ErrDataPlaneQuorumNotReached},
+		{"quorum reached", 1, []int{http.StatusAccepted, http.StatusInternalServerError}, nil},
+		{"quorum not reached", 2, []int{http.StatusAccepted, http.StatusUnauthorized, http.StatusInternalServerError}, ErrDataPlaneQuorumNotReached},
+		{"quorum above group size requires all", 3, []int{http.StatusAccepted, http.StatusInternalServerError}, ErrDataPlaneQuorumNotReached},
+	}
+
+	for _, tt := range tests {
+		tt := tt // linter
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestGroup(tt.quorum, tt.statusCodes...).PostConfig(context.TODO(), "cfg")
+
+			if tt.expErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.expErr)
+			assert.ErrorIs(t, err, ErrDataPlaneHTTPError)
+		})
+	}
+}
+
+func TestGroupFrontendStatus(t *testing.T) {
+	bodies := []string{
+		`[{"stats":[{"name":"loadprt-test","type":"frontend","stats":{"status":"OPEN"}}]}]`,
+		`[{"stats":[{"name":"loadprt-test","type":"frontend","stats":{"status":"STOP"}}]}]`,
+	}
+
+	g := &Group{logger: zap.NewNop().Sugar()}
+
+	for _, body := range bodies {
+		body := body
+
+		g.clients = append(g.clients, &Client{
+			client: &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+			})},
+			baseURL: "http://localhost:5555/v2",
+		})
+	}
+
+	status, err := g.FrontendStatus(context.TODO())
+	require.NoError(t, err)
+
+	// stopped on one of the pair
+	assert.Equal(t, map[string]string{"loadprt-test": "STOP"}, status)
+}
+
+func TestGroupGetConfig(t *testing.T) {
+	newGroup := func(configs ...string) *Group {
+		g := &Group{logger: zap.NewNop().Sugar()}
+
+		for i, config := range configs {
+			body := fmt.Sprintf(`{"_version":1,"data":%q}`, config)
+
+			g.clients = append(g.clients, &Client{
+				client: &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+				})},
+				baseURL: fmt.Sprintf("http://haproxy-%d:5555/v2", i),
+			})
+		}
+
+		return g
+	}
+
+	t.Run("same configs", func(t *testing.T) {
+		config, err := newGroup("global\n", "global\n").GetConfig(context.TODO())
+		require.NoError(t, err)
+
+		assert.Equal(t, "global\n", config)
+	})
+
+	t.Run("diverged configs", func(t *testing.T) {
+		_, err := newGroup("global\n", "global\n  maxconn 200\n").GetConfig(context.TODO())
+		assert.ErrorIs(t, err, ErrDataPlaneConfigsDiverged)
+	})
+}
+
+func TestGroupHAProxyVersion(t *testing.T) {
+	newGroup := func(versions ...string) *Group {
+		g := &Group{logger: zap.NewNop().Sugar()}
+
+		for i, version := range versions {
+			body := fmt.Sprintf(`[{"info":{"version":%q}}]`, version)
+
+			g.clients = append(g.clients, &Client{
+				client: &http.Client{Transport: RoundTripFunc(func(req *http.Request) *http.Response {
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+				})},
+				baseURL: fmt.Sprintf("http://haproxy-%d:5555/v2", i),
+			})
+		}
+
+		return g
+	}
+
+	t.Run("same versions", func(t *testing.T) {
+		version, err := newGroup("2.6.6", "2.6.6").HAProxyVersion(context.TODO())
+		require.NoError(t, err)
+
+		assert.Equal(t, "2.6.6", version)
+	})
+
+	t.Run("diverged versions", func(t *testing.T) {
+		_, err := newGroup("2.6.6", "2.8.1").HAProxyVersion(context.TODO())
+		assert.ErrorIs(t, err, ErrDataPlaneVersionsDiverged)
+	})
+}
+
+func TestGroupClose(t *testing.T) {
+	g := NewGroup([]string{"http://localhost:5555/v2", "http://localhost:5556/v2"})
+
+	assert.NotPanics(t, func() {
+		g.Close()
+		g.Close()
+	})
+
+	assert.NotPanics(t, NewGroup(nil).Close)
+}
+
+func TestGroupEmpty(t *testing.T) {
+	g := NewGroup(nil)
+
+	assert.ErrorIs(t, g.CheckConfig(context.TODO(), "global"), ErrDataPlaneGroupEmpty)
+	assert.ErrorIs(t, g.PostConfig(context.TODO(), "global"), ErrDataPlaneGroupEmpty)
+	assert.False(t, g.APIIsReady(context.TODO()))
+}
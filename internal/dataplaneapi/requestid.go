@@ -0,0 +1,29 @@
+package dataplaneapi
+
+import (
+	"context"
+	"net/http"
+)
+
+const headerRequestID = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the request id sent with dataplaneapi requests
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by the context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// setRequestID sets the request id header from the request's context, when it carries one
+func setRequestID(req *http.Request) {
+	if id := RequestIDFromContext(req.Context()); id != "" {
+		req.Header.Set(headerRequestID, id)
+	}
+}
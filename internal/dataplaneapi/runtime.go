@@ -0,0 +1,127 @@
+package dataplaneapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// ServerStateReady is the runtime admin state of a server receiving connections
+	ServerStateReady = "ready"
+
+	// ServerStateMaint is the runtime admin state of a server in maintenance
+	ServerStateMaint = "maint"
+
+	runtimeEnabled = "enabled"
+)
+
+// RuntimeServer is a server added to a backend through the runtime api
+type RuntimeServer struct {
+	Name            string
+	Address         string
+	Port            int
+	Check           bool
+	HealthCheckPort int
+	Backup          bool
+	Maintenance     bool
+}
+
+// runtimeAddServer is the request body for adding a server through the runtime api
+type runtimeAddServer struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	Port            int    `json:"port,omitempty"`
+	Check           string `json:"check,omitempty"`
+	HealthCheckPort int    `json:"health_check_port,omitempty"`
+	Backup          string `json:"backup,omitempty"`
+	Maintenance     string `json:"maintenance,omitempty"`
+}
+
+// runtimeServerState is the request body for changing the state of a server through the runtime api
+type runtimeServerState struct {
+	AdminState string `json:"admin_state"`
+}
+
+// AddRuntimeServer adds a server to a backend of the running haproxy, without a reload
+func (c *Client) AddRuntimeServer(ctx context.Context, backend string, server RuntimeServer) error {
+	body := runtimeAddServer{
+		Name:            server.Name,
+		Address:         server.Address,
+		Port:            server.Port,
+		HealthCheckPort: server.HealthCheckPort,
+		Check:           enabled(server.Check),
+		Backup:          enabled(server.Backup),
+		Maintenance:     enabled(server.Maintenance),
+	}
+
+	return c.runtimeRequest(ctx, http.MethodPost, "/services/haproxy/runtime/servers", backend, body)
+}
+
+// DeleteRuntimeServer removes a server from a backend of the running haproxy, without a reload.
+// haproxy only deletes servers in maintenance.
+func (c *Client) DeleteRuntimeServer(ctx context.Context, backend, name string) error {
+	return c.runtimeRequest(ctx, http.MethodDelete, "/services/haproxy/runtime/servers/"+url.PathEscape(name), backend, nil)
+}
+
+// SetRuntimeServerState sets the admin state, ServerStateReady or ServerStateMaint, of a server of the
+// running haproxy
+func (c *Client) SetRuntimeServerState(ctx context.Context, backend, name, state string) error {
+	body := runtimeServerState{AdminState: state}
+
+	return c.runtimeRequest(ctx, http.MethodPut, "/services/haproxy/runtime/servers/"+url.PathEscape(name), backend, body)
+}
+
+// runtimeRequest sends a request to a runtime server endpoint for a backend, with an optional json body
+func (c *Client) runtimeRequest(ctx context.Context, method, path, backend string, body any) error {
+	reqURL := c.baseURL + path + "?backend=" + url.QueryEscape(backend)
+
+	buf := &bytes.Buffer{}
+
+	if body != nil {
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, buf)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(viper.GetString("dataplane.user.name"), viper.GetString("dataplane.user.pwd"))
+	setRequestID(req)
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrDataPlaneHTTPUnauthorized
+	default:
+		return ErrDataPlaneHTTPError
+	}
+}
+
+// enabled returns the runtime api value of an enabled option, or nothing when it isn't set
+func enabled(b bool) string {
+	if b {
+		return runtimeEnabled
+	}
+
+	return ""
+}
@@ -0,0 +1,84 @@
+package dataplaneapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodySize is the most of an error response body read, haproxy's output can be long
+const maxErrorBodySize = 64 << 10
+
+// configLineRegex matches the file and line haproxy reports a config error at, e.g. `[/etc/haproxy/haproxy.cfg:12]`
+var configLineRegex = regexp.MustCompile(`\[[^\]\s]+:(\d+)\]`)
+
+// DataPlaneValidationError is a config rejected by the dataplaneapi, with the code and message of
+// its error response. Line is the line of the config haproxy reported the error at, or 0 when the
+// message doesn't include one. It wraps ErrDataPlaneConfigInvalid.
+type DataPlaneValidationError struct {
+	Code    int
+	Message string
+	Line    int
+}
+
+// Error returns the error with the dataplaneapi's message
+func (e *DataPlaneValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: line %d: %s", ErrDataPlaneConfigInvalid, e.Line, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", ErrDataPlaneConfigInvalid, e.Message)
+}
+
+// Unwrap returns ErrDataPlaneConfigInvalid, so callers can check for it with errors.Is
+func (e *DataPlaneValidationError) Unwrap() error {
+	return ErrDataPlaneConfigInvalid
+}
+
+// validationError returns the error for a config rejected with the response body, which is a
+// json object with a code and message. Bodies which aren't are used as the message, and
+// ErrDataPlaneConfigInvalid is returned when there is no body.
+func validationError(statusCode int, body io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+		return ErrDataPlaneConfigInvalid
+	}
+
+	resp := struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{}
+
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Message == "" {
+		resp.Code, resp.Message = statusCode, strings.TrimSpace(string(data))
+	}
+
+	if resp.Code == 0 {
+		resp.Code = statusCode
+	}
+
+	validationErr := &DataPlaneValidationError{
+		Code:    resp.Code,
+		Message: resp.Message,
+	}
+
+	validationErr.Line = ConfigLine(resp.Message)
+
+	return validationErr
+}
+
+// ConfigLine returns the config line of the first error haproxy reported in the message, or 0
+// when it doesn't include one
+func ConfigLine(message string) int {
+	match := configLineRegex.FindStringSubmatch(message)
+	if match == nil {
+		return 0
+	}
+
+	line, _ := strconv.Atoi(match[1])
+
+	return line
+}
@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+)
+
+// ApplyForLB fetches, merges, checks and posts the config of a loadbalancer other than the managed
+// one, e.g. to recover a haproxy from the CLI, without changing ManagedLBID. It is serialized with
+// the updates of the managed loadbalancer, and the next event or reconcile restores its config. The
+// managed loadbalancer is updated as usual.
+func (m *Manager) ApplyForLB(ctx context.Context, id gidx.PrefixedID) (err error) {
+	if m.DataPlaneClient == nil {
+		return errDataPlaneClientNotInitialized
+	}
+
+	if m.LBClient == nil {
+		return errLBClientNotInitialized
+	}
+
+	if id != "" && id == m.ManagedLBID {
+		_, err = m.updateConfigToLatest(ctx)
+
+		return err
+	}
+
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	m.logger(ctx).Infow("applying haproxy config for a loadbalancer on demand",
+		zap.String("loadbalancerID", id.String()),
+		zap.String("managedLoadbalancerID", m.ManagedLBID.String()))
+
+	cfg, lb, err := m.desiredConfigFor(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	config := m.render(cfg, lb)
+	previous := m.CurrentConfig()
+
+	defer func() {
+		m.recordApply(ctx, id.String(), previous, config, err)
+	}()
+
+	if err := m.checkConfigSize(config); err != nil {
+		return err
+	}
+
+	crtLists, err := m.stageCrtLists(lb)
+	if err != nil {
+		return err
+	}
+
+	// the lists are in place when haproxy checks the config, and restored when it isn't applied
+	defer func() {
+		if err != nil {
+			m.restoreCrtLists(ctx, crtLists)
+		}
+	}()
+
+	if err := crtLists.install(); err != nil {
+		return err
+	}
+
+	if !m.SkipCheck {
+		if err := m.DataPlaneClient.CheckConfig(ctx, config); err != nil {
+			m.logValidationError(ctx, config, err)
+
+			return err
+		}
+	}
+
+	if err := m.DataPlaneClient.PostConfig(ctx, config); err != nil {
+		return err
+	}
+
+	m.logger(ctx).Infow("config successfully applied on demand", zap.String("loadbalancerID", id.String()))
+
+	// record what haproxy now runs, so runtime updates don't diff against the managed config and
+	// the reload counts towards the minimum reload interval
+	m.setCurrentConfig(id.String(), config)
+	m.lastApplied = time.Now()
+
+	return nil
+}
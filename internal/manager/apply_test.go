@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+func TestApplyForLB(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	newManager := func(requested *[]string, posted *[]string) *Manager {
+		return &Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					*posted = append(*posted, config)
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					*requested = append(*requested, id)
+
+					if id == "loadbal-other" {
+						return &mergeTestData2, nil
+					}
+
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath: testBaseCfgPath,
+			ManagedLBID: gidx.PrefixedID("loadbal-test"),
+		}
+	}
+
+	t.Run("non-managed loadbalancer", func(t *testing.T) {
+		requested, posted := []string{}, []string{}
+		mgr := newManager(&requested, &posted)
+
+		require.NoError(t, mgr.ApplyForLB(context.Background(), gidx.PrefixedID("loadbal-other")))
+
+		assert.Equal(t, []string{"loadbal-other"}, requested)
+		require.Len(t, posted, 1)
+		assert.Contains(t, posted[0], "server loadogn-test4 ")
+		assert.Equal(t, gidx.PrefixedID("loadbal-test"), mgr.ManagedLBID, "managed loadbalancer changed")
+		assert.Equal(t, posted[0], mgr.CurrentConfig())
+
+		// the managed loadbalancer's config is restored by the next update
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"loadbal-other", "loadbal-test"}, requested)
+		require.Len(t, posted, 2)
+		assert.NotContains(t, posted[1], "loadogn-test4")
+	})
+
+	t.Run("managed loadbalancer is updated as usual", func(t *testing.T) {
+		requested, posted := []string{}, []string{}
+		mgr := newManager(&requested, &posted)
+
+		require.NoError(t, mgr.ApplyForLB(context.Background(), mgr.ManagedLBID))
+
+		assert.Equal(t, []string{"loadbal-test"}, requested)
+		assert.Len(t, posted, 1)
+	})
+
+	t.Run("empty id", func(t *testing.T) {
+		requested, posted := []string{}, []string{}
+		mgr := newManager(&requested, &posted)
+
+		assert.ErrorIs(t, mgr.ApplyForLB(context.Background(), ""), errLoadBalancerIDParamInvalid)
+		assert.Empty(t, posted)
+	})
+}
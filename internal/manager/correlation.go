@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"context"
+	"strings"
+
+	"go.infratographer.com/x/events"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	lbclient "go.infratographer.com/loadbalancer-manager-haproxy/pkg/lbapi"
+)
+
+// traceparentHeader is the trace context entry of W3C trace context propagation
+const traceparentHeader = "traceparent"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the correlation id of an update. It is logged with
+// each line of the update, and sent as the request id of the loadbalancer api and dataplaneapi
+// requests.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, correlationIDKey{}, id)
+	ctx = dataplaneapi.ContextWithRequestID(ctx, id)
+
+	return lbclient.ContextWithRequestID(ctx, id)
+}
+
+// CorrelationID returns the correlation id carried by the context, if any. A manager without a
+// context, e.g. in tests, has none.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+
+	return id
+}
+
+// correlationID returns the correlation id of a change message: the trace id of its trace
+// context, so the update can be followed along with the change which caused it, or the id of the
+// message when it wasn't traced
+func correlationID(msg events.ChangeMessage, messageID string) string {
+	// traceparent is version-traceid-spanid-flags
+	parts := strings.Split(msg.TraceContext[traceparentHeader], "-")
+	if len(parts) == 4 && len(parts[1]) == 32 && strings.Trim(parts[1], "0") != "" {
+		return parts[1]
+	}
+
+	return messageID
+}
+
+// logger returns the logger for an update, with its correlation id when the context carries one
+func (m *Manager) logger(ctx context.Context) *zap.SugaredLogger {
+	if id := CorrelationID(ctx); id != "" {
+		return m.Logger.With(zap.String("correlationID", id))
+	}
+
+	return m.Logger
+}
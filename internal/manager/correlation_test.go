@@ -0,0 +1,88 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+	lbclient "go.infratographer.com/loadbalancer-manager-haproxy/pkg/lbapi"
+)
+
+func TestCorrelationIDFromMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		traceContext map[string]string
+		expID        string
+	}{
+		{"traced message", map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"untraced message", nil, "msg-1234"},
+		{"malformed traceparent", map[string]string{"traceparent": "4bf92f3577b34da6"}, "msg-1234"},
+		{"invalid trace id", map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"}, "msg-1234"},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expID, correlationID(events.ChangeMessage{TraceContext: tt.traceContext}, "msg-1234"))
+		})
+	}
+}
+
+func TestCorrelationIDLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	var lbRequestID, checkID string
+
+	mgr := &Manager{
+		Context: context.Background(),
+		Logger:  zap.New(core).Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				checkID = dataplaneapi.RequestIDFromContext(ctx)
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				lbRequestID = lbclient.RequestIDFromContext(ctx)
+				return &mergeTestData1, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+	}
+
+	ctx := WithCorrelationID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	_, err := mgr.updateConfigToLatest(ctx)
+	require.NoError(t, err)
+
+	// the id reaches the loadbalancer api and dataplaneapi calls
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", lbRequestID)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", checkID)
+
+	require.NotZero(t, logs.Len())
+
+	for _, entry := range logs.All() {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entry.ContextMap()["correlationID"], entry.Message)
+	}
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// LoadBalancerSummary is a structured summary of the desired state of a loadbalancer, as
+// requested from lbapi, independent of the haproxy config generated for it
+type LoadBalancerSummary struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Location string        `json:"location,omitempty"`
+	Ports    []PortSummary `json:"ports"`
+}
+
+// PortSummary is a port of a loadbalancer and its pools
+type PortSummary struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Number int64         `json:"number"`
+	Pools  []PoolSummary `json:"pools"`
+}
+
+// PoolSummary is a pool of a port and the number of its origins, by state
+type PoolSummary struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Origins  int    `json:"origins"`
+	Active   int    `json:"active"`
+	Inactive int    `json:"inactive"`
+}
+
+// Describe returns the ports, pools and origin counts of the loadbalancer, in the order lbapi
+// returned them
+func Describe(lb *lbapi.LoadBalancer) LoadBalancerSummary {
+	summary := LoadBalancerSummary{
+		ID:       lb.ID,
+		Name:     lb.Name,
+		Location: lb.Location.ID,
+		Ports:    []PortSummary{},
+	}
+
+	for _, p := range lb.Ports.Edges {
+		port := PortSummary{ID: p.Node.ID, Name: p.Node.Name, Number: p.Node.Number, Pools: []PoolSummary{}}
+
+		for _, pool := range p.Node.Pools {
+			ps := PoolSummary{ID: pool.ID, Name: pool.Name, Protocol: pool.Protocol, Origins: len(pool.Origins.Edges)}
+
+			for _, origin := range pool.Origins.Edges {
+				if origin.Node.Active {
+					ps.Active++
+				} else {
+					ps.Inactive++
+				}
+			}
+
+			port.Pools = append(port.Pools, ps)
+		}
+
+		summary.Ports = append(summary.Ports, port)
+	}
+
+	return summary
+}
@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/haproxytech/config-parser/v4/types"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// minServerFields is the number of fields in a server line: server, its name and address
+const minServerFields = 3
+
+type serverOp int
+
+const (
+	serverAdd serverOp = iota
+	serverRemove
+	serverEnable
+	serverDisable
+)
+
+// serverChange is a change to a single server, which can be applied through the runtime api
+type serverChange struct {
+	op      serverOp
+	backend string
+	server  types.Server
+}
+
+// diffServers returns the server changes between the applied and the desired config. It returns
+// false when the configs differ in more than their servers, or a server change can't be applied
+// at runtime, and the desired config must be applied with a reload.
+func diffServers(current, desired string) ([]serverChange, bool) {
+	current, desired = haproxyconfig.StripAnnotations(current), haproxyconfig.StripAnnotations(desired)
+
+	if stripServers(current) != stripServers(desired) {
+		return nil, false
+	}
+
+	currentServers := backendServers(current)
+	desiredServers := backendServers(desired)
+
+	changes := []serverChange{}
+
+	for backend, servers := range currentServers {
+		for name, srv := range servers {
+			if _, ok := desiredServers[backend][name]; !ok {
+				changes = append(changes, serverChange{op: serverRemove, backend: backend, server: srv})
+			}
+		}
+	}
+
+	for backend, servers := range desiredServers {
+		for name, srv := range servers {
+			cur, ok := currentServers[backend][name]
+
+			switch {
+			case !ok:
+				if _, ok := runtimeServer(srv); !ok {
+					return nil, false
+				}
+
+				changes = append(changes, serverChange{op: serverAdd, backend: backend, server: srv})
+			case cur.Address == srv.Address:
+				continue
+			case trimDisabled(cur.Address) != trimDisabled(srv.Address):
+				// anything but the state changed, the server can't be modified at runtime
+				return nil, false
+			case disabled(srv.Address):
+				changes = append(changes, serverChange{op: serverDisable, backend: backend, server: srv})
+			default:
+				changes = append(changes, serverChange{op: serverEnable, backend: backend, server: srv})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].backend != changes[j].backend {
+			return changes[i].backend < changes[j].backend
+		}
+
+		return changes[i].server.Name < changes[j].server.Name
+	})
+
+	return changes, true
+}
+
+// stripServers returns the config without its server lines
+func stripServers(cfg string) string {
+	lines := []string{}
+
+	for _, line := range strings.Split(cfg, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "server ") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// backendServers returns the servers of each backend in the rendered config, keyed by backend and
+// server name. The server address holds everything after the name, as it does when generated.
+func backendServers(cfg string) map[string]map[string]types.Server {
+	servers := map[string]map[string]types.Server{}
+	backend := ""
+
+	for _, line := range strings.Split(cfg, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			backend = ""
+
+			if fields[0] == "backend" && len(fields) > 1 {
+				backend = fields[1]
+				servers[backend] = map[string]types.Server{}
+			}
+
+			continue
+		}
+
+		if backend == "" || fields[0] != "server" || len(fields) < minServerFields {
+			continue
+		}
+
+		servers[backend][fields[1]] = types.Server{Name: fields[1], Address: strings.Join(fields[2:], " ")}
+	}
+
+	return servers
+}
+
+// runtimeServer converts a server generated by mergeBackend, e.g. `1.2.3.4:2222 check port 2222`,
+// to a runtime api server. It returns false for servers with params the runtime api can't set.
+func runtimeServer(srv types.Server) (dataplaneapi.RuntimeServer, bool) {
+	fields := strings.Fields(srv.Address)
+	if len(fields) == 0 {
+		return dataplaneapi.RuntimeServer{}, false
+	}
+
+	host, port, err := net.SplitHostPort(fields[0])
+	if err != nil {
+		return dataplaneapi.RuntimeServer{}, false
+	}
+
+	rs := dataplaneapi.RuntimeServer{Name: srv.Name, Address: host}
+
+	if rs.Port, err = strconv.Atoi(port); err != nil {
+		return dataplaneapi.RuntimeServer{}, false
+	}
+
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "check":
+			rs.Check = true
+		case "backup":
+			rs.Backup = true
+		case "disabled":
+			rs.Maintenance = true
+		case "port":
+			i++
+
+			if i == len(fields) {
+				return dataplaneapi.RuntimeServer{}, false
+			}
+
+			if rs.HealthCheckPort, err = strconv.Atoi(fields[i]); err != nil {
+				return dataplaneapi.RuntimeServer{}, false
+			}
+		default:
+			return dataplaneapi.RuntimeServer{}, false
+		}
+	}
+
+	return rs, true
+}
+
+// disabled returns true when the server address has the disabled param
+func disabled(address string) bool {
+	for _, f := range strings.Fields(address) {
+		if f == "disabled" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trimDisabled returns the server address without the disabled param
+func trimDisabled(address string) string {
+	fields := []string{}
+
+	for _, f := range strings.Fields(address) {
+		if f != "disabled" {
+			fields = append(fields, f)
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haproxytech/config-parser/v4/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+const testDiffCfg = `frontend loadprt-test
+  bind ipv4@:22
+  use_backend loadprt-test
+
+backend loadprt-test
+  server loadogn-test1 1.2.3.4:2222 check port 2222
+  server loadogn-test2 1.2.3.4:222 check port 222
+`
+
+func TestDiffServers(t *testing.T) {
+	tests := []struct {
+		name      string
+		desired   string
+		changes   []serverChange
+		runtimeOK bool
+	}{
+		{"no changes", testDiffCfg, []serverChange{}, true},
+		{
+			"server added",
+			testDiffCfg + "  server loadogn-test3 4.3.2.1:2222 check port 2222\n",
+			[]serverChange{
+				{serverAdd, "loadprt-test", types.Server{Name: "loadogn-test3", Address: "4.3.2.1:2222 check port 2222"}},
+			},
+			true,
+		},
+		{
+			"server removed",
+			"frontend loadprt-test\n  bind ipv4@:22\n  use_backend loadprt-test\n\nbackend loadprt-test\n  server loadogn-test1 1.2.3.4:2222 check port 2222\n",
+			[]serverChange{
+				{serverRemove, "loadprt-test", types.Server{Name: "loadogn-test2", Address: "1.2.3.4:222 check port 222"}},
+			},
+			true,
+		},
+		{
+			"server disabled",
+			"frontend loadprt-test\n  bind ipv4@:22\n  use_backend loadprt-test\n\nbackend loadprt-test\n" +
+				"  server loadogn-test1 1.2.3.4:2222 check port 2222 disabled\n  server loadogn-test2 1.2.3.4:222 check port 222\n",
+			[]serverChange{
+				{serverDisable, "loadprt-test", types.Server{Name: "loadogn-test1", Address: "1.2.3.4:2222 check port 2222 disabled"}},
+			},
+			true,
+		},
+		{
+			"server address changed",
+			"frontend loadprt-test\n  bind ipv4@:22\n  use_backend loadprt-test\n\nbackend loadprt-test\n" +
+				"  server loadogn-test1 5.6.7.8:2222 check port 2222\n  server loadogn-test2 1.2.3.4:222 check port 222\n",
+			nil,
+			false,
+		},
+		{
+			"frontend changed",
+			"frontend loadprt-test\n  bind ipv4@:2222\n  use_backend loadprt-test\n\nbackend loadprt-test\n" +
+				"  server loadogn-test1 1.2.3.4:2222 check port 2222\n  server loadogn-test2 1.2.3.4:222 check port 222\n",
+			nil,
+			false,
+		},
+		{
+			"added server with params the runtime api can't set",
+			testDiffCfg + "  server loadogn-test3 4.3.2.1:2222 check port 2222 weight 10\n",
+			nil,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			changes, ok := diffServers(testDiffCfg, tt.desired)
+			require.Equal(t, tt.runtimeOK, ok)
+			assert.Equal(t, tt.changes, changes)
+		})
+	}
+
+	t.Run("server enabled", func(t *testing.T) {
+		t.Parallel()
+
+		current := testDiffCfg + "  server loadogn-test3 4.3.2.1:2222 check port 2222 disabled\n"
+		desired := testDiffCfg + "  server loadogn-test3 4.3.2.1:2222 check port 2222\n"
+
+		changes, ok := diffServers(current, desired)
+		require.True(t, ok)
+		assert.Equal(t, []serverChange{
+			{serverEnable, "loadprt-test", types.Server{Name: "loadogn-test3", Address: "4.3.2.1:2222 check port 2222"}},
+		}, changes)
+	})
+}
+
+func TestRuntimeServer(t *testing.T) {
+	rs, ok := runtimeServer(types.Server{Name: "loadogn-test1", Address: "1.2.3.4:2222 check port 2222 backup disabled"})
+	require.True(t, ok)
+
+	assert.Equal(t, dataplaneapi.RuntimeServer{
+		Name:            "loadogn-test1",
+		Address:         "1.2.3.4",
+		Port:            2222,
+		Check:           true,
+		HealthCheckPort: 2222,
+		Backup:          true,
+		Maintenance:     true,
+	}, rs)
+
+	_, ok = runtimeServer(types.Server{Name: "loadogn-test1", Address: "1.2.3.4:2222 check port"})
+	assert.False(t, ok)
+}
+
+func TestUpdateConfigAtRuntime(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	require.Nil(t, err)
+
+	lb := mergeTestData1
+
+	posted, postedNoReload := 0, 0
+	states := map[string]string{}
+
+	mgr := Manager{
+		Context: context.Background(),
+		Logger:  l.Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				posted++
+				return nil
+			},
+			DoPostConfigNoReload: func(ctx context.Context, config string) error {
+				postedNoReload++
+				return nil
+			},
+			DoSetRuntimeServerState: func(ctx context.Context, backend, name, state string) error {
+				states[name] = state
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return &lb, nil
+			},
+		},
+		BaseCfgPath:          testBaseCfgPath,
+		ManagedLBID:          gidx.PrefixedID("loadbal-test"),
+		RuntimeServerUpdates: true,
+	}
+
+	// the first config is always posted
+	_, err = mgr.updateConfigToLatest(mgr.Context)
+	require.NoError(t, err)
+	assert.Equal(t, 1, posted)
+
+	// enable the disabled origin
+	lb.Ports.Edges = []lbapi.PortEdges{mergeTestData1.Ports.Edges[0]}
+	lb.Ports.Edges[0].Node.Pools = []lbapi.Pool{mergeTestData1.Ports.Edges[0].Node.Pools[0]}
+	lb.Ports.Edges[0].Node.Pools[0].Origins.Edges = append([]lbapi.OriginEdges{}, mergeTestData1.Ports.Edges[0].Node.Pools[0].Origins.Edges...)
+	lb.Ports.Edges[0].Node.Pools[0].Origins.Edges[2].Node.Active = true
+
+	_, err = mgr.updateConfigToLatest(mgr.Context)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, posted)
+	assert.Equal(t, 1, postedNoReload)
+	assert.Equal(t, map[string]string{"loadogn-test3": dataplaneapi.ServerStateReady}, states)
+	assert.Contains(t, mgr.currentConfig, "server loadogn-test3 4.3.2.1:2222 check port 2222\n")
+}
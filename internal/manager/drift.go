@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// watchDrift checks for drift every DriftCheckInterval until the context is done
+func (m *Manager) watchDrift(ctx context.Context) {
+	if m.DriftCheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.DriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.CheckDrift(ctx); err != nil {
+				m.logger(ctx).Warnw("failed to check for config drift", zap.Error(err))
+			}
+		}
+	}
+}
+
+// CheckDrift compares the config haproxy runs with, as stored by the dataplaneapi, to the last
+// applied config, returning true when they differ, e.g. after a manual edit or a change by another
+// controller. Drift is logged with its diff, and the last applied config is re-applied with
+// ReapplyOnDrift. Nothing has drifted before a config is applied.
+func (m *Manager) CheckDrift(ctx context.Context) (bool, error) {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	applied := m.CurrentConfig()
+	if applied == "" {
+		return false, nil
+	}
+
+	live, err := m.DataPlaneClient.GetConfig(ctx)
+
+	switch {
+	case errors.Is(err, dataplaneapi.ErrDataPlaneConfigsDiverged):
+		m.logger(ctx).Warnw("config drift detected, the haproxys of the group run different configs", zap.Error(err))
+	case err != nil:
+		return false, err
+	case configsMatch(applied, live):
+		return false, nil
+	default:
+		m.logger(ctx).Warnw("config drift detected",
+			zap.String("loadbalancerID", m.ManagedLBID.String()),
+			zap.String("diff", diffLines(normalizeForDrift(applied), normalizeForDrift(live))),
+		)
+	}
+
+	if !m.ReapplyOnDrift {
+		return true, nil
+	}
+
+	err = m.DataPlaneClient.PostConfig(ctx, applied)
+	m.recordApply(ctx, m.ManagedLBID.String(), live, applied, err)
+
+	if err != nil {
+		return true, err
+	}
+
+	m.logger(ctx).Infow("re-applied the last applied config after drift", zap.String("loadbalancerID", m.ManagedLBID.String()))
+
+	return true, nil
+}
+
+// configsMatch returns true when two configs have the same meaning, ignoring formatting, the order
+// of sections and servers, section annotations, and the version the dataplaneapi adds to the
+// configs it stores
+func configsMatch(a, b string) bool {
+	return normalizeForDrift(a) == normalizeForDrift(b)
+}
+
+// normalizeForDrift returns the config in the form configs are compared in
+func normalizeForDrift(config string) string {
+	return haproxyconfig.Canonicalize(haproxyconfig.StripAnnotations(stripVersion(config)))
+}
+
+// stripVersion removes the "# _version=N" comment the dataplaneapi adds to the configs it stores
+func stripVersion(config string) string {
+	lines := strings.Split(config, "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "# _version") {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+const driftTestCfg = `global
+  maxconn 200
+
+frontend loadprt-test
+  bind ipv4@:22
+  use_backend loadprt-test
+
+backend loadprt-test
+  server loadogn-test1 1.2.3.4:2222 check port 2222
+  server loadogn-test2 1.2.3.5:2222 check port 2222
+`
+
+func TestCheckDrift(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	tests := []struct {
+		name     string
+		applied  string
+		live     string
+		liveErr  error
+		reapply  bool
+		expDrift bool
+		expErr   error
+	}{
+		{
+			name:     "nothing applied",
+			live:     driftTestCfg,
+			expDrift: false,
+		},
+		{
+			name:     "matching",
+			applied:  driftTestCfg,
+			live:     driftTestCfg,
+			expDrift: false,
+		},
+		{
+			name:     "matching with the dataplaneapi version",
+			applied:  driftTestCfg,
+			live:     "# _version=7\n" + driftTestCfg,
+			expDrift: false,
+		},
+		{
+			name:     "matching with section annotations",
+			applied:  "# managed: port=ssh id=loadprt-test generated=2023-07-01T10:00:00Z\n" + driftTestCfg,
+			live:     driftTestCfg,
+			expDrift: false,
+		},
+		{
+			name:    "matching with formatting and server order changes",
+			applied: driftTestCfg,
+			live: "global\n\tmaxconn 200\nfrontend loadprt-test\n\tbind ipv4@:22\n\tuse_backend loadprt-test\n" +
+				"backend loadprt-test\n\tserver loadogn-test2 1.2.3.5:2222 check port 2222\n\tserver loadogn-test1 1.2.3.4:2222 check port 2222\n",
+			expDrift: false,
+		},
+		{
+			name:     "drifted",
+			applied:  driftTestCfg,
+			live:     driftTestCfg + "  server loadogn-manual 1.2.3.6:2222\n",
+			expDrift: true,
+		},
+		{
+			name:     "drifted and re-applied",
+			applied:  driftTestCfg,
+			live:     driftTestCfg + "  server loadogn-manual 1.2.3.6:2222\n",
+			reapply:  true,
+			expDrift: true,
+		},
+		{
+			name:     "diverged group",
+			applied:  driftTestCfg,
+			liveErr:  dataplaneapi.ErrDataPlaneConfigsDiverged,
+			expDrift: true,
+		},
+		{
+			name:    "live config unavailable",
+			applied: driftTestCfg,
+			liveErr: dataplaneapi.ErrDataPlaneHTTPError,
+			expErr:  dataplaneapi.ErrDataPlaneHTTPError,
+		},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			posted := []string{}
+
+			mgr := Manager{
+				Context: context.Background(),
+				Logger:  logger,
+				DataPlaneClient: &mock.DataplaneAPIClient{
+					DoGetConfig: func(ctx context.Context) (string, error) {
+						return tt.live, tt.liveErr
+					},
+					DoPostConfig: func(ctx context.Context, config string) error {
+						posted = append(posted, config)
+						return nil
+					},
+				},
+				ManagedLBID:    gidx.PrefixedID("loadbal-test"),
+				ReapplyOnDrift: tt.reapply,
+			}
+
+			mgr.setCurrentConfig(mgr.ManagedLBID.String(), tt.applied)
+
+			drift, err := mgr.CheckDrift(context.Background())
+
+			if tt.expErr != nil {
+				assert.ErrorIs(t, err, tt.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expDrift, drift)
+
+			if tt.reapply && tt.expDrift {
+				assert.Equal(t, []string{tt.applied}, posted)
+				require.Len(t, mgr.History(), 1)
+				assert.Contains(t, mgr.History()[0].Diff, "-  server loadogn-manual 1.2.3.6:2222")
+
+				return
+			}
+
+			assert.Empty(t, posted)
+		})
+	}
+}
+
+func TestWatchDrift(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reapplied := make(chan string, 1)
+
+	mgr := Manager{
+		Context: ctx,
+		Logger:  logger,
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoGetConfig: func(ctx context.Context) (string, error) {
+				return "global\n  maxconn 4000\n", nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				select {
+				case reapplied <- config:
+				default:
+				}
+
+				return nil
+			},
+		},
+		ManagedLBID:        gidx.PrefixedID("loadbal-test"),
+		DriftCheckInterval: 10 * time.Millisecond,
+		ReapplyOnDrift:     true,
+	}
+
+	mgr.setCurrentConfig(mgr.ManagedLBID.String(), driftTestCfg)
+
+	go mgr.watchDrift(ctx)
+
+	select {
+	case config := <-reapplied:
+		assert.Equal(t, driftTestCfg, config)
+	case <-time.After(time.Second):
+		t.Fatal("drift was not re-applied")
+	}
+}
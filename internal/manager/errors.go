@@ -3,32 +3,91 @@ package manager
 import (
 	"errors"
 	"fmt"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
 )
 
 var (
 	// errLoadBalancerIDParamInvalid is returned when an invalid load balancer ID is provided
 	errLoadBalancerIDParamInvalid = errors.New("loadbalancer ID is empty")
 
-	// errFrontendSectionLabelFailure is returned when a frontend section cannot be created
-	errFrontendSectionLabelFailure = errors.New("failed to create frontend section with label")
+	// errDataPlaneClientNotInitialized is returned when the manager has no dataplaneapi client
+	errDataPlaneClientNotInitialized = errors.New("dataplane api is not initialized")
+
+	// errLBClientNotInitialized is returned when the manager has no loadbalancer api client
+	errLBClientNotInitialized = errors.New("loadbalancer api client is not initialized")
+
+	// errSubscriberNotInitialized is returned when the manager has no pubsub subscriber
+	errSubscriberNotInitialized = errors.New("pubsub subscriber client is not initialized")
+
+	// errPollIntervalInvalid is returned when polling without a positive poll interval
+	errPollIntervalInvalid = errors.New("poll interval must be positive")
+
+	// errDataPlaneUnreachable is returned when the dataplaneapi doesn't become ready at startup
+	errDataPlaneUnreachable = errors.New("unable to reach dataplaneapi")
+
+	// errConfigInitFailure is returned when the desired config cannot be applied at startup
+	errConfigInitFailure = errors.New("failed to initialize the config")
+
+	// errBaseConfigLoad is returned when the haproxy base config cannot be loaded
+	errBaseConfigLoad = errors.New("failed to load haproxy base config")
+
+	// errPostApplyCheckFailed is returned when frontends are not up after applying a config
+	errPostApplyCheckFailed = errors.New("post-apply check failed")
+
+	// errRollbackFailure is returned when the previous config cannot be re-applied
+	errRollbackFailure = errors.New("failed to roll back to previous config")
 
-	// errUseBackendFailure is returned when the use_backend attr cannot be applied to a frontend
-	errUseBackendFailure = errors.New("failed to create frontend attr use_backend")
+	// errRuntimeServerFailure is returned when a server change cannot be applied through the runtime api
+	errRuntimeServerFailure = errors.New("failed to apply runtime change to server")
 
-	// errFrontendBindFailure is returned when the bind attribute cannot be applied to a frontend
-	errFrontendBindFailure = errors.New("failed to create frontend attr bind")
+	// errConfigHookFailure is returned when a config hook rejects the merged config
+	errConfigHookFailure = errors.New("config hook failed")
 
-	// errBackendSectionLabelFailure is returned when a backend section cannot be created
-	errBackendSectionLabelFailure = errors.New("failed to create section backend with label")
+	// errCrtListWrite is returned when a managed crt-list cannot be written
+	errCrtListWrite = errors.New("failed to write crt-list")
 
-	// errBackendServerFailure is returned when a server cannot be applied to a backend
-	errBackendServerFailure = errors.New("failed to add backend attr server: ")
+	// errCrtListRestore is returned when a crt-list replaced for a config which wasn't applied cannot be restored
+	errCrtListRestore = errors.New("failed to restore crt-list")
+
+	// errConfigTooLarge is returned when the rendered config is larger than the maximum config size
+	errConfigTooLarge = errors.New("config is too large")
+
+	// errLoadBalancerFetchFailure is returned when loadbalancers cannot be requested from lbapi
+	errLoadBalancerFetchFailure = errors.New("failed to fetch loadbalancers")
+
+	// errHAProxyVersionUnsupported is returned by a strict version check when the haproxy version
+	// doesn't support directives generated for the settings
+	errHAProxyVersionUnsupported = errors.New("haproxy version doesn't support the configured features")
 )
 
-func newLabelError(label string, err error, labelErr error) error {
-	return fmt.Errorf("%w %q: %v", err, label, labelErr)
+// permanentErrors are errors updating the config which retrying the same change won't resolve
+var permanentErrors = []error{
+	errLoadBalancerIDParamInvalid,
+	errConfigTooLarge,
+	haproxyconfig.ErrOriginTargetInvalid,
+	haproxyconfig.ErrPortSettingsInvalid,
+	haproxyconfig.ErrPoolSettingsInvalid,
+	haproxyconfig.ErrPoolSettingsConflict,
+	haproxyconfig.ErrSectionConflict,
+	haproxyconfig.ErrDefaultsModeInvalid,
+	haproxyconfig.ErrDefaultsNotFound,
+	haproxyconfig.ErrLogFormatInvalid,
+	haproxyconfig.ErrMetricsPortInvalid,
+	haproxyconfig.ErrMetricsPortConflict,
 }
 
-func newAttrError(err error, attrErr error) error {
-	return fmt.Errorf("%w: %v", err, attrErr)
+// isPermanent returns true when err matches one of the permanent errors
+func isPermanent(err error) bool {
+	for _, permErr := range permanentErrors {
+		if errors.Is(err, permErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newLabelError(label string, err error, labelErr error) error {
+	return fmt.Errorf("%w %q: %w", err, label, labelErr)
 }
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+)
+
+// defaultFetchConcurrency is the most loadbalancers requested at once when FetchConcurrency isn't set
+const defaultFetchConcurrency = 4
+
+// FetchLoadBalancers requests the loadbalancers from lbapi concurrently, at most FetchConcurrency
+// at a time, so starting up with many loadbalancers isn't bound by the latency of each request.
+// The loadbalancers fetched are returned by ID along with an error joining the failures, which
+// include the loadbalancers not requested before the context is done.
+func (m *Manager) FetchLoadBalancers(ctx context.Context, ids []gidx.PrefixedID) (map[gidx.PrefixedID]*lbapi.LoadBalancer, error) {
+	if m.LBClient == nil {
+		return nil, errLBClientNotInitialized
+	}
+
+	// managers without a context, e.g. in tests, fetch without cancellation
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	concurrency := m.FetchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultFetchConcurrency
+	}
+
+	lbs := make([]*lbapi.LoadBalancer, len(ids))
+	errs := make([]error, len(ids))
+
+	sem := make(chan struct{}, concurrency)
+	wg := &sync.WaitGroup{}
+
+	for i, id := range ids {
+		// checked first, as select picks at random when a slot is also free
+		if err := ctx.Err(); err != nil {
+			errs[i] = fmt.Errorf("%s: %w", id, err)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("%s: %w", id, ctx.Err())
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func(i int, id gidx.PrefixedID) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			lb, err := m.LBClient.GetLoadBalancer(ctx, id.String())
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", id, err)
+				return
+			}
+
+			lbs[i] = lb
+		}(i, id)
+	}
+
+	wg.Wait()
+
+	fetched := make(map[gidx.PrefixedID]*lbapi.LoadBalancer, len(ids))
+	failed := []error{}
+
+	for i, id := range ids {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+			continue
+		}
+
+		fetched[id] = lbs[i]
+	}
+
+	if len(failed) > 0 {
+		return fetched, fmt.Errorf("%w: %w", errLoadBalancerFetchFailure, errors.Join(failed...))
+	}
+
+	return fetched, nil
+}
@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+var errTestFetch = errors.New("lbapi unavailable")
+
+// fetchTestIDs returns n loadbalancer IDs
+func fetchTestIDs(n int) []gidx.PrefixedID {
+	ids := make([]gidx.PrefixedID, 0, n)
+
+	for i := 0; i < n; i++ {
+		ids = append(ids, gidx.PrefixedID(fmt.Sprintf("loadbal-test%d", i)))
+	}
+
+	return ids
+}
+
+func TestFetchLoadBalancers(t *testing.T) {
+	t.Run("bounded by the concurrency", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+
+		started := make(chan struct{}, 10)
+		release := make(chan struct{})
+
+		m := Manager{
+			Logger:           zap.NewNop().Sugar(),
+			FetchConcurrency: 3,
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					n := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+
+					for {
+						peak := atomic.LoadInt32(&maxInFlight)
+						if n <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, n) {
+							break
+						}
+					}
+
+					started <- struct{}{}
+					<-release
+
+					return &lbapi.LoadBalancer{ID: id}, nil
+				},
+			},
+		}
+
+		ids := fetchTestIDs(10)
+
+		type result struct {
+			lbs map[gidx.PrefixedID]*lbapi.LoadBalancer
+			err error
+		}
+
+		done := make(chan result)
+
+		go func() {
+			lbs, err := m.FetchLoadBalancers(context.TODO(), ids)
+			done <- result{lbs, err}
+		}()
+
+		// the first fetches block until released, filling every slot
+		for i := 0; i < 3; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatalf("only %d loadbalancers fetched concurrently", i)
+			}
+		}
+
+		select {
+		case <-started:
+			t.Fatal("more loadbalancers fetched concurrently than the limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+
+		res := <-done
+		require.NoError(t, res.err)
+
+		require.Len(t, res.lbs, len(ids))
+
+		for _, id := range ids {
+			assert.Equal(t, id.String(), res.lbs[id].ID)
+		}
+
+		assert.Equal(t, int32(3), atomic.LoadInt32(&maxInFlight))
+	})
+
+	t.Run("failures are joined", func(t *testing.T) {
+		m := Manager{
+			Logger: zap.NewNop().Sugar(),
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					if id == "loadbal-test1" || id == "loadbal-test3" {
+						return nil, errTestFetch
+					}
+
+					return &lbapi.LoadBalancer{ID: id}, nil
+				},
+			},
+		}
+
+		lbs, err := m.FetchLoadBalancers(context.TODO(), fetchTestIDs(5))
+
+		assert.ErrorIs(t, err, errLoadBalancerFetchFailure)
+		assert.ErrorIs(t, err, errTestFetch)
+		assert.ErrorContains(t, err, "loadbal-test1")
+		assert.ErrorContains(t, err, "loadbal-test3")
+
+		assert.Len(t, lbs, 3)
+		assert.NotContains(t, lbs, gidx.PrefixedID("loadbal-test1"))
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var fetches int32
+
+		m := Manager{
+			Logger:           zap.NewNop().Sugar(),
+			FetchConcurrency: 1,
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					atomic.AddInt32(&fetches, 1)
+					cancel()
+
+					return &lbapi.LoadBalancer{ID: id}, nil
+				},
+			},
+		}
+
+		lbs, err := m.FetchLoadBalancers(ctx, fetchTestIDs(5))
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, atomic.LoadInt32(&fetches), int32(5))
+		assert.Len(t, lbs, int(atomic.LoadInt32(&fetches)))
+	})
+
+	t.Run("lbapi client not initialized", func(t *testing.T) {
+		m := Manager{Logger: zap.NewNop().Sugar()}
+
+		_, err := m.FetchLoadBalancers(context.TODO(), fetchTestIDs(1))
+		assert.ErrorIs(t, err, errLBClientNotInitialized)
+	})
+}
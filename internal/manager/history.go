@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHistorySize is the number of applies kept when HistorySize isn't set
+	defaultHistorySize = 20
+
+	// maxDiffCells bounds the table of the longest common subsequence of the changed lines of two
+	// configs. Larger changes are diffed as the removal of all changed lines and addition of the new.
+	maxDiffCells = 1 << 22
+)
+
+// ApplyRecord is a config the manager applied or failed to apply, with its diff against the config
+// applied before it
+type ApplyRecord struct {
+	Time           time.Time `json:"time"`
+	LoadBalancerID string    `json:"loadbalancerID"`
+	CorrelationID  string    `json:"correlationID,omitempty"`
+	Diff           string    `json:"diff"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// applyHistory is a ring buffer of the most recent applies
+type applyHistory struct {
+	mu      sync.Mutex
+	records []ApplyRecord
+	start   int
+}
+
+// add records an apply, replacing the oldest one when size applies are already kept
+func (h *applyHistory) add(record ApplyRecord, size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the size changed after the buffer wrapped, keep the most recent applies in order
+	if h.start != 0 && len(h.records) != size {
+		h.records, h.start = h.list(), 0
+	}
+
+	if len(h.records) > size {
+		h.records = h.records[len(h.records)-size:]
+	}
+
+	if len(h.records) < size {
+		h.records = append(h.records, record)
+		return
+	}
+
+	h.records[h.start] = record
+	h.start = (h.start + 1) % size
+}
+
+// list returns the applies kept, oldest first. It must be called with mu held.
+func (h *applyHistory) list() []ApplyRecord {
+	records := make([]ApplyRecord, 0, len(h.records))
+	records = append(records, h.records[h.start:]...)
+
+	return append(records, h.records[:h.start]...)
+}
+
+// History returns the most recent applies, oldest first, for reviewing what changed and when
+func (m *Manager) History() []ApplyRecord {
+	m.history.mu.Lock()
+	defer m.history.mu.Unlock()
+
+	return m.history.list()
+}
+
+// recordApply adds an apply of config to the history, diffed against the config applied before it
+func (m *Manager) recordApply(ctx context.Context, id, previous, config string, err error) {
+	size := m.HistorySize
+	if size == 0 {
+		size = defaultHistorySize
+	}
+
+	if size < 0 {
+		return
+	}
+
+	record := ApplyRecord{
+		Time:           time.Now(),
+		LoadBalancerID: id,
+		CorrelationID:  CorrelationID(ctx),
+		Diff:           diffLines(previous, config),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	m.history.add(record, size)
+}
+
+// diffLines returns the lines removed from a, prefixed with -, and added in b, prefixed with +, in
+// the order they appear. Unchanged lines are left out.
+func diffLines(a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	before, after := splitLines(a), splitLines(b)
+
+	// only the lines between the common prefix and suffix changed
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	before = before[prefix : len(before)-suffix]
+	after = after[prefix : len(after)-suffix]
+
+	diff := []string{}
+
+	if len(before)*len(after) > maxDiffCells {
+		for _, l := range before {
+			diff = append(diff, "-"+l)
+		}
+
+		for _, l := range after {
+			diff = append(diff, "+"+l)
+		}
+
+		return strings.Join(diff, "\n")
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			switch {
+			case before[i] == after[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			i++
+			j++
+		case j == len(after) || (i < len(before) && lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "-"+before[i])
+			i++
+		default:
+			diff = append(diff, "+"+after[j])
+			j++
+		}
+	}
+
+	return strings.Join(diff, "\n")
+}
+
+// splitLines splits a config into its lines, an empty config having none
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
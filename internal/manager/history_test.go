@@ -0,0 +1,140 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+func TestHistory(t *testing.T) {
+	postErr := errors.New("post failed") // nolint:goerr113
+
+	var failPost bool
+
+	mgr := &Manager{
+		Context: context.Background(),
+		Logger:  zap.NewNop().Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				if failPost {
+					return postErr
+				}
+
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				if id == "loadbal-other" {
+					return &mergeTestData2, nil
+				}
+
+				return &mergeTestData1, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+		HistorySize: 2,
+	}
+
+	assert.Empty(t, mgr.History())
+
+	_, err := mgr.updateConfigToLatest(WithCorrelationID(context.Background(), "abc"))
+	require.NoError(t, err)
+
+	history := mgr.History()
+	require.Len(t, history, 1)
+	assert.Equal(t, "loadbal-test", history[0].LoadBalancerID)
+	assert.Equal(t, "abc", history[0].CorrelationID)
+	assert.Empty(t, history[0].Error)
+	assert.Contains(t, history[0].Diff, "+  server loadogn-test1 ")
+
+	require.NoError(t, mgr.ApplyForLB(context.Background(), gidx.PrefixedID("loadbal-other")))
+
+	history = mgr.History()
+	require.Len(t, history, 2)
+	assert.Equal(t, "loadbal-other", history[1].LoadBalancerID)
+	assert.Contains(t, history[1].Diff, "+  server loadogn-test4 ")
+
+	failPost = true
+
+	_, err = mgr.updateConfigToLatest(context.Background())
+	require.ErrorIs(t, err, postErr)
+
+	// the oldest apply is evicted
+	history = mgr.History()
+	require.Len(t, history, 2)
+	assert.Equal(t, "loadbal-other", history[0].LoadBalancerID)
+	assert.Equal(t, "loadbal-test", history[1].LoadBalancerID)
+	assert.Equal(t, postErr.Error(), history[1].Error)
+	assert.Contains(t, history[1].Diff, "-  server loadogn-test4 ")
+}
+
+func TestApplyHistoryEviction(t *testing.T) {
+	h := applyHistory{}
+
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		h.add(ApplyRecord{LoadBalancerID: id}, 3)
+	}
+
+	ids := func() []string {
+		out := []string{}
+
+		for _, r := range h.list() {
+			out = append(out, r.LoadBalancerID)
+		}
+
+		return out
+	}
+
+	assert.Equal(t, []string{"c", "d", "e"}, ids())
+
+	// shrinking keeps the most recent applies
+	h.add(ApplyRecord{LoadBalancerID: "f"}, 2)
+	assert.Equal(t, []string{"e", "f"}, ids())
+
+	// growing keeps them in order
+	h.add(ApplyRecord{LoadBalancerID: "g"}, 4)
+	h.add(ApplyRecord{LoadBalancerID: "h"}, 4)
+	h.add(ApplyRecord{LoadBalancerID: "i"}, 4)
+	assert.Equal(t, []string{"f", "g", "h", "i"}, ids())
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		expDiff string
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", ""},
+		{"first config", "", "a\nb\n", "+a\n+b"},
+		{"line added", "a\nc\n", "a\nb\nc\n", "+b"},
+		{"line removed", "a\nb\nc\n", "a\nc\n", "-b"},
+		{"line changed", "a\nb\nc\n", "a\nx\nc\n", "-b\n+x"},
+		{"lines moved", "a\nb\nc\n", "c\na\nb\n", "+c\n-c"},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expDiff, diffLines(tt.a, tt.b))
+		})
+	}
+}
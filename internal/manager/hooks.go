@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// ConfigHook post-processes the merged config before it is checked and applied, e.g. to inject
+// standard directives or enforce policies. An error aborts the apply.
+type ConfigHook func(cfg parser.Parser) error
+
+// AddConfigHook appends a hook to the chain run on each merged config. Hooks run in the order they
+// were added, and must be added before the manager starts applying configs.
+func (m *Manager) AddConfigHook(fn ConfigHook) {
+	m.configHooks = append(m.configHooks, fn)
+}
+
+// runConfigHooks runs the hook chain on the config, stopping at the first failure
+func (m *Manager) runConfigHooks(cfg parser.Parser) error {
+	for i, hook := range m.configHooks {
+		if err := hook(cfg); err != nil {
+			return fmt.Errorf("%w %d: %w", errConfigHookFailure, i, err)
+		}
+	}
+
+	return nil
+}
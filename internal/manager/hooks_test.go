@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+func TestConfigHooks(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	newManager := func(posted *string) *Manager {
+		return &Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					*posted = config
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath: testBaseCfgPath,
+			ManagedLBID: gidx.PrefixedID("loadbal-test"),
+		}
+	}
+
+	t.Run("hooks run in order on the merged config", func(t *testing.T) {
+		posted := ""
+		mgr := newManager(&posted)
+
+		order := []string{}
+
+		mgr.AddConfigHook(func(cfg parser.Parser) error {
+			order = append(order, "first")
+
+			// the merged frontend is visible to hooks
+			_, err := cfg.Get(parser.Frontends, "loadprt-test", "bind")
+
+			return err
+		})
+
+		mgr.AddConfigHook(func(cfg parser.Parser) error {
+			order = append(order, "second")
+
+			return cfg.Set(parser.Frontends, "loadprt-test", "option dontlognull", types.SimpleOption{})
+		})
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"first", "second"}, order)
+		assert.Contains(t, posted, "option dontlognull")
+	})
+
+	t.Run("hook errors abort the apply", func(t *testing.T) {
+		posted := ""
+		mgr := newManager(&posted)
+
+		// nolint:goerr113
+		errPolicy := errors.New("policy violation")
+		ran := false
+
+		mgr.AddConfigHook(func(cfg parser.Parser) error {
+			return errPolicy
+		})
+
+		mgr.AddConfigHook(func(cfg parser.Parser) error {
+			ran = true
+			return nil
+		})
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+
+		assert.ErrorIs(t, err, errConfigHookFailure)
+		assert.ErrorIs(t, err, errPolicy)
+		assert.False(t, ran, "hook ran after a failure")
+		assert.Empty(t, posted, "config posted after a hook failure")
+	})
+}
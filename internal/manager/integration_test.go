@@ -0,0 +1,146 @@
+//go:build integration
+
+package manager
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+// The integration tests drive the docker cli rather than testcontainers-go, which would add the
+// docker engine client and its dependencies to go.mod for a single build tagged test. The
+// containers are labeled, removed in a cleanup, which also runs when the test panics, and any left
+// behind by a killed test run are removed before the next one starts.
+
+const (
+	integrationImage          = "loadbalancer-manager-haproxy-integration"
+	integrationLabel          = "loadbalancer-manager-haproxy.integration"
+	integrationConnectRetries = 60
+	integrationRetryInterval  = time.Second
+)
+
+// startDataPlane builds the devcontainer haproxy image and runs it with the dataplaneapi listening on a
+// random local port, returning a client for it once it is ready. The container is removed when the test ends.
+func startDataPlane(t *testing.T) *dataplaneapi.Client {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker is required for integration tests")
+	}
+
+	removeIntegrationContainers(t)
+
+	docker(t, "build", "-t", integrationImage, "-f", "../../.devcontainer/Dockerfile.haproxy", "../../.devcontainer")
+
+	dataplaneCfg, err := filepath.Abs("testdata/integration/dataplaneapi.yaml")
+	require.NoError(t, err)
+
+	id := docker(t, "create",
+		"--label", integrationLabel,
+		"-p", "127.0.0.1::5555",
+		"-v", dataplaneCfg+":/bitnami/haproxy/conf/dataplaneapi.yaml:ro",
+		integrationImage)
+
+	t.Cleanup(func() {
+		_ = exec.Command("docker", "rm", "-f", "-v", id).Run()
+	})
+
+	docker(t, "start", id)
+
+	// e.g. 127.0.0.1:49153
+	addr := docker(t, "port", id, "5555/tcp")
+
+	viper.Set("dataplane.user.name", "haproxy")
+	viper.Set("dataplane.user.pwd", "adminpwd")
+
+	client := dataplaneapi.NewClient("http://" + strings.Split(addr, "\n")[0] + "/v2")
+
+	require.NoError(t, client.WaitForDataPlaneReady(context.Background(), integrationConnectRetries, integrationRetryInterval))
+
+	return client
+}
+
+// removeIntegrationContainers removes the containers of earlier test runs which were killed before
+// their cleanup ran, e.g. by the test timeout
+func removeIntegrationContainers(t *testing.T) {
+	t.Helper()
+
+	for _, id := range strings.Fields(docker(t, "ps", "-aq", "--filter", "label="+integrationLabel)) {
+		docker(t, "rm", "-f", "-v", id)
+	}
+}
+
+// docker runs a docker command, failing the test when it fails, and returns its trimmed output
+func docker(t *testing.T, args ...string) string {
+	t.Helper()
+
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	return strings.TrimSpace(string(out))
+}
+
+func TestIntegrationUpdateConfigToLatest(t *testing.T) {
+	client := startDataPlane(t)
+
+	l, err := zap.NewDevelopmentConfig().Build()
+	require.NoError(t, err)
+
+	// haproxy runs unprivileged in the container, so the port must be unprivileged too
+	lb := &lbapi.LoadBalancer{
+		ID: "loadbal-integration",
+		Ports: lbapi.Ports{Edges: []lbapi.PortEdges{{Node: lbapi.PortNode{
+			ID:     "loadprt-integration",
+			Name:   "http",
+			Number: 8080,
+			Pools: []lbapi.Pool{{
+				ID:       "loadpol-integration",
+				Protocol: "tcp",
+				Origins: lbapi.Origins{Edges: []lbapi.OriginEdges{{Node: lbapi.OriginNode{
+					ID:         "loadogn-integration",
+					Target:     "127.0.0.1",
+					PortNumber: 8081,
+					Active:     true,
+				}}}},
+			}},
+		}}}},
+	}
+
+	mgr := &Manager{
+		Context:         context.Background(),
+		Logger:          l.Sugar(),
+		DataPlaneClient: client,
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return lb, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-integration"),
+	}
+
+	_, err = mgr.updateConfigToLatest(mgr.Context)
+	require.NoError(t, err)
+
+	// the frontend is only open once haproxy has reloaded with the posted config
+	assert.Eventually(t, func() bool {
+		status, err := client.FrontendStatus(mgr.Context)
+
+		return err == nil && status["loadprt-integration"] == "OPEN"
+	}, time.Minute, time.Second)
+}
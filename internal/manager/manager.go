@@ -2,20 +2,44 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	parser "github.com/haproxytech/config-parser/v4"
-	"github.com/haproxytech/config-parser/v4/options"
-	"github.com/haproxytech/config-parser/v4/types"
 
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
 
 	"go.infratographer.com/x/events"
 	"go.infratographer.com/x/gidx"
 	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/pubsub"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// gidx prefixes of the loadbalancer api resources, and of the ipam ip addresses assigned to loadbalancers
+const (
+	prefixLoadBalancer = "loadbal"
+	prefixPort         = "loadprt"
+	prefixPool         = "loadpol"
+	prefixOrigin       = "loadogn"
+	prefixIPAddress    = "ipamipa"
 )
 
+// supportedPrefixes are the prefixes of subjects whose changes can affect a loadbalancer
+var supportedPrefixes = map[string]bool{
+	prefixLoadBalancer: true,
+	prefixPort:         true,
+	prefixPool:         true,
+	prefixOrigin:       true,
+	prefixIPAddress:    true,
+}
+
 type lbAPI interface {
 	GetLoadBalancer(ctx context.Context, id string) (*lbapi.LoadBalancer, error)
 }
@@ -25,6 +49,14 @@ type dataPlaneAPI interface {
 	CheckConfig(ctx context.Context, config string) error
 	APIIsReady(ctx context.Context) bool
 	WaitForDataPlaneReady(ctx context.Context, retries int, sleep time.Duration) error
+	FrontendStatus(ctx context.Context) (map[string]string, error)
+	PostConfigWithoutReload(ctx context.Context, config string) error
+	AddRuntimeServer(ctx context.Context, backend string, server dataplaneapi.RuntimeServer) error
+	DeleteRuntimeServer(ctx context.Context, backend, name string) error
+	SetRuntimeServerState(ctx context.Context, backend, name, state string) error
+	GetConfig(ctx context.Context) (string, error)
+	HAProxyVersion(ctx context.Context) (string, error)
+	Close()
 }
 
 type eventSubscriber interface {
@@ -43,53 +75,301 @@ type Manager struct {
 	LBClient                      lbAPI
 	ManagedLBID                   gidx.PrefixedID
 	BaseCfgPath                   string
-
-	// currentConfig for unit testing
-	currentConfig string
+	Settings                      haproxyconfig.Settings
+
+	// FetchConcurrency is the most loadbalancers FetchLoadBalancers requests from lbapi at once,
+	// defaultFetchConcurrency when zero
+	FetchConcurrency int
+
+	// PollInterval is how often RunPolling requests the desired state from lbapi
+	PollInterval time.Duration
+
+	// PostReadyGrace is a delay between the dataplaneapi reporting it is ready and the first
+	// config applied, for dataplaneapis which don't accept configs reliably as soon as they are
+	PostReadyGrace time.Duration
+
+	// BaseCfgDir is a directory of base config fragments, used instead of BaseCfgPath when set
+	BaseCfgDir string
+
+	// BaseCfgProfiles are base config paths by the value of the loadbalancer's
+	// BaseCfgProfileAttribute, e.g. for separate base configs of internal and external
+	// loadbalancers. Loadbalancers without a profile use BaseCfgPath or BaseCfgDir.
+	BaseCfgProfiles map[string]string
+
+	// BaseCfgProfileAttribute is the loadbalancer attribute selecting its profile,
+	// ProfileAttributeLocation when empty
+	BaseCfgProfileAttribute ProfileAttribute
+
+	// ConfigTemplate renders additional config appended to the generated config, with the
+	// loadbalancer as its data
+	ConfigTemplate *template.Template
+
+	// RollbackOnFailure verifies the frontends are up after applying a config, and re-applies
+	// the previous config when they aren't
+	RollbackOnFailure           bool
+	PostApplyCheckAttempts      int
+	PostApplyCheckRetryInterval time.Duration
+
+	// SkipCheck posts configs without validating them with the dataplaneapi first. An invalid
+	// config is then only caught when haproxy rejects it on reload, so it is only safe for configs
+	// which were already validated, e.g. with the validate command.
+	SkipCheck bool
+
+	// RuntimeServerUpdates applies changes which only add, remove, enable or disable servers
+	// through the runtime api, instead of posting the config with a reload. It selects
+	// ApplyStrategyRuntime when ApplyStrategy isn't set.
+	RuntimeServerUpdates bool
+
+	// ApplyStrategy is how configs are applied to haproxy, ApplyStrategyRaw when empty
+	ApplyStrategy ApplyStrategy
+
+	// MinReloadInterval is the minimum time between applying configs. Updates arriving sooner wait
+	// for the interval to pass, and are coalesced when a config fetched after they arrived is applied.
+	MinReloadInterval time.Duration
+
+	// AnnotateSections prefixes the frontends and backends generated for the loadbalancer with a
+	// comment naming their port or pool, for reviewing rendered configs, e.g. committed to git
+	AnnotateSections bool
+
+	// CanonicalConfig normalizes rendered configs with haproxyconfig.Canonicalize, so the diffs
+	// between applied configs are minimal and stable
+	CanonicalConfig bool
+
+	// StrictTargeting only acts on change messages whose subject is the managed loadbalancer,
+	// ignoring the fan-out of changes listing it as an additional subject
+	StrictTargeting bool
+
+	// MaxConfigBytes is the largest rendered config applied, guarding haproxy from reloading a
+	// runaway config, e.g. of a loadbalancer with thousands of origins. Zero is unlimited.
+	MaxConfigBytes int
+
+	// MergeStrategy generates the config of the loadbalancer, DefaultMergeStrategy with Settings
+	// when nil
+	MergeStrategy MergeStrategy
+
+	// DriftCheckInterval is how often the config haproxy runs with is compared to the last applied
+	// config, to detect changes made outside the manager. Zero disables the check.
+	DriftCheckInterval time.Duration
+
+	// ReapplyOnDrift re-applies the last applied config when drift is detected, instead of only
+	// logging it
+	ReapplyOnDrift bool
+
+	// StrictVersionCheck fails startup when the haproxy version doesn't support directives
+	// generated for Settings, instead of only logging a warning
+	StrictVersionCheck bool
+
+	// HistorySize is the number of applies History keeps, defaultHistorySize when zero. A negative
+	// size keeps none.
+	HistorySize int
+
+	// configHooks post-process the merged config, in order
+	configHooks []ConfigHook
+
+	// currentConfig is the last successfully applied config, of the loadbalancer currentLBID and
+	// applied at currentAppliedAt, written under configMu so it can be read while an update is in
+	// progress
+	currentConfig    string
+	currentLBID      string
+	currentAppliedAt time.Time
+	configMu         sync.RWMutex
+
+	// updateMu serializes config updates, lastFetched is when the desired state of the last applied
+	// config was requested from lbapi, and lastApplied is when it was applied
+	updateMu    sync.Mutex
+	lastFetched time.Time
+	lastApplied time.Time
+
+	// history is the most recent applies, for History
+	history applyHistory
 }
 
-// Run subscribes to a NATS subject and updates the haproxy config via dataplaneapi
+// Run subscribes to a NATS subject and updates the haproxy config via dataplaneapi. Errors
+// initializing the manager are returned, so the caller decides whether to exit or restart it.
 func (m *Manager) Run() error {
 	m.Logger.Info("Starting manager")
 
 	if m.DataPlaneClient == nil {
-		m.Logger.Fatal("dataplane api is not initialized")
+		return errDataPlaneClientNotInitialized
 	}
 
 	if m.LBClient == nil {
-		m.Logger.Fatal("loadbalancer api client is not initialized")
+		return errLBClientNotInitialized
 	}
 
 	if m.Subscriber == nil {
-		m.Logger.Fatal("pubsub subscriber client is not initialized")
+		return errSubscriberNotInitialized
+	}
+
+	// release the dataplaneapi connections on shutdown
+	defer m.DataPlaneClient.Close()
+
+	started, err := m.start()
+	if err != nil || !started {
+		return err
+	}
+
+	// listen for event messages on subject(s)
+	return m.Subscriber.Listen()
+}
+
+// RunPolling updates the haproxy config to the desired state requested from lbapi every
+// PollInterval, without subscribing to events, for environments without NATS
+func (m *Manager) RunPolling() error {
+	m.Logger.Infow("Starting manager polling the loadbalancer api", "interval", m.PollInterval)
+
+	if m.DataPlaneClient == nil {
+		return errDataPlaneClientNotInitialized
+	}
+
+	if m.LBClient == nil {
+		return errLBClientNotInitialized
+	}
+
+	if m.PollInterval <= 0 {
+		return errPollIntervalInvalid
+	}
+
+	// release the dataplaneapi connections on shutdown
+	defer m.DataPlaneClient.Close()
+
+	started, err := m.start()
+	if err != nil || !started {
+		return err
+	}
+
+	ticker := time.NewTicker(m.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.Context.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.poll(m.Context); err != nil {
+				m.Logger.Errorw("failed to update haproxy config", zap.Error(err))
+			}
+		}
 	}
+}
 
+// start waits for the dataplaneapi to be ready and applies the desired config, returning false
+// when the context is done first
+func (m *Manager) start() (bool, error) {
 	// wait until the Data Plane API is running
 	if err := m.DataPlaneClient.WaitForDataPlaneReady(m.Context, m.DataPlaneConnectRetries, m.DataPlaneConnectRetryInterval); err != nil {
-		m.Logger.Fatal("unable to reach dataplaneapi. is it running?")
+		return false, fmt.Errorf("%w: %w", errDataPlaneUnreachable, err)
+	}
+
+	if !m.waitPostReadyGrace() {
+		return false, nil
+	}
+
+	if err := m.checkHAProxyVersion(m.Context); err != nil {
+		return false, err
 	}
 
 	select {
 	case <-m.Context.Done():
-		return nil
+		return false, nil
 	default:
-		// use desired config on start
-		if err := m.updateConfigToLatest(); err != nil {
-			m.Logger.Fatalw("failed to initialize the config", zap.Error(err))
-		}
+	}
 
-		// listen for event messages on subject(s)
-		if err := m.Subscriber.Listen(); err != nil {
-			return err
-		}
+	// use desired config on start
+	if _, err := m.updateConfigToLatest(m.Context); err != nil {
+		return false, fmt.Errorf("%w: %w", errConfigInitFailure, err)
 	}
 
-	return nil
+	go m.watchDrift(m.Context)
+
+	return true, nil
+}
+
+// poll reconciles the haproxy config when the desired config differs from the applied one, so
+// polling doesn't reload haproxy every interval
+func (m *Manager) poll(ctx context.Context) error {
+	cfg, lb, err := m.desiredConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	// annotations differ by the time they were generated
+	if haproxyconfig.StripAnnotations(m.render(cfg, lb)) == haproxyconfig.StripAnnotations(m.CurrentConfig()) {
+		m.logger(ctx).Debugw("desired config is already applied", zap.String("loadbalancerID", m.ManagedLBID.String()))
+		return nil
+	}
+
+	return m.Reconcile(ctx)
+}
+
+// RunOnce waits for the dataplaneapi to be ready, applies the desired config a single time and returns
+func (m *Manager) RunOnce() error {
+	m.Logger.Info("Applying config once")
+
+	if m.DataPlaneClient == nil {
+		return errDataPlaneClientNotInitialized
+	}
+
+	if m.LBClient == nil {
+		return errLBClientNotInitialized
+	}
+
+	defer m.DataPlaneClient.Close()
+
+	if err := m.DataPlaneClient.WaitForDataPlaneReady(m.Context, m.DataPlaneConnectRetries, m.DataPlaneConnectRetryInterval); err != nil {
+		return err
+	}
+
+	if !m.waitPostReadyGrace() {
+		return m.Context.Err()
+	}
+
+	if err := m.checkHAProxyVersion(m.Context); err != nil {
+		return err
+	}
+
+	_, err := m.updateConfigToLatest(m.Context)
+
+	return err
+}
+
+// waitPostReadyGrace waits out the PostReadyGrace once the dataplaneapi is ready, returning false
+// when the context is done first
+func (m *Manager) waitPostReadyGrace() bool {
+	if m.PostReadyGrace <= 0 {
+		return true
+	}
+
+	m.Logger.Infow("waiting before applying the first config", "grace", m.PostReadyGrace)
+
+	select {
+	case <-m.Context.Done():
+		return false
+	case <-time.After(m.PostReadyGrace):
+		return true
+	}
+}
+
+// Reconcile immediately updates the haproxy config to the latest desired state, without waiting
+// for an event, e.g. when an operator suspects drift
+func (m *Manager) Reconcile(ctx context.Context) error {
+	m.Logger.Infow("reconciling haproxy config", zap.String("loadbalancerID", m.ManagedLBID.String()))
+
+	_, err := m.updateConfigToLatest(ctx)
+
+	return err
+}
+
+// supportedSubjectPrefix returns true when the id has the prefix of a loadbalancer api resource, or
+// of an ip address, whose changes update the binds of the loadbalancer listing it as an additional subject
+func supportedSubjectPrefix(id gidx.PrefixedID) bool {
+	return supportedPrefixes[id.Prefix()]
 }
 
 // loadbalancerTargeted returns true if this ChangeMessage is targeted to the
-// loadbalancerID the manager is configured to act on
-func (m Manager) loadbalancerTargeted(msg events.ChangeMessage) bool {
+// loadbalancerID the manager is configured to act on. With StrictTargeting it
+// must be the subject, otherwise it may also be an additional subject.
+func (m *Manager) loadbalancerTargeted(msg events.ChangeMessage) bool {
 	m.Logger.Debugw("change msg received",
 		"event-type", msg.EventType,
 		"subjectID", msg.SubjectID,
@@ -97,7 +377,7 @@ func (m Manager) loadbalancerTargeted(msg events.ChangeMessage) bool {
 
 	if msg.SubjectID == m.ManagedLBID {
 		return true
-	} else {
+	} else if !m.StrictTargeting {
 		for _, subject := range msg.AdditionalSubjectIDs {
 			if subject == m.ManagedLBID {
 				return true
@@ -108,11 +388,14 @@ func (m Manager) loadbalancerTargeted(msg events.ChangeMessage) bool {
 	return false
 }
 
-// ProcessMsg message handler
-func (m *Manager) ProcessMsg(msg events.Message[events.ChangeMessage]) error {
+// ProcessMsg message handler, the config update is abandoned when ctx is done
+func (m *Manager) ProcessMsg(ctx context.Context, msg events.Message[events.ChangeMessage]) error {
 	changeMsg := msg.Message()
 
-	mlogger := m.Logger.With(
+	// follow the update back to the message, and the change which caused it
+	ctx = WithCorrelationID(ctx, correlationID(changeMsg, msg.ID()))
+
+	mlogger := m.logger(ctx).With(
 		"event.message.id", msg.ID(),
 		"event.message.topic", msg.Topic(),
 		"event.message.source", msg.Source(),
@@ -127,6 +410,12 @@ func (m *Manager) ProcessMsg(msg events.Message[events.ChangeMessage]) error {
 	case events.DeleteChangeType:
 		fallthrough
 	case events.UpdateChangeType:
+		// drop msg, if not about a loadbalancer api resource
+		if !supportedSubjectPrefix(changeMsg.SubjectID) {
+			m.logger(ctx).Debugw("ignoring msg, subject prefix not supported", zap.String("subjectID", changeMsg.SubjectID.String()))
+			return nil
+		}
+
 		// drop msg, if not targeted for this lb
 		if !m.loadbalancerTargeted(changeMsg) {
 			return nil
@@ -134,12 +423,23 @@ func (m *Manager) ProcessMsg(msg events.Message[events.ChangeMessage]) error {
 
 		mlogger.Infow("msg received")
 
-		if err := m.updateConfigToLatest(); err != nil {
-			mlogger.Errorw("failed to update haproxy config")
+		result, err := m.updateConfigToLatest(ctx)
+		if err != nil {
+			mlogger.Errorw("failed to update haproxy config", zap.Error(err))
+
+			if isPermanent(err) {
+				return pubsub.Permanent(err)
+			}
+
 			return err
 		}
+
+		mlogger.Debugw("msg processed",
+			"changed", result.Changed,
+			"hash", result.Hash,
+			"duration", result.Duration)
 	default:
-		m.Logger.Debugw("ignoring msg, not a create/update/delete event",
+		m.logger(ctx).Debugw("ignoring msg, not a create/update/delete event",
 			zap.String("event-type", changeMsg.EventType),
 			zap.String("messageID", msg.ID()))
 	}
@@ -147,91 +447,249 @@ func (m *Manager) ProcessMsg(msg events.Message[events.ChangeMessage]) error {
 	return nil
 }
 
-// updateConfigToLatest update the haproxy cfg to either baseline or one requested from lbapi with optional lbID param
-func (m *Manager) updateConfigToLatest() error {
-	m.Logger.Infow("updating haproxy config", zap.String("loadbalancerID", m.ManagedLBID.String()))
+// RenderConfig returns the haproxy config for the managed loadbalancer without applying it
+func (m *Manager) RenderConfig() (string, error) {
+	cfg, lb, err := m.desiredConfig(m.Context)
+	if err != nil {
+		return "", err
+	}
+
+	return m.render(cfg, lb), nil
+}
+
+// render returns the config of the loadbalancer as it is applied to haproxy, canonicalized and
+// annotated when enabled
+func (m *Manager) render(cfg parser.Parser, lb *lbapi.LoadBalancer) string {
+	config := haproxyconfig.Render(cfg)
 
-	if m.ManagedLBID == "" {
-		return errLoadBalancerIDParamInvalid
+	if m.CanonicalConfig {
+		config = haproxyconfig.Canonicalize(config)
 	}
 
-	// load base config
-	cfg, err := parser.New(options.Path(m.BaseCfgPath), options.NoNamedDefaultsFrom)
+	if m.AnnotateSections {
+		config = haproxyconfig.Annotate(config, lb, m.Settings, time.Now())
+	}
+
+	return config
+}
+
+// baseConfig parses the base config, from its fragments when a directory is set
+func (m *Manager) baseConfig() (parser.Parser, error) {
+	if m.BaseCfgDir != "" {
+		return haproxyconfig.ParseBaseDir(m.BaseCfgDir)
+	}
+
+	return haproxyconfig.ParseBase(m.BaseCfgPath)
+}
+
+// desiredConfig returns the desired config of the managed loadbalancer, and the loadbalancer
+func (m *Manager) desiredConfig(ctx context.Context) (parser.Parser, *lbapi.LoadBalancer, error) {
+	return m.desiredConfigFor(ctx, m.ManagedLBID)
+}
+
+// desiredConfigFor requests the desired state of the loadbalancer from lbapi, merges it into the
+// base config of its profile and runs the config hooks on the result. The loadbalancer is returned
+// with the config.
+func (m *Manager) desiredConfigFor(ctx context.Context, id gidx.PrefixedID) (parser.Parser, *lbapi.LoadBalancer, error) {
+	if id == "" {
+		return nil, nil, errLoadBalancerIDParamInvalid
+	}
+
+	// get desired state from lbapi, through the same bounded fetch as several loadbalancers
+	lbs, err := m.FetchLoadBalancers(ctx, []gidx.PrefixedID{id})
 	if err != nil {
-		m.Logger.Fatalw("failed to load haproxy base config", zap.Error(err))
+		return nil, nil, err
 	}
 
-	// get desired state from lbapi
-	lb, err := m.LBClient.GetLoadBalancer(m.Context, m.ManagedLBID.String())
+	lb := lbs[id]
+
+	// load base config, which depends on the loadbalancer when profiles are set
+	cfg, err := m.baseConfigFor(lb)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("%w: %w", errBaseConfigLoad, err)
+	}
+
+	if m.Settings.LoadBalancerDisabled(lb.ID) {
+		m.logger(ctx).Infow("loadbalancer is disabled, applying its maintenance config", zap.String("loadbalancerID", lb.ID))
 	}
 
 	// merge response
-	cfg, err = mergeConfig(cfg, lb)
+	cfg, err = m.mergeStrategy().Merge(cfg, lb)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// check dataplaneapi to see if a valid config
-	if err := m.DataPlaneClient.CheckConfig(m.Context, cfg.String()); err != nil {
-		return err
+	if m.ConfigTemplate != nil {
+		cfg, err = haproxyconfig.ApplyTemplate(cfg, m.ConfigTemplate, lb)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// post dataplaneapi
-	if err := m.DataPlaneClient.PostConfig(m.Context, cfg.String()); err != nil {
-		return err
+	if err := m.runConfigHooks(cfg); err != nil {
+		return nil, nil, err
 	}
 
-	m.Logger.Infow("config successfully updated", zap.String("loadbalancerID", m.ManagedLBID.String()))
-	m.currentConfig = cfg.String() // for testing
-
-	return nil
+	return cfg, lb, nil
 }
 
-// mergeConfig takes the response from lb api, merges with the base haproxy config and returns it
-func mergeConfig(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error) {
-	for _, p := range lb.Ports.Edges {
-		// create port
-		if err := cfg.SectionsCreate(parser.Frontends, p.Node.ID); err != nil {
-			return nil, newLabelError(p.Node.ID, errFrontendSectionLabelFailure, err)
-		}
+// updateConfigToLatest update the haproxy cfg to either baseline or one requested from lbapi with
+// optional lbID param, returning whether the update changed the config, its hash and how long it took
+func (m *Manager) updateConfigToLatest(ctx context.Context) (ApplyResult, error) {
+	requested := time.Now()
+
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
 
-		if err := cfg.Insert(parser.Frontends, p.Node.ID, "bind", types.Bind{
-			// TODO AddressFamily?
-			Path: fmt.Sprintf("%s@:%d", "ipv4", p.Node.Number)}); err != nil {
-			return nil, newAttrError(errFrontendBindFailure, err)
+	// the desired state was fetched after this update was requested, so it's already applied
+	if m.lastFetched.After(requested) {
+		m.logger(ctx).Debugw("config update coalesced", zap.String("loadbalancerID", m.ManagedLBID.String()))
+		return ApplyResult{Hash: configHash(m.CurrentConfig())}, nil
+	}
+
+	if wait := m.MinReloadInterval - time.Since(m.lastApplied); m.MinReloadInterval > 0 && wait > 0 {
+		m.logger(ctx).Infow("waiting for the minimum reload interval", "wait", wait)
+
+		select {
+		case <-ctx.Done():
+			return ApplyResult{}, ctx.Err()
+		case <-time.After(wait):
 		}
+	}
+
+	fetched := time.Now()
 
-		// map frontend to backend
-		if err := cfg.Set(parser.Frontends, p.Node.ID, "use_backend", types.UseBackend{Name: p.Node.ID}); err != nil {
-			return nil, newAttrError(errUseBackendFailure, err)
+	result, err := m.applyLatest(ctx)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	m.lastFetched = fetched
+	m.lastApplied = time.Now()
+
+	result.Duration = m.lastApplied.Sub(fetched)
+
+	return result, nil
+}
+
+// applyLatest validates and applies the desired config, returning whether it changed and its hash
+func (m *Manager) applyLatest(ctx context.Context) (result ApplyResult, err error) {
+	m.logger(ctx).Infow("updating haproxy config", zap.String("loadbalancerID", m.ManagedLBID.String()))
+
+	cfg, lb, err := m.desiredConfig(ctx)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	config := m.render(cfg, lb)
+	previous := m.CurrentConfig()
+
+	// annotations carry the time the config was rendered, so they're left out of the comparison
+	if haproxyconfig.StripAnnotations(config) == haproxyconfig.StripAnnotations(previous) {
+		m.logger(ctx).Infow("haproxy config unchanged", zap.String("loadbalancerID", m.ManagedLBID.String()))
+
+		return ApplyResult{Hash: configHash(previous)}, nil
+	}
+
+	defer func() {
+		m.recordApply(ctx, m.ManagedLBID.String(), previous, config, err)
+	}()
+
+	if err := m.checkConfigSize(config); err != nil {
+		return ApplyResult{}, err
+	}
+
+	crtLists, err := m.stageCrtLists(lb)
+	if err != nil {
+		return ApplyResult{}, err
+	}
+
+	// the lists are in place when haproxy checks the config, and restored when it isn't applied
+	defer func() {
+		if err != nil {
+			m.restoreCrtLists(ctx, crtLists)
 		}
+	}()
+
+	if err := crtLists.install(); err != nil {
+		return ApplyResult{}, err
+	}
+
+	result = ApplyResult{Changed: true, Hash: configHash(config)}
 
-		// create backend
-		if err := cfg.SectionsCreate(parser.Backends, p.Node.ID); err != nil {
-			return nil, newLabelError(p.Node.ID, errBackendSectionLabelFailure, err)
+	// check dataplaneapi to see if a valid config
+	if !m.SkipCheck {
+		if err := m.DataPlaneClient.CheckConfig(ctx, config); err != nil {
+			m.logValidationError(ctx, config, err)
+
+			return ApplyResult{}, err
 		}
+	}
 
-		for _, pool := range p.Node.Pools {
-			for _, origin := range pool.Origins.Edges {
-				srvAddr := fmt.Sprintf("%s:%d check port %d", origin.Node.Target, origin.Node.PortNumber, origin.Node.PortNumber)
+	if m.applyRuntime(ctx, config) {
+		m.setCurrentConfig(m.ManagedLBID.String(), config)
 
-				if !origin.Node.Active {
-					srvAddr += " disabled"
-				}
+		return result, nil
+	}
 
-				srvr := types.Server{
-					Name:    origin.Node.ID,
-					Address: srvAddr,
-				}
+	// post dataplaneapi
+	if err := m.DataPlaneClient.PostConfig(ctx, config); err != nil {
+		return ApplyResult{}, err
+	}
 
-				if err := cfg.Set(parser.Backends, p.Node.ID, "server", srvr); err != nil {
-					return nil, newLabelError(p.Node.ID, errBackendServerFailure, err)
-				}
-			}
+	if m.RollbackOnFailure {
+		if err := m.verifyFrontends(ctx, cfg); err != nil {
+			return ApplyResult{}, m.rollback(ctx, err)
 		}
 	}
 
-	return cfg, nil
+	m.logger(ctx).Infow("config successfully updated", zap.String("loadbalancerID", m.ManagedLBID.String()))
+	m.setCurrentConfig(m.ManagedLBID.String(), config)
+
+	return result, nil
+}
+
+// checkConfigSize returns an error when the config is larger than MaxConfigBytes
+func (m *Manager) checkConfigSize(config string) error {
+	if m.MaxConfigBytes > 0 && len(config) > m.MaxConfigBytes {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", errConfigTooLarge, len(config), m.MaxConfigBytes)
+	}
+
+	return nil
+}
+
+// logValidationError logs where haproxy rejected the config when the dataplaneapi reports it,
+// with the rejected line, as the config itself isn't logged
+func (m *Manager) logValidationError(ctx context.Context, config string, err error) {
+	var validationErr *dataplaneapi.DataPlaneValidationError
+
+	if !errors.As(err, &validationErr) {
+		return
+	}
+
+	fields := []interface{}{"code", validationErr.Code, "message", validationErr.Message}
+
+	if lines := strings.Split(config, "\n"); validationErr.Line > 0 && validationErr.Line <= len(lines) {
+		fields = append(fields, "line", validationErr.Line, "config-line", strings.TrimSpace(lines[validationErr.Line-1]))
+	}
+
+	m.logger(ctx).Errorw("dataplaneapi rejected the config", fields...)
+}
+
+// CurrentConfig returns the last successfully applied config, or an empty string when none has been
+func (m *Manager) CurrentConfig() string {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+
+	return m.currentConfig
+}
+
+// setCurrentConfig records the config of the loadbalancer as successfully applied
+func (m *Manager) setCurrentConfig(id, config string) {
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	m.currentConfig = config
+	m.currentLBID = id
+	m.currentAppliedAt = time.Now()
 }
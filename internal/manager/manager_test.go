@@ -6,11 +6,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	parser "github.com/haproxytech/config-parser/v4"
-	"github.com/haproxytech/config-parser/v4/options"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -21,49 +20,17 @@ import (
 
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
 
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
 	"go.infratographer.com/loadbalancer-manager-haproxy/internal/pubsub"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
 )
 
 const (
-	testDataBaseDir = "testdata"
+	testDataBaseDir = "../../pkg/haproxyconfig/testdata"
 	testBaseCfgPath = "../../.devcontainer/config/haproxy.cfg"
 )
 
-func TestMergeConfig(t *testing.T) {
-	MergeConfigTests := []struct {
-		name                string
-		testInput           lbapi.LoadBalancer
-		expectedCfgFilename string
-	}{
-		{"ssh service one pool", mergeTestData1, "lb-ex-1-exp.cfg"},
-		{"ssh service two pools", mergeTestData2, "lb-ex-2-exp.cfg"},
-		{"http and https", mergeTestData3, "lb-ex-3-exp.cfg"},
-	}
-
-	for _, tt := range MergeConfigTests {
-		// go vet
-		tt := tt
-
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-
-			cfg, err := parser.New(options.Path("../../.devcontainer/config/haproxy.cfg"), options.NoNamedDefaultsFrom)
-			require.Nil(t, err)
-
-			newCfg, err := mergeConfig(cfg, &tt.testInput)
-			assert.Nil(t, err)
-
-			t.Log("Generated config ===> ", newCfg.String())
-
-			expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, tt.expectedCfgFilename))
-			require.Nil(t, err)
-
-			assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(newCfg.String()))
-		})
-	}
-}
-
 func TestUpdateConfigToLatest(t *testing.T) {
 	l, err := zap.NewDevelopmentConfig().Build()
 	logger := l.Sugar()
@@ -86,7 +53,7 @@ func TestUpdateConfigToLatest(t *testing.T) {
 			ManagedLBID: gidx.PrefixedID("loadbal-testing"),
 		}
 
-		err := mgr.updateConfigToLatest()
+		_, err := mgr.updateConfigToLatest(mgr.Context)
 		assert.NotNil(t, err)
 	})
 
@@ -109,7 +76,7 @@ func TestUpdateConfigToLatest(t *testing.T) {
 		}
 
 		// initial config
-		err := mgr.updateConfigToLatest()
+		_, err := mgr.updateConfigToLatest(mgr.Context)
 		require.Error(t, err)
 	})
 
@@ -119,7 +86,7 @@ func TestUpdateConfigToLatest(t *testing.T) {
 			BaseCfgPath: testBaseCfgPath,
 		}
 
-		err := mgr.updateConfigToLatest()
+		_, err := mgr.updateConfigToLatest(mgr.Context)
 		require.ErrorIs(t, err, errLoadBalancerIDParamInvalid)
 	})
 
@@ -152,16 +119,12 @@ func TestUpdateConfigToLatest(t *testing.T) {
 			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
 		}
 
-		err := mgr.updateConfigToLatest()
+		_, err := mgr.updateConfigToLatest(mgr.Context)
 		require.Nil(t, err)
 
 		contents, err := os.ReadFile(testBaseCfgPath)
 		require.Nil(t, err)
 
-		// remove that 'unnamed_defaults_1' thing the haproxy parser library puts in the default section,
-		// even though the library is configured to not include default section labels
-		mgr.currentConfig = strings.ReplaceAll(mgr.currentConfig, " unnamed_defaults_1", "")
-
 		assert.Equal(t, strings.TrimSpace(string(contents)), strings.TrimSpace(mgr.currentConfig))
 	})
 
@@ -242,14 +205,710 @@ func TestUpdateConfigToLatest(t *testing.T) {
 			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
 		}
 
-		err := mgr.updateConfigToLatest()
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.Nil(t, err)
+
+		expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-1-exp.cfg"))
 		require.Nil(t, err)
 
+		assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(mgr.currentConfig))
+	})
+}
+
+func TestSkipCheck(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	for _, skip := range []bool{false, true} {
+		checked, posted := 0, 0
+
+		mgr := Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					checked++
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					posted++
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath: testBaseCfgPath,
+			ManagedLBID: gidx.PrefixedID("loadbal-test"),
+			SkipCheck:   skip,
+		}
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, posted)
+
+		if skip {
+			assert.Zero(t, checked, "config checked with skip enabled")
+		} else {
+			assert.Equal(t, 1, checked)
+		}
+	}
+}
+
+func TestMaxConfigBytes(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	// a loadbalancer whose pool has a thousand origins
+	origins := []lbapi.OriginEdges{}
+
+	for i := 0; i < 1000; i++ {
+		origins = append(origins, lbapi.OriginEdges{Node: lbapi.OriginNode{
+			ID:         fmt.Sprintf("loadogn-test%d", i),
+			Target:     fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			PortNumber: 22,
+			Active:     true,
+		}})
+	}
+
+	large := lbapi.LoadBalancer{
+		ID: "loadbal-test",
+		Ports: lbapi.Ports{Edges: []lbapi.PortEdges{{Node: lbapi.PortNode{
+			ID:     "loadprt-test",
+			Number: 22,
+			Pools:  []lbapi.Pool{{ID: "loadpol-test", Origins: lbapi.Origins{Edges: origins}}},
+		}}}},
+	}
+
+	for _, limit := range []int{0, 4096} {
+		posted := 0
+
+		mgr := Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					posted++
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &large, nil
+				},
+			},
+			BaseCfgPath:    testBaseCfgPath,
+			ManagedLBID:    gidx.PrefixedID("loadbal-test"),
+			MaxConfigBytes: limit,
+		}
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+
+		if limit == 0 {
+			require.NoError(t, err, "unlimited config size")
+			assert.Equal(t, 1, posted)
+
+			continue
+		}
+
+		require.ErrorIs(t, err, errConfigTooLarge)
+		assert.ErrorContains(t, err, "exceeds the limit of 4096 bytes")
+		assert.True(t, isPermanent(err))
+		assert.Zero(t, posted, "config posted beyond the limit")
+	}
+}
+
+func TestLoadBalancerDisabled(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	for _, disabled := range []bool{false, true} {
+		posted := ""
+
+		mgr := Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					posted = config
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath: testBaseCfgPath,
+			ManagedLBID: gidx.PrefixedID("loadbal-test"),
+			Settings: haproxyconfig.Settings{
+				LoadBalancers: []haproxyconfig.LoadBalancerSettings{{ID: "loadbal-test", Disabled: disabled}},
+			},
+		}
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		// the port stays bound either way
+		assert.Contains(t, posted, "frontend loadprt-test\n")
+
+		if disabled {
+			assert.NotContains(t, posted, "server loadogn-", "servers of a disabled loadbalancer")
+			assert.Contains(t, posted, "tcp-request content reject")
+		} else {
+			assert.Contains(t, posted, "server loadogn-test1 1.2.3.4:2222")
+			assert.NotContains(t, posted, "tcp-request content reject")
+		}
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	mockLBAPI := &mock.LBAPIClient{
+		DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+			return &mergeTestData1, nil
+		},
+	}
+
+	t.Run("applies config once without a subscriber", func(t *testing.T) {
+		posted := 0
+		closed := false
+
+		mockDataplaneAPI := &mock.DataplaneAPIClient{
+			DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+				return nil
+			},
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				posted++
+				return nil
+			},
+			DoClose: func() {
+				closed = true
+			},
+		}
+
+		mgr := Manager{
+			Context:         context.Background(),
+			Logger:          logger,
+			LBClient:        mockLBAPI,
+			DataPlaneClient: mockDataplaneAPI,
+			BaseCfgPath:     testBaseCfgPath,
+			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+		}
+
+		err := mgr.RunOnce()
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, posted)
+		assert.True(t, closed, "dataplaneapi connections should be closed on return")
+
 		expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-1-exp.cfg"))
 		require.Nil(t, err)
 
 		assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(mgr.currentConfig))
 	})
+
+	t.Run("waits the post-ready grace before applying", func(t *testing.T) {
+		var ready, checked time.Time
+
+		mockDataplaneAPI := &mock.DataplaneAPIClient{
+			DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+				ready = time.Now()
+				return nil
+			},
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				checked = time.Now()
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+		}
+
+		mgr := Manager{
+			Context:         context.Background(),
+			Logger:          logger,
+			LBClient:        mockLBAPI,
+			DataPlaneClient: mockDataplaneAPI,
+			BaseCfgPath:     testBaseCfgPath,
+			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+			PostReadyGrace:  50 * time.Millisecond,
+		}
+
+		require.NoError(t, mgr.RunOnce())
+		assert.GreaterOrEqual(t, checked.Sub(ready), 50*time.Millisecond)
+	})
+
+	t.Run("stops waiting the post-ready grace when canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		mockDataplaneAPI := &mock.DataplaneAPIClient{
+			DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+				cancel()
+				return nil
+			},
+		}
+
+		mgr := Manager{
+			Context:         ctx,
+			Logger:          logger,
+			LBClient:        mockLBAPI,
+			DataPlaneClient: mockDataplaneAPI,
+			BaseCfgPath:     testBaseCfgPath,
+			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+			PostReadyGrace:  time.Hour,
+		}
+
+		assert.ErrorIs(t, mgr.RunOnce(), context.Canceled)
+		assert.Empty(t, mgr.currentConfig)
+	})
+
+	t.Run("returns error when dataplaneapi is not ready", func(t *testing.T) {
+		mockDataplaneAPI := &mock.DataplaneAPIClient{
+			DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+				return errors.New("not ready") // nolint:goerr113
+			},
+		}
+
+		mgr := Manager{
+			Context:         context.Background(),
+			Logger:          logger,
+			LBClient:        mockLBAPI,
+			DataPlaneClient: mockDataplaneAPI,
+			BaseCfgPath:     testBaseCfgPath,
+			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+		}
+
+		err := mgr.RunOnce()
+		require.Error(t, err)
+		assert.Empty(t, mgr.currentConfig)
+	})
+}
+
+func TestRun(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	mockLBAPI := &mock.LBAPIClient{
+		DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+			return &mergeTestData1, nil
+		},
+	}
+
+	newDataplaneAPI := func(readyErr, checkErr error) *mock.DataplaneAPIClient {
+		return &mock.DataplaneAPIClient{
+			DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+				return readyErr
+			},
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return checkErr
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+		}
+	}
+
+	listened := false
+
+	mockSubscriber := &mock.Subscriber{
+		DoListen: func() error {
+			listened = true
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		dataplane  dataPlaneAPI
+		lbClient   lbAPI
+		subscriber eventSubscriber
+		baseCfg    string
+		expErr     error
+	}{
+		{"dataplaneapi client missing", nil, mockLBAPI, mockSubscriber, testBaseCfgPath, errDataPlaneClientNotInitialized},
+		{"lbapi client missing", newDataplaneAPI(nil, nil), nil, mockSubscriber, testBaseCfgPath, errLBClientNotInitialized},
+		{"subscriber missing", newDataplaneAPI(nil, nil), mockLBAPI, nil, testBaseCfgPath, errSubscriberNotInitialized},
+		{"dataplaneapi unreachable", newDataplaneAPI(dataplaneapi.ErrDataPlaneNotReady, nil), mockLBAPI, mockSubscriber, testBaseCfgPath, errDataPlaneUnreachable},
+		{"config rejected", newDataplaneAPI(nil, dataplaneapi.ErrDataPlaneConfigInvalid), mockLBAPI, mockSubscriber, testBaseCfgPath, errConfigInitFailure},
+		{"base config missing", newDataplaneAPI(nil, nil), mockLBAPI, mockSubscriber, "testdata/missing.cfg", errBaseConfigLoad},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := Manager{
+				Context:         context.Background(),
+				Logger:          logger,
+				LBClient:        tt.lbClient,
+				DataPlaneClient: tt.dataplane,
+				Subscriber:      tt.subscriber,
+				BaseCfgPath:     tt.baseCfg,
+				ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+			}
+
+			err := mgr.Run()
+			assert.ErrorIs(t, err, tt.expErr)
+			assert.False(t, listened)
+		})
+	}
+
+	t.Run("listens once the config is applied", func(t *testing.T) {
+		mgr := Manager{
+			Context:         context.Background(),
+			Logger:          logger,
+			LBClient:        mockLBAPI,
+			DataPlaneClient: newDataplaneAPI(nil, nil),
+			Subscriber:      mockSubscriber,
+			BaseCfgPath:     testBaseCfgPath,
+			ManagedLBID:     gidx.PrefixedID("loadbal-test"),
+		}
+
+		require.NoError(t, mgr.Run())
+		assert.True(t, listened)
+	})
+}
+
+func TestRunPolling(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	t.Run("requires a poll interval", func(t *testing.T) {
+		mgr := Manager{
+			Context:         context.Background(),
+			Logger:          logger,
+			LBClient:        &mock.LBAPIClient{},
+			DataPlaneClient: &mock.DataplaneAPIClient{},
+		}
+
+		assert.ErrorIs(t, mgr.RunPolling(), errPollIntervalInvalid)
+	})
+
+	t.Run("applies changes polled from lbapi", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		mu := sync.Mutex{}
+		lb := &mergeTestData1
+		posted := make(chan string, 10)
+
+		mgr := Manager{
+			Context: ctx,
+			Logger:  logger,
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					return lb, nil
+				},
+			},
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoWaitForDataPlaneReady: func(ctx context.Context, retries int, sleep time.Duration) error {
+					return nil
+				},
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					posted <- config
+					return nil
+				},
+			},
+			BaseCfgPath:  testBaseCfgPath,
+			ManagedLBID:  gidx.PrefixedID("loadbal-test"),
+			PollInterval: 10 * time.Millisecond,
+		}
+
+		done := make(chan error, 1)
+
+		go func() {
+			done <- mgr.RunPolling()
+		}()
+
+		// the initial config is applied on start
+		select {
+		case config := <-posted:
+			assert.NotContains(t, config, "loadogn-test4")
+		case <-time.After(time.Second):
+			t.Fatal("initial config was not applied")
+		}
+
+		// polling an unchanged loadbalancer doesn't reload haproxy
+		select {
+		case <-posted:
+			t.Fatal("unchanged config was applied")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		mu.Lock()
+		lb = &mergeTestData2
+		mu.Unlock()
+
+		select {
+		case config := <-posted:
+			assert.Contains(t, config, "loadogn-test4")
+		case <-time.After(time.Second):
+			t.Fatal("polled change was not applied")
+		}
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("polling didn't stop when the context was done")
+		}
+	})
+}
+
+func TestRollbackOnFailure(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	mockLBAPI := &mock.LBAPIClient{
+		DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+			return &mergeTestData1, nil
+		},
+	}
+
+	newManager := func(posted *[]string, status map[string]string) Manager {
+		return Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					*posted = append(*posted, config)
+					return nil
+				},
+				DoFrontendStatus: func(ctx context.Context) (map[string]string, error) {
+					return status, nil
+				},
+			},
+			LBClient:                    mockLBAPI,
+			BaseCfgPath:                 testBaseCfgPath,
+			ManagedLBID:                 gidx.PrefixedID("loadbal-test"),
+			RollbackOnFailure:           true,
+			PostApplyCheckAttempts:      2,
+			PostApplyCheckRetryInterval: time.Millisecond,
+		}
+	}
+
+	t.Run("keeps config when frontends are open", func(t *testing.T) {
+		posted := []string{}
+
+		mgr := newManager(&posted, map[string]string{"loadprt-test": "OPEN", "stats": "OPEN"})
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Len(t, posted, 1)
+		assert.Equal(t, posted[0], mgr.currentConfig)
+	})
+
+	t.Run("rolls back when a frontend is down", func(t *testing.T) {
+		posted := []string{}
+
+		mgr := newManager(&posted, map[string]string{"loadprt-test": "STOP"})
+		mgr.currentConfig = "previous config"
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.ErrorIs(t, err, errPostApplyCheckFailed)
+		assert.ErrorContains(t, err, "loadprt-test")
+
+		require.Len(t, posted, 2)
+		assert.Equal(t, "previous config", posted[1])
+		assert.Equal(t, "previous config", mgr.currentConfig)
+	})
+
+	t.Run("no previous config to roll back to", func(t *testing.T) {
+		posted := []string{}
+
+		mgr := newManager(&posted, map[string]string{})
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.ErrorIs(t, err, errPostApplyCheckFailed)
+
+		assert.Len(t, posted, 1)
+		assert.Empty(t, mgr.currentConfig)
+	})
+}
+
+func TestMinReloadInterval(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	newManager := func(posted *[]time.Time, mu *sync.Mutex, lb func() *lbapi.LoadBalancer) *Manager {
+		return &Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					mu.Lock()
+					defer mu.Unlock()
+
+					*posted = append(*posted, time.Now())
+
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return lb(), nil
+				},
+			},
+			BaseCfgPath:       testBaseCfgPath,
+			ManagedLBID:       gidx.PrefixedID("loadbal-test"),
+			MinReloadInterval: 100 * time.Millisecond,
+		}
+	}
+
+	t.Run("updates wait for the interval", func(t *testing.T) {
+		mu := &sync.Mutex{}
+		posted := []time.Time{}
+
+		lb := &mergeTestData1
+
+		// unchanged configs aren't posted, so the second update has another loadbalancer state
+		mgr := newManager(&posted, mu, func() *lbapi.LoadBalancer { return lb })
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		lb = &mergeTestData2
+
+		_, err = mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		require.Len(t, posted, 2)
+		assert.GreaterOrEqual(t, posted[1].Sub(posted[0]), 100*time.Millisecond)
+	})
+
+	t.Run("waiting updates are coalesced with the latest state", func(t *testing.T) {
+		mu := &sync.Mutex{}
+		posted := []time.Time{}
+		lb := &mergeTestData1
+
+		mgr := newManager(&posted, mu, func() *lbapi.LoadBalancer {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return lb
+		})
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		mu.Lock()
+		lb = &mergeTestData2
+		mu.Unlock()
+
+		wg := sync.WaitGroup{}
+
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := mgr.updateConfigToLatest(mgr.Context)
+				assert.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Len(t, posted, 2)
+
+		expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-2-exp.cfg"))
+		require.Nil(t, err)
+
+		assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(mgr.currentConfig))
+	})
+
+	t.Run("context done while waiting", func(t *testing.T) {
+		mu := &sync.Mutex{}
+		posted := []time.Time{}
+
+		mgr := newManager(&posted, mu, func() *lbapi.LoadBalancer { return &mergeTestData1 })
+		mgr.MinReloadInterval = time.Minute
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = mgr.updateConfigToLatest(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, posted, 1)
+	})
+}
+
+func TestSupportedSubjectPrefix(t *testing.T) {
+	tests := []struct {
+		id        gidx.PrefixedID
+		supported bool
+	}{
+		{"loadbal-test", true},
+		{"loadprt-test", true},
+		{"loadpol-test", true},
+		{"loadogn-test", true},
+		{"ipamipa-test", true},
+		{"tnntten-test", false},
+		{"invalid-", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.id.String(), func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.supported, supportedSubjectPrefix(tt.id))
+		})
+	}
 }
 
 func TestLoadBalancerTargeted(t *testing.T) {
@@ -259,6 +918,7 @@ func TestLoadBalancerTargeted(t *testing.T) {
 	testcases := []struct {
 		name             string
 		pubsubMsg        events.ChangeMessage
+		strict           bool
 		msgTargetedForLB bool
 	}{
 		{
@@ -277,6 +937,14 @@ func TestLoadBalancerTargeted(t *testing.T) {
 			},
 			msgTargetedForLB: true,
 		},
+		{
+			name: "ip address change targeted for loadbalancer",
+			pubsubMsg: events.ChangeMessage{
+				SubjectID:            gidx.PrefixedID("ipamipa-testing"),
+				AdditionalSubjectIDs: []gidx.PrefixedID{"loadbal-testing"},
+			},
+			msgTargetedForLB: true,
+		},
 		{
 			name: "msg is not targeted for loadbalancer",
 			pubsubMsg: events.ChangeMessage{
@@ -285,11 +953,24 @@ func TestLoadBalancerTargeted(t *testing.T) {
 			},
 			msgTargetedForLB: false,
 		},
-	}
-
-	mgr := Manager{
-		ManagedLBID: gidx.PrefixedID("loadbal-testing"),
-		Logger:      logger,
+		{
+			name: "subjectID targeted for loadbalancer with strict targeting",
+			pubsubMsg: events.ChangeMessage{
+				SubjectID:            gidx.PrefixedID("loadbal-testing"),
+				AdditionalSubjectIDs: []gidx.PrefixedID{"loadpol-testing"},
+			},
+			strict:           true,
+			msgTargetedForLB: true,
+		},
+		{
+			name: "AdditionalSubjectID ignored with strict targeting",
+			pubsubMsg: events.ChangeMessage{
+				SubjectID:            gidx.PrefixedID("loadprt-testing"),
+				AdditionalSubjectIDs: []gidx.PrefixedID{"loadbal-testing"},
+			},
+			strict:           true,
+			msgTargetedForLB: false,
+		},
 	}
 
 	for _, tt := range testcases {
@@ -299,6 +980,12 @@ func TestLoadBalancerTargeted(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
+			mgr := Manager{
+				ManagedLBID:     gidx.PrefixedID("loadbal-testing"),
+				Logger:          logger,
+				StrictTargeting: tt.strict,
+			}
+
 			targeted := mgr.loadbalancerTargeted(tt.pubsubMsg)
 			assert.Equal(t, tt.msgTargetedForLB, targeted)
 		})
@@ -360,7 +1047,7 @@ func TestProcessMsg(t *testing.T) {
 
 		t.Run(tt.name, func(t *testing.T) {
 			msg := PublishTestMessage(t, context.Background(), eventsConn, tt.pubsubMsg)
-			err := mgr.ProcessMsg(msg)
+			err := mgr.ProcessMsg(mgr.Context, msg)
 
 			if tt.errMsg != "" {
 				require.Error(t, err)
@@ -403,9 +1090,104 @@ func TestProcessMsg(t *testing.T) {
 			EventType: string(events.CreateChangeType),
 		})
 
-		err = mgr.ProcessMsg(msg)
+		err = mgr.ProcessMsg(mgr.Context, msg)
 		require.Nil(t, err)
 	})
+
+	t.Run("ip address change updates the config", func(t *testing.T) {
+		posted := 0
+
+		mgr := &Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					posted++
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &lbapi.LoadBalancer{
+						ID: "loadbal-managedbythisprocess",
+					}, nil
+				},
+			},
+			ManagedLBID: gidx.PrefixedID("loadbal-managedbythisprocess"),
+		}
+
+		msg := PublishTestMessage(t, mgr.Context, eventsConn, events.ChangeMessage{
+			SubjectID:            gidx.PrefixedID("ipamipa-reserved"),
+			AdditionalSubjectIDs: []gidx.PrefixedID{"loadbal-managedbythisprocess"},
+			EventType:            string(events.UpdateChangeType),
+		})
+
+		require.NoError(t, mgr.ProcessMsg(mgr.Context, msg))
+		assert.Equal(t, 1, posted)
+	})
+
+	t.Run("permanent and transient errors", func(t *testing.T) {
+		errUnavailable := errors.New("lbapi unavailable") // nolint:goerr113
+
+		invalidTarget := lbapi.LoadBalancer{
+			ID: "loadbal-managedbythisprocess",
+			Ports: lbapi.Ports{Edges: []lbapi.PortEdges{{Node: lbapi.PortNode{
+				ID:     "loadprt-test",
+				Number: 22,
+				Pools: []lbapi.Pool{{
+					ID: "loadpol-test",
+					Origins: lbapi.Origins{Edges: []lbapi.OriginEdges{{Node: lbapi.OriginNode{
+						ID:         "loadogn-test",
+						Target:     "not a host!",
+						PortNumber: 22,
+					}}}},
+				}},
+			}}}},
+		}
+
+		tests := []struct {
+			name      string
+			lb        *lbapi.LoadBalancer
+			lbErr     error
+			permanent bool
+		}{
+			{"invalid origin target is permanent", &invalidTarget, nil, true},
+			{"lbapi failure is transient", nil, errUnavailable, false},
+		}
+
+		for _, tt := range tests {
+			// go vet
+			tt := tt
+
+			t.Run(tt.name, func(t *testing.T) {
+				mgr := &Manager{
+					Context: context.Background(),
+					Logger:  logger,
+					LBClient: &mock.LBAPIClient{
+						DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+							return tt.lb, tt.lbErr
+						},
+					},
+					BaseCfgPath: testBaseCfgPath,
+					ManagedLBID: gidx.PrefixedID("loadbal-managedbythisprocess"),
+				}
+
+				msg := PublishTestMessage(t, mgr.Context, eventsConn, events.ChangeMessage{
+					SubjectID: gidx.PrefixedID("loadbal-managedbythisprocess"),
+					EventType: string(events.UpdateChangeType),
+				})
+
+				err := mgr.ProcessMsg(mgr.Context, msg)
+				require.Error(t, err)
+
+				var permErr *pubsub.PermanentError
+				assert.Equal(t, tt.permanent, errors.As(err, &permErr))
+			})
+		}
+	})
 }
 
 func TestEventsIntegration(t *testing.T) {
@@ -673,68 +1455,3 @@ var mergeTestData2 = lbapi.LoadBalancer{
 		},
 	},
 }
-
-var mergeTestData3 = lbapi.LoadBalancer{
-	ID:   "loadbal-test",
-	Name: "http/https",
-	Ports: lbapi.Ports{
-		Edges: []lbapi.PortEdges{
-			{
-				Node: lbapi.PortNode{
-					// TODO - @rizzza - AddressFamily?
-					ID:     "loadprt-testhttp",
-					Name:   "http",
-					Number: 80,
-					Pools: []lbapi.Pool{
-						{
-							ID:       "loadpol-test",
-							Name:     "ssh-service-a",
-							Protocol: "tcp",
-							Origins: lbapi.Origins{
-								Edges: []lbapi.OriginEdges{
-									{
-										Node: lbapi.OriginNode{
-											ID:         "loadogn-test1",
-											Name:       "svr1",
-											Target:     "3.1.4.1",
-											PortNumber: 80,
-											Active:     true,
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-			{
-				Node: lbapi.PortNode{
-					// TODO - @rizzza - AddressFamily?
-					ID:     "loadprt-testhttps",
-					Name:   "https",
-					Number: 443,
-					Pools: []lbapi.Pool{
-						{
-							ID:       "loadpol-test",
-							Name:     "ssh-service-a",
-							Protocol: "tcp",
-							Origins: lbapi.Origins{
-								Edges: []lbapi.OriginEdges{
-									{
-										Node: lbapi.OriginNode{
-											ID:         "loadogn-test2",
-											Name:       "svr1",
-											Target:     "3.1.4.1",
-											PortNumber: 443,
-											Active:     true,
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	},
-}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
 )
 
 // LBAPIClient mock client
@@ -22,6 +24,14 @@ type DataplaneAPIClient struct {
 	DoCheckConfig           func(ctx context.Context, config string) error
 	DoAPIIsReady            func(ctx context.Context) bool
 	DoWaitForDataPlaneReady func(ctx context.Context, retries int, sleep time.Duration) error
+	DoFrontendStatus        func(ctx context.Context) (map[string]string, error)
+	DoPostConfigNoReload    func(ctx context.Context, config string) error
+	DoAddRuntimeServer      func(ctx context.Context, backend string, server dataplaneapi.RuntimeServer) error
+	DoDeleteRuntimeServer   func(ctx context.Context, backend, name string) error
+	DoSetRuntimeServerState func(ctx context.Context, backend, name, state string) error
+	DoGetConfig             func(ctx context.Context) (string, error)
+	DoHAProxyVersion        func(ctx context.Context) (string, error)
+	DoClose                 func()
 }
 
 func (c *DataplaneAPIClient) PostConfig(ctx context.Context, config string) error {
@@ -40,6 +50,44 @@ func (c DataplaneAPIClient) WaitForDataPlaneReady(ctx context.Context, retries i
 	return c.DoWaitForDataPlaneReady(ctx, retries, sleep)
 }
 
+func (c DataplaneAPIClient) FrontendStatus(ctx context.Context) (map[string]string, error) {
+	return c.DoFrontendStatus(ctx)
+}
+
+func (c DataplaneAPIClient) PostConfigWithoutReload(ctx context.Context, config string) error {
+	return c.DoPostConfigNoReload(ctx, config)
+}
+
+func (c DataplaneAPIClient) AddRuntimeServer(ctx context.Context, backend string, server dataplaneapi.RuntimeServer) error {
+	return c.DoAddRuntimeServer(ctx, backend, server)
+}
+
+func (c DataplaneAPIClient) DeleteRuntimeServer(ctx context.Context, backend, name string) error {
+	return c.DoDeleteRuntimeServer(ctx, backend, name)
+}
+
+func (c DataplaneAPIClient) SetRuntimeServerState(ctx context.Context, backend, name, state string) error {
+	return c.DoSetRuntimeServerState(ctx, backend, name, state)
+}
+
+func (c DataplaneAPIClient) GetConfig(ctx context.Context) (string, error) {
+	return c.DoGetConfig(ctx)
+}
+
+func (c DataplaneAPIClient) HAProxyVersion(ctx context.Context) (string, error) {
+	if c.DoHAProxyVersion == nil {
+		return "", nil
+	}
+
+	return c.DoHAProxyVersion(ctx)
+}
+
+func (c DataplaneAPIClient) Close() {
+	if c.DoClose != nil {
+		c.DoClose()
+	}
+}
+
 // Subscriber mock client
 type Subscriber struct {
 	DoClose     func() error
@@ -0,0 +1,83 @@
+package manager
+
+import (
+	parser "github.com/haproxytech/config-parser/v4"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// ProfileAttribute is the loadbalancer attribute selecting its base config profile
+type ProfileAttribute string
+
+const (
+	// ProfileAttributeLocation selects base config profiles by the ID of the loadbalancer's location
+	ProfileAttributeLocation ProfileAttribute = "location"
+
+	// ProfileAttributeOwner selects base config profiles by the ID of the loadbalancer's owner
+	ProfileAttributeOwner ProfileAttribute = "owner"
+
+	// ProfileAttributeName selects base config profiles by the loadbalancer's name
+	ProfileAttributeName ProfileAttribute = "name"
+)
+
+// ProfileAttributes are the supported profile attributes
+var ProfileAttributes = []ProfileAttribute{ProfileAttributeLocation, ProfileAttributeOwner, ProfileAttributeName}
+
+// Valid returns true when the attribute is supported
+func (a ProfileAttribute) Valid() bool {
+	for _, attribute := range ProfileAttributes {
+		if a == attribute {
+			return true
+		}
+	}
+
+	return false
+}
+
+// value returns the value of the attribute for the loadbalancer
+func (a ProfileAttribute) value(lb *lbapi.LoadBalancer) string {
+	switch a {
+	case ProfileAttributeLocation:
+		return lb.Location.ID
+	case ProfileAttributeOwner:
+		return lb.Owner.ID
+	case ProfileAttributeName:
+		return lb.Name
+	default:
+		return ""
+	}
+}
+
+// baseConfigFor parses the base config of the profile selected by the loadbalancer, or the default
+// base config when no profile matches it
+func (m *Manager) baseConfigFor(lb *lbapi.LoadBalancer) (parser.Parser, error) {
+	if path, ok := m.baseCfgProfilePath(lb); ok {
+		return haproxyconfig.ParseBase(path)
+	}
+
+	return m.baseConfig()
+}
+
+// baseCfgProfilePath returns the base config path of the profile selected by the loadbalancer's
+// BaseCfgProfileAttribute, and false when there is none
+func (m *Manager) baseCfgProfilePath(lb *lbapi.LoadBalancer) (string, bool) {
+	if len(m.BaseCfgProfiles) == 0 {
+		return "", false
+	}
+
+	attribute := m.BaseCfgProfileAttribute
+	if attribute == "" {
+		attribute = ProfileAttributeLocation
+	}
+
+	value := attribute.value(lb)
+	if value == "" {
+		return "", false
+	}
+
+	path, ok := m.BaseCfgProfiles[value]
+
+	return path, ok && path != ""
+}
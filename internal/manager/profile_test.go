@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+func TestBaseCfgProfiles(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	base, err := os.ReadFile(testBaseCfgPath)
+	require.NoError(t, err)
+
+	// the external profile differs from the default base config by its maxconn
+	externalCfgPath := filepath.Join(t.TempDir(), "external.cfg")
+	require.NoError(t, os.WriteFile(externalCfgPath, []byte(strings.Replace(string(base), "maxconn 200", "maxconn 4000", 1)), 0o600))
+
+	profiles := map[string]string{
+		"lctnloc-external": externalCfgPath,
+		"tnntten-external": externalCfgPath,
+		"external":         externalCfgPath,
+	}
+
+	tests := []struct {
+		name       string
+		attribute  ProfileAttribute
+		profiles   map[string]string
+		lb         func(lb *lbapi.LoadBalancer)
+		expMaxconn string
+	}{
+		{
+			name:       "no profiles",
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Location.ID = "lctnloc-external" },
+			expMaxconn: "maxconn 200",
+		},
+		{
+			name:       "location profile",
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Location.ID = "lctnloc-external" },
+			expMaxconn: "maxconn 4000",
+		},
+		{
+			name:       "location without a profile falls back to the default",
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Location.ID = "lctnloc-internal" },
+			expMaxconn: "maxconn 200",
+		},
+		{
+			name:       "no location falls back to the default",
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) {},
+			expMaxconn: "maxconn 200",
+		},
+		{
+			name:       "owner profile",
+			attribute:  ProfileAttributeOwner,
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Owner.ID = "tnntten-external" },
+			expMaxconn: "maxconn 4000",
+		},
+		{
+			name:       "owner profile ignores the location",
+			attribute:  ProfileAttributeOwner,
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Location.ID = "lctnloc-external" },
+			expMaxconn: "maxconn 200",
+		},
+		{
+			name:       "name profile",
+			attribute:  ProfileAttributeName,
+			profiles:   profiles,
+			lb:         func(lb *lbapi.LoadBalancer) { lb.Name = "external" },
+			expMaxconn: "maxconn 4000",
+		},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lb := mergeTestData1
+			tt.lb(&lb)
+
+			mgr := Manager{
+				Context: context.Background(),
+				Logger:  logger,
+				LBClient: &mock.LBAPIClient{
+					DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+						return &lb, nil
+					},
+				},
+				BaseCfgPath:             testBaseCfgPath,
+				BaseCfgProfiles:         tt.profiles,
+				BaseCfgProfileAttribute: tt.attribute,
+				ManagedLBID:             gidx.PrefixedID("loadbal-test"),
+			}
+
+			config, err := mgr.RenderConfig()
+			require.NoError(t, err)
+
+			assert.Contains(t, config, "  "+tt.expMaxconn+"\n")
+			assert.Contains(t, config, "frontend loadprt-test\n")
+		})
+	}
+}
+
+func TestProfileAttributeValid(t *testing.T) {
+	for _, attribute := range ProfileAttributes {
+		assert.True(t, attribute.Valid())
+	}
+
+	assert.False(t, ProfileAttribute("").Valid())
+	assert.False(t, ProfileAttribute("label").Valid())
+}
@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPostApplyCheckAttempts      = 5
+	defaultPostApplyCheckRetryInterval = 1 * time.Second
+
+	frontendStatusOpen = "OPEN"
+)
+
+// verifyFrontends polls the runtime status of the frontends in the applied config until they are
+// all open, returning an error naming the frontends which are still down after the last attempt
+func (m *Manager) verifyFrontends(ctx context.Context, cfg parser.Parser) error {
+	frontends, err := cfg.SectionsGet(parser.Frontends)
+	if err != nil {
+		return err
+	}
+
+	attempts := m.PostApplyCheckAttempts
+	if attempts <= 0 {
+		attempts = defaultPostApplyCheckAttempts
+	}
+
+	interval := m.PostApplyCheckRetryInterval
+	if interval <= 0 {
+		interval = defaultPostApplyCheckRetryInterval
+	}
+
+	var down []string
+
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		status, err := m.DataPlaneClient.FrontendStatus(ctx)
+		if err != nil {
+			m.logger(ctx).Warnw("failed to get frontend status", zap.Error(err))
+			continue
+		}
+
+		down = []string{}
+
+		for _, frontend := range frontends {
+			if status[frontend] != frontendStatusOpen {
+				down = append(down, frontend)
+			}
+		}
+
+		if len(down) == 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: frontends not open %v", errPostApplyCheckFailed, down)
+}
+
+// rollback re-applies the last known-good config after a failed post-apply check
+func (m *Manager) rollback(ctx context.Context, checkErr error) error {
+	prev := m.CurrentConfig()
+	if prev == "" {
+		m.logger(ctx).Errorw("post-apply check failed, no previous config to roll back to", zap.Error(checkErr))
+		return checkErr
+	}
+
+	m.logger(ctx).Errorw("post-apply check failed, rolling back to previous config", zap.Error(checkErr))
+
+	if err := m.DataPlaneClient.PostConfig(ctx, prev); err != nil {
+		return errors.Join(checkErr, fmt.Errorf("%w: %w", errRollbackFailure, err))
+	}
+
+	return checkErr
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+)
+
+// applyRuntime applies the desired config without a reload when the runtime apply strategy is used
+// and it only differs from the applied config by its servers, returning false when the config
+// must be posted with a reload instead
+func (m *Manager) applyRuntime(ctx context.Context, desired string) bool {
+	if m.applyStrategy() != ApplyStrategyRuntime {
+		return false
+	}
+
+	current := m.CurrentConfig()
+	if current == "" {
+		return false
+	}
+
+	changes, ok := diffServers(current, desired)
+	if !ok || len(changes) == 0 {
+		return false
+	}
+
+	if err := m.applyServerChanges(ctx, changes); err != nil {
+		m.logger(ctx).Warnw("failed to apply server changes at runtime, reloading", zap.Error(err))
+		return false
+	}
+
+	// keep the config file in sync with the running haproxy
+	if err := m.DataPlaneClient.PostConfigWithoutReload(ctx, desired); err != nil {
+		m.logger(ctx).Warnw("failed to post config without reload, reloading", zap.Error(err))
+		return false
+	}
+
+	m.logger(ctx).Infow("applied server changes at runtime", "changes", len(changes))
+
+	return true
+}
+
+// applyServerChanges applies server changes through the runtime api
+func (m *Manager) applyServerChanges(ctx context.Context, changes []serverChange) error {
+	for _, c := range changes {
+		var err error
+
+		switch c.op {
+		case serverAdd:
+			// diffServers only returns additions which convert
+			rs, _ := runtimeServer(c.server)
+
+			err = m.DataPlaneClient.AddRuntimeServer(ctx, c.backend, rs)
+		case serverRemove:
+			// haproxy only deletes servers in maintenance
+			err = m.DataPlaneClient.SetRuntimeServerState(ctx, c.backend, c.server.Name, dataplaneapi.ServerStateMaint)
+			if err == nil {
+				err = m.DataPlaneClient.DeleteRuntimeServer(ctx, c.backend, c.server.Name)
+			}
+		case serverEnable:
+			err = m.DataPlaneClient.SetRuntimeServerState(ctx, c.backend, c.server.Name, dataplaneapi.ServerStateReady)
+		case serverDisable:
+			err = m.DataPlaneClient.SetRuntimeServerState(ctx, c.backend, c.server.Name, dataplaneapi.ServerStateMaint)
+		}
+
+		if err != nil {
+			return newLabelError(c.server.Name, errRuntimeServerFailure, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AppliedState is the config the manager last successfully applied, for external systems to
+// verify haproxy converged to the desired state
+type AppliedState struct {
+	LoadBalancerID string `json:"loadbalancerID"`
+	Config         string `json:"config"`
+
+	// Hash is the hex encoded sha256 of the config, for comparing it without transferring it
+	Hash string `json:"hash"`
+
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+// AppliedState returns the config last successfully applied, and false when none has been
+func (m *Manager) AppliedState() (AppliedState, bool) {
+	m.configMu.RLock()
+	defer m.configMu.RUnlock()
+
+	if m.currentConfig == "" {
+		return AppliedState{}, false
+	}
+
+	return AppliedState{
+		LoadBalancerID: m.currentLBID,
+		Config:         m.currentConfig,
+		Hash:           configHash(m.currentConfig),
+		AppliedAt:      m.currentAppliedAt,
+	}, true
+}
+
+// ApplyResult describes an update of the haproxy config to the latest desired state, for callers
+// observing updates without re-deriving the applied state
+type ApplyResult struct {
+	// Changed is true when the applied config differs from the config applied before it, other than
+	// by the time in its section annotations. Unchanged configs aren't posted, and updates coalesced
+	// with an earlier one, which already applied the latest state, don't change it.
+	Changed bool
+
+	// Hash is the hex encoded sha256 of the config haproxy runs after the update
+	Hash string
+
+	// Duration is how long fetching, checking and applying the config took, excluding the wait
+	// for the minimum reload interval
+	Duration time.Duration
+}
+
+// configHash returns the hex encoded sha256 of a config
+func configHash(config string) string {
+	hash := sha256.Sum256([]byte(config))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+func TestAppliedState(t *testing.T) {
+	postErr := errors.New("post failed") // nolint:goerr113
+
+	var failPost bool
+
+	lb := &mergeTestData1
+
+	mgr := &Manager{
+		Context: context.Background(),
+		Logger:  zap.NewNop().Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				if failPost {
+					return postErr
+				}
+
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return lb, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+	}
+
+	_, ok := mgr.AppliedState()
+	assert.False(t, ok)
+
+	before := time.Now()
+
+	_, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+
+	state, ok := mgr.AppliedState()
+	require.True(t, ok)
+
+	hash := sha256.Sum256([]byte(mgr.CurrentConfig()))
+
+	assert.Equal(t, "loadbal-test", state.LoadBalancerID)
+	assert.Equal(t, mgr.CurrentConfig(), state.Config)
+	assert.Equal(t, hex.EncodeToString(hash[:]), state.Hash)
+	assert.False(t, state.AppliedAt.Before(before))
+
+	// a failed apply keeps the state of the last successful one
+	failPost = true
+	lb = &mergeTestData2
+
+	require.ErrorIs(t, mgr.Reconcile(context.Background()), postErr)
+
+	failed, ok := mgr.AppliedState()
+	require.True(t, ok)
+	assert.Equal(t, state, failed)
+}
+
+func TestApplyResult(t *testing.T) {
+	lb := &mergeTestData1
+
+	mgr := &Manager{
+		Context: context.Background(),
+		Logger:  zap.NewNop().Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return lb, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+	}
+
+	result, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+
+	state, ok := mgr.AppliedState()
+	require.True(t, ok)
+
+	assert.True(t, result.Changed)
+	assert.Equal(t, state.Hash, result.Hash)
+	assert.Positive(t, result.Duration)
+
+	// applying the same state again doesn't change the config
+	again, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, again.Changed)
+	assert.Equal(t, result.Hash, again.Hash)
+
+	lb = &mergeTestData2
+
+	changed, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, changed.Changed)
+	assert.NotEqual(t, result.Hash, changed.Hash)
+}
+
+func TestApplyResultAnnotated(t *testing.T) {
+	posted := 0
+
+	mgr := &Manager{
+		Context: context.Background(),
+		Logger:  zap.NewNop().Sugar(),
+		DataPlaneClient: &mock.DataplaneAPIClient{
+			DoCheckConfig: func(ctx context.Context, config string) error {
+				return nil
+			},
+			DoPostConfig: func(ctx context.Context, config string) error {
+				posted++
+				return nil
+			},
+		},
+		LBClient: &mock.LBAPIClient{
+			DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+				return &mergeTestData1, nil
+			},
+		},
+		BaseCfgPath: testBaseCfgPath,
+		ManagedLBID: gidx.PrefixedID("loadbal-test"),
+	}
+
+	_, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, posted)
+
+	// the config applied an hour ago only differs by the time in its annotations
+	applied := haproxyconfig.Annotate(mgr.CurrentConfig(), &mergeTestData1, mgr.Settings, time.Now().Add(-time.Hour))
+	mgr.setCurrentConfig("loadbal-test", applied)
+	mgr.AnnotateSections = true
+
+	result, err := mgr.updateConfigToLatest(context.Background())
+	require.NoError(t, err)
+
+	assert.False(t, result.Changed)
+	assert.Equal(t, configHash(applied), result.Hash)
+	assert.Equal(t, 1, posted, "unchanged config posted")
+	assert.Equal(t, applied, mgr.CurrentConfig())
+	assert.Len(t, mgr.History(), 1, "unchanged config recorded")
+}
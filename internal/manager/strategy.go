@@ -0,0 +1,81 @@
+package manager
+
+import (
+	parser "github.com/haproxytech/config-parser/v4"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// MergeStrategy generates the config of a loadbalancer from the base config, e.g. to generate a
+// minimal config, or one for another haproxy version. Config templates and hooks run on its result.
+type MergeStrategy interface {
+	Merge(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error)
+}
+
+// MergeStrategyFunc adapts a function to a MergeStrategy
+type MergeStrategyFunc func(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error)
+
+// Merge calls f(cfg, lb)
+func (f MergeStrategyFunc) Merge(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error) {
+	return f(cfg, lb)
+}
+
+// DefaultMergeStrategy merges with haproxyconfig.Merge and the settings
+type DefaultMergeStrategy struct {
+	Settings haproxyconfig.Settings
+}
+
+// Merge merges the loadbalancer into the config with haproxyconfig.Merge
+func (s DefaultMergeStrategy) Merge(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error) {
+	return haproxyconfig.Merge(cfg, lb, s.Settings)
+}
+
+// mergeStrategy returns the strategy of the manager, the default one with its settings when none is set
+func (m *Manager) mergeStrategy() MergeStrategy {
+	if m.MergeStrategy != nil {
+		return m.MergeStrategy
+	}
+
+	return DefaultMergeStrategy{Settings: m.Settings}
+}
+
+// ApplyStrategy is how the manager applies configs to haproxy, trading simplicity for fewer reloads
+type ApplyStrategy string
+
+const (
+	// ApplyStrategyRaw posts each config in full, reloading haproxy
+	ApplyStrategyRaw ApplyStrategy = "raw"
+
+	// ApplyStrategyRuntime applies changes which only add, remove, enable or disable servers
+	// through the runtime api without a reload, and posts other changes in full
+	ApplyStrategyRuntime ApplyStrategy = "runtime"
+)
+
+// ApplyStrategies are the supported apply strategies
+var ApplyStrategies = []ApplyStrategy{ApplyStrategyRaw, ApplyStrategyRuntime}
+
+// Valid returns true when the strategy is supported
+func (s ApplyStrategy) Valid() bool {
+	for _, strategy := range ApplyStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyStrategy returns the apply strategy of the manager, ApplyStrategyRuntime when none is set
+// and RuntimeServerUpdates is, and ApplyStrategyRaw otherwise
+func (m *Manager) applyStrategy() ApplyStrategy {
+	switch {
+	case m.ApplyStrategy != "":
+		return m.ApplyStrategy
+	case m.RuntimeServerUpdates:
+		return ApplyStrategyRuntime
+	default:
+		return ApplyStrategyRaw
+	}
+}
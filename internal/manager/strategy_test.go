@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/x/gidx"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+)
+
+func TestMergeStrategy(t *testing.T) {
+	l, err := zap.NewDevelopmentConfig().Build()
+	logger := l.Sugar()
+
+	require.Nil(t, err)
+
+	newManager := func(posted *string, strategy MergeStrategy) *Manager {
+		return &Manager{
+			Context: context.Background(),
+			Logger:  logger,
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					*posted = config
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath:   testBaseCfgPath,
+			ManagedLBID:   gidx.PrefixedID("loadbal-test"),
+			MergeStrategy: strategy,
+		}
+	}
+
+	t.Run("default strategy", func(t *testing.T) {
+		posted := ""
+		mgr := newManager(&posted, nil)
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Contains(t, posted, "frontend loadprt-test\n")
+		assert.Contains(t, posted, "backend loadprt-test\n")
+	})
+
+	t.Run("alternate strategy", func(t *testing.T) {
+		posted := ""
+
+		// a minimal strategy generating a single frontend of its own
+		var merged *lbapi.LoadBalancer
+
+		mgr := newManager(&posted, MergeStrategyFunc(func(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error) {
+			merged = lb
+
+			return cfg, cfg.SectionsCreate(parser.Frontends, "minimal")
+		}))
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		assert.Equal(t, &mergeTestData1, merged)
+		assert.Contains(t, posted, "frontend minimal\n")
+		assert.NotContains(t, posted, "loadprt-test")
+	})
+
+	t.Run("strategy errors abort the apply", func(t *testing.T) {
+		posted := ""
+
+		// nolint:goerr113
+		errStrategy := errors.New("unsupported loadbalancer")
+
+		mgr := newManager(&posted, MergeStrategyFunc(func(cfg parser.Parser, lb *lbapi.LoadBalancer) (parser.Parser, error) {
+			return nil, errStrategy
+		}))
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		assert.ErrorIs(t, err, errStrategy)
+		assert.Empty(t, posted, "config posted after a strategy failure")
+	})
+}
+
+func TestApplyStrategy(t *testing.T) {
+	tests := []struct {
+		name              string
+		strategy          ApplyStrategy
+		runtimeUpdates    bool
+		expPosted         int
+		expPostedNoReload int
+	}{
+		{"default posts in full", "", false, 2, 0},
+		{"raw posts in full", ApplyStrategyRaw, false, 2, 0},
+		{"runtime applies server changes without a reload", ApplyStrategyRuntime, false, 1, 1},
+		{"runtime server updates select runtime", "", true, 1, 1},
+		{"raw overrides runtime server updates", ApplyStrategyRaw, true, 2, 0},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			lb := mergeTestData1
+			posted, postedNoReload := 0, 0
+
+			mgr := Manager{
+				Context: context.Background(),
+				Logger:  zap.NewNop().Sugar(),
+				DataPlaneClient: &mock.DataplaneAPIClient{
+					DoCheckConfig: func(ctx context.Context, config string) error {
+						return nil
+					},
+					DoPostConfig: func(ctx context.Context, config string) error {
+						posted++
+						return nil
+					},
+					DoPostConfigNoReload: func(ctx context.Context, config string) error {
+						postedNoReload++
+						return nil
+					},
+					DoSetRuntimeServerState: func(ctx context.Context, backend, name, state string) error {
+						return nil
+					},
+				},
+				LBClient: &mock.LBAPIClient{
+					DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+						return &lb, nil
+					},
+				},
+				BaseCfgPath:          testBaseCfgPath,
+				ManagedLBID:          gidx.PrefixedID("loadbal-test"),
+				ApplyStrategy:        tt.strategy,
+				RuntimeServerUpdates: tt.runtimeUpdates,
+			}
+
+			_, err := mgr.updateConfigToLatest(mgr.Context)
+			require.NoError(t, err)
+
+			// enable the disabled origin, a change which can be applied at runtime
+			lb.Ports.Edges = []lbapi.PortEdges{mergeTestData1.Ports.Edges[0]}
+			lb.Ports.Edges[0].Node.Pools = []lbapi.Pool{mergeTestData1.Ports.Edges[0].Node.Pools[0]}
+			lb.Ports.Edges[0].Node.Pools[0].Origins.Edges = append([]lbapi.OriginEdges{}, mergeTestData1.Ports.Edges[0].Node.Pools[0].Origins.Edges...)
+			lb.Ports.Edges[0].Node.Pools[0].Origins.Edges[2].Node.Active = true
+
+			_, err = mgr.updateConfigToLatest(mgr.Context)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expPosted, posted)
+			assert.Equal(t, tt.expPostedNoReload, postedNoReload)
+		})
+	}
+}
+
+func TestApplyStrategyValid(t *testing.T) {
+	assert.True(t, ApplyStrategyRaw.Valid())
+	assert.True(t, ApplyStrategyRuntime.Valid())
+	assert.False(t, ApplyStrategy("transaction").Valid())
+	assert.False(t, ApplyStrategy("").Valid())
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/params"
+	"github.com/haproxytech/config-parser/v4/types"
+)
+
+// ConfigSummary is a structured summary of the frontends and backends of a config
+type ConfigSummary struct {
+	Frontends []FrontendSummary `json:"frontends"`
+	Backends  []BackendSummary  `json:"backends"`
+}
+
+// FrontendSummary is a frontend section, its binds and the backends it uses
+type FrontendSummary struct {
+	Name     string        `json:"name"`
+	Binds    []BindSummary `json:"binds"`
+	Backends []string      `json:"backends"`
+}
+
+// BindSummary is a bind line of a frontend
+type BindSummary struct {
+	Address string   `json:"address"`
+	Params  []string `json:"params,omitempty"`
+}
+
+// BackendSummary is a backend section and its servers
+type BackendSummary struct {
+	Name    string          `json:"name"`
+	Servers []ServerSummary `json:"servers"`
+}
+
+// ServerSummary is a server line of a backend
+type ServerSummary struct {
+	Name    string   `json:"name"`
+	Address string   `json:"address"`
+	Params  []string `json:"params,omitempty"`
+}
+
+// Disabled returns true when the server is disabled
+func (s ServerSummary) Disabled() bool {
+	for _, p := range s.Params {
+		if p == "disabled" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Summarize returns the frontends and backends of the config, sorted by name. Generated and
+// parsed configs are summarized alike: generated lines hold their params in the address, which
+// are parsed out.
+func Summarize(cfg parser.Parser) ConfigSummary {
+	summary := ConfigSummary{
+		Frontends: []FrontendSummary{},
+		Backends:  []BackendSummary{},
+	}
+
+	frontends, _ := cfg.SectionsGet(parser.Frontends)
+	sort.Strings(frontends)
+
+	for _, name := range frontends {
+		summary.Frontends = append(summary.Frontends, summarizeFrontend(cfg, name))
+	}
+
+	backends, _ := cfg.SectionsGet(parser.Backends)
+	sort.Strings(backends)
+
+	for _, name := range backends {
+		summary.Backends = append(summary.Backends, summarizeBackend(cfg, name))
+	}
+
+	return summary
+}
+
+func summarizeFrontend(cfg parser.Parser, name string) FrontendSummary {
+	fs := FrontendSummary{Name: name, Binds: []BindSummary{}, Backends: []string{}}
+
+	if data, err := cfg.Get(parser.Frontends, name, "bind"); err == nil {
+		if binds, ok := data.([]types.Bind); ok {
+			for _, b := range binds {
+				address, extra := splitAddress(b.Path)
+
+				opts := append(params.ParseBindOptions(extra), b.Params...)
+
+				bind := BindSummary{Address: address, Params: make([]string, 0, len(opts))}
+				for _, o := range opts {
+					bind.Params = append(bind.Params, o.String())
+				}
+
+				fs.Binds = append(fs.Binds, bind)
+			}
+		}
+	}
+
+	if data, err := cfg.Get(parser.Frontends, name, "use_backend"); err == nil {
+		if useBackends, ok := data.([]types.UseBackend); ok {
+			for _, ub := range useBackends {
+				fs.Backends = append(fs.Backends, ub.Name)
+			}
+		}
+	}
+
+	if data, err := cfg.Get(parser.Frontends, name, "default_backend"); err == nil {
+		if defaultBackend, ok := data.(*types.StringC); ok {
+			fs.Backends = append(fs.Backends, defaultBackend.Value)
+		}
+	}
+
+	return fs
+}
+
+func summarizeBackend(cfg parser.Parser, name string) BackendSummary {
+	bs := BackendSummary{Name: name, Servers: []ServerSummary{}}
+
+	data, err := cfg.Get(parser.Backends, name, "server")
+	if err != nil {
+		return bs
+	}
+
+	servers, ok := data.([]types.Server)
+	if !ok {
+		return bs
+	}
+
+	for _, s := range servers {
+		address, extra := splitAddress(s.Address)
+
+		opts := append(params.ParseServerOptions(extra), s.Params...)
+
+		server := ServerSummary{Name: s.Name, Address: address, Params: make([]string, 0, len(opts))}
+		for _, o := range opts {
+			server.Params = append(server.Params, o.String())
+		}
+
+		bs.Servers = append(bs.Servers, server)
+	}
+
+	return bs
+}
+
+// splitAddress splits an address holding params, as generated, into the address and its params
+func splitAddress(address string) (string, []string) {
+	fields := strings.Fields(address)
+	if len(fields) == 0 {
+		return "", nil
+	}
+
+	return fields[0], fields[1:]
+}
@@ -0,0 +1,73 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+func TestSummarize(t *testing.T) {
+	expected := ConfigSummary{
+		Frontends: []FrontendSummary{
+			{
+				Name:     "loadprt-test",
+				Binds:    []BindSummary{{Address: "ipv4@:22", Params: []string{}}},
+				Backends: []string{"loadprt-test"},
+			},
+			{
+				Name:     "stats",
+				Binds:    []BindSummary{{Address: "127.0.0.1:29782", Params: []string{}}},
+				Backends: []string{},
+			},
+		},
+		Backends: []BackendSummary{
+			{
+				Name: "loadprt-test",
+				Servers: []ServerSummary{
+					{Name: "loadogn-test1", Address: "1.2.3.4:2222", Params: []string{"check", "port 2222"}},
+					{Name: "loadogn-test2", Address: "1.2.3.4:222", Params: []string{"check", "port 222"}},
+					{Name: "loadogn-test3", Address: "4.3.2.1:2222", Params: []string{"check", "port 2222", "disabled"}},
+				},
+			},
+		},
+	}
+
+	t.Run("parsed golden config", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-1-exp.cfg")), options.NoNamedDefaultsFrom)
+		require.NoError(t, err)
+
+		summary := Summarize(cfg)
+		assert.Equal(t, expected, summary)
+		assert.True(t, summary.Backends[0].Servers[2].Disabled())
+	})
+
+	t.Run("generated config", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.NoError(t, err)
+
+		merged, err := haproxyconfig.Merge(cfg, &mergeTestData1, haproxyconfig.Settings{})
+		require.NoError(t, err)
+
+		summary := Summarize(merged)
+		require.Len(t, summary.Backends, 1)
+
+		// generated servers hold their params in the address, which are parsed out
+		assert.Equal(t, expected.Backends, summary.Backends)
+	})
+
+	t.Run("routed backends", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-8-exp.cfg")), options.NoNamedDefaultsFrom)
+		require.NoError(t, err)
+
+		summary := Summarize(cfg)
+		require.Len(t, summary.Backends, 2)
+
+		assert.Equal(t, []string{"loadprt-test-loadpol-test2", "loadprt-test"}, summary.Frontends[0].Backends)
+	})
+}
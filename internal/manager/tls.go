@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// crtListUpdate is the managed crt-lists of a config being applied. They are staged in temporary
+// files next to the lists, renamed into place before haproxy checks the config, which loads them,
+// and restored when the config isn't applied, so a rejected list doesn't stay in place for the
+// next reload.
+type crtListUpdate []*stagedCrtList
+
+// stagedCrtList is a crt-list being replaced, and the list it replaces
+type stagedCrtList struct {
+	path string
+
+	// staged is the temporary file of the new list, empty once it is renamed into place
+	staged string
+
+	previous []byte
+	existed  bool
+}
+
+// stageCrtLists writes the crt-lists managed for the loadbalancer's ports to temporary files next
+// to them, skipping the lists which are unchanged
+func (m *Manager) stageCrtLists(lb *lbapi.LoadBalancer) (crtListUpdate, error) {
+	lists := haproxyconfig.CrtLists(lb, m.Settings)
+	update := crtListUpdate{}
+
+	for _, path := range haproxyconfig.CrtListPaths(lists) {
+		list := &stagedCrtList{path: path}
+
+		current, err := os.ReadFile(path)
+
+		switch {
+		case err == nil && string(current) == lists[path]:
+			continue
+		case err == nil:
+			list.previous, list.existed = current, true
+		case !errors.Is(err, fs.ErrNotExist):
+			update.discard()
+
+			return nil, fmt.Errorf("%w %q: %w", errCrtListWrite, path, err)
+		}
+
+		if list.staged, err = writeTemp(path, []byte(lists[path])); err != nil {
+			update.discard()
+
+			return nil, fmt.Errorf("%w %q: %w", errCrtListWrite, path, err)
+		}
+
+		update = append(update, list)
+	}
+
+	return update, nil
+}
+
+// install renames the staged lists into place
+func (u crtListUpdate) install() error {
+	for _, list := range u {
+		if err := os.Rename(list.staged, list.path); err != nil {
+			return fmt.Errorf("%w %q: %w", errCrtListWrite, list.path, err)
+		}
+
+		list.staged = ""
+	}
+
+	return nil
+}
+
+// restore puts back the lists replaced by installed ones, removing those which didn't exist, and
+// discards the lists which weren't installed
+func (u crtListUpdate) restore() error {
+	u.discard()
+
+	errs := []error{}
+
+	for _, list := range u {
+		if list.staged != "" {
+			continue
+		}
+
+		var err error
+
+		if list.existed {
+			err = writeFileAtomic(list.path, list.previous)
+		} else {
+			err = os.Remove(list.path)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w %q: %w", errCrtListRestore, list.path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// discard removes the temporary files of the lists which weren't installed
+func (u crtListUpdate) discard() {
+	for _, list := range u {
+		if list.staged != "" {
+			_ = os.Remove(list.staged)
+		}
+	}
+}
+
+// restoreCrtLists restores the crt-lists replaced for a config which wasn't applied, logging the
+// lists which couldn't be, as the config failing is the error returned
+func (m *Manager) restoreCrtLists(ctx context.Context, update crtListUpdate) {
+	if err := update.restore(); err != nil {
+		m.logger(ctx).Errorw("failed to restore crt-lists", zap.Error(err))
+	}
+}
+
+// writeTemp writes data to a temporary file in the directory of path, returning its name
+func writeTemp(path string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path and renames it to path
+func writeFileAtomic(path string, data []byte) error {
+	name, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(name, path); err != nil {
+		os.Remove(name)
+
+		return err
+	}
+
+	return nil
+}
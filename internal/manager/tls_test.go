@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+var errTestConfigRejected = errors.New("config rejected")
+
+func TestCrtLists(t *testing.T) {
+	const newList = "/srv/certs/www.pem www.example.com\n"
+
+	newManager := func(t *testing.T, checkErr error) (*Manager, string) {
+		t.Helper()
+
+		crtList := filepath.Join(t.TempDir(), "crt-list.txt")
+
+		mgr := &Manager{
+			Context: context.Background(),
+			Logger:  zap.NewNop().Sugar(),
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoCheckConfig: func(ctx context.Context, config string) error {
+					// haproxy loads the crt-lists of the config it checks
+					list, err := os.ReadFile(crtList)
+					require.NoError(t, err)
+					assert.Equal(t, newList, string(list))
+
+					return checkErr
+				},
+				DoPostConfig: func(ctx context.Context, config string) error {
+					return nil
+				},
+			},
+			LBClient: &mock.LBAPIClient{
+				DoGetLoadBalancer: func(ctx context.Context, id string) (*lbapi.LoadBalancer, error) {
+					return &mergeTestData1, nil
+				},
+			},
+			BaseCfgPath: testBaseCfgPath,
+			ManagedLBID: gidx.PrefixedID("loadbal-test"),
+			Settings: haproxyconfig.Settings{
+				Ports: []haproxyconfig.PortSettings{{
+					ID:      "loadprt-test",
+					CrtList: crtList,
+					Certs:   []haproxyconfig.CertRef{{Cert: "/srv/certs/www.pem", SNIFilters: []string{"www.example.com"}}},
+				}},
+			},
+		}
+
+		return mgr, crtList
+	}
+
+	// staged lists are renamed into place, so no temporary files are left next to them
+	assertNoStagedLists := func(t *testing.T, crtList string) {
+		t.Helper()
+
+		entries, err := os.ReadDir(filepath.Dir(crtList))
+		require.NoError(t, err)
+
+		for _, e := range entries {
+			assert.Equal(t, filepath.Base(crtList), e.Name())
+		}
+	}
+
+	t.Run("applied config installs the lists", func(t *testing.T) {
+		mgr, crtList := newManager(t, nil)
+
+		require.NoError(t, os.WriteFile(crtList, []byte("/srv/certs/old.pem\n"), 0o600))
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.NoError(t, err)
+
+		list, err := os.ReadFile(crtList)
+		require.NoError(t, err)
+		assert.Equal(t, newList, string(list))
+		assertNoStagedLists(t, crtList)
+	})
+
+	t.Run("rejected config restores the lists", func(t *testing.T) {
+		mgr, crtList := newManager(t, errTestConfigRejected)
+
+		require.NoError(t, os.WriteFile(crtList, []byte("/srv/certs/old.pem\n"), 0o600))
+
+		_, err := mgr.updateConfigToLatest(mgr.Context)
+		require.ErrorIs(t, err, errTestConfigRejected)
+
+		list, err := os.ReadFile(crtList)
+		require.NoError(t, err)
+		assert.Equal(t, "/srv/certs/old.pem\n", string(list))
+		assertNoStagedLists(t, crtList)
+	})
+
+	t.Run("rejected config removes new lists", func(t *testing.T) {
+		mgr, crtList := newManager(t, errTestConfigRejected)
+
+		err := mgr.ApplyForLB(mgr.Context, gidx.PrefixedID("loadbal-other"))
+		require.ErrorIs(t, err, errTestConfigRejected)
+
+		assert.NoFileExists(t, crtList)
+		assertNoStagedLists(t, crtList)
+	})
+}
@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+// checkHAProxyVersion warns when the haproxy version doesn't support directives generated for the
+// settings, so configs haproxy would reject are flagged before the first CheckConfig. With
+// StrictVersionCheck an unsupported version is returned as an error instead, as it always is for
+// directives the version removed, such as nbproc. Versions which can't be determined are logged
+// and skipped.
+func (m *Manager) checkHAProxyVersion(ctx context.Context) error {
+	version, err := m.DataPlaneClient.HAProxyVersion(ctx)
+	if err != nil {
+		m.Logger.Warnw("unable to determine haproxy version, skipping version check", zap.Error(err))
+		return nil
+	}
+
+	if version == "" {
+		return nil
+	}
+
+	unsupported, err := haproxyconfig.UnsupportedFeatures(m.Settings, version)
+	if err != nil {
+		m.Logger.Warnw("unable to parse haproxy version, skipping version check", "version", version, zap.Error(err))
+		return nil
+	}
+
+	if len(unsupported) == 0 {
+		m.Logger.Debugw("haproxy version supports the configured features", "version", version)
+		return nil
+	}
+
+	features := make([]string, 0, len(unsupported))
+	removed := false
+
+	for _, req := range unsupported {
+		if req.RemovedIn != "" {
+			m.Logger.Errorw("haproxy version removed configured feature",
+				"version", version,
+				"feature", req.Feature,
+				"removedIn", req.RemovedIn)
+
+			features = append(features, fmt.Sprintf("%s was removed in %s", req.Feature, req.RemovedIn))
+			removed = true
+
+			continue
+		}
+
+		m.Logger.Warnw("haproxy version doesn't support configured feature",
+			"version", version,
+			"feature", req.Feature,
+			"minVersion", req.MinVersion)
+
+		features = append(features, fmt.Sprintf("%s requires %s", req.Feature, req.MinVersion))
+	}
+
+	if m.StrictVersionCheck || removed {
+		return fmt.Errorf("%w: haproxy %s: %s", errHAProxyVersionUnsupported, version, strings.Join(features, ", "))
+	}
+
+	return nil
+}
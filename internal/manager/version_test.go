@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/dataplaneapi"
+	"go.infratographer.com/loadbalancer-manager-haproxy/internal/manager/mock"
+	"go.infratographer.com/loadbalancer-manager-haproxy/pkg/haproxyconfig"
+)
+
+func TestCheckHAProxyVersion(t *testing.T) {
+	settings := haproxyconfig.Settings{
+		Pools: []haproxyconfig.PoolSettings{
+			{RetryOn: []string{"conn-failure"}},
+			{HTTPCheck: haproxyconfig.HTTPCheckSettings{Path: "/healthz"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		err     error
+		strict  bool
+		wantErr bool
+	}{
+		{name: "supported", version: "2.6.6-274d1a4", strict: true},
+		{name: "unsupported", version: "2.1.12"},
+		{name: "unsupported strict", version: "2.1.12", strict: true, wantErr: true},
+		{name: "unknown version", strict: true},
+		{name: "invalid version", version: "dev", strict: true},
+		{name: "query failure", err: dataplaneapi.ErrDataPlaneHTTPError, strict: true},
+	}
+
+	t.Run("removed features are rejected without strict", func(t *testing.T) {
+		m := Manager{
+			Logger: zap.NewNop().Sugar(),
+			Settings: haproxyconfig.Settings{
+				Global: haproxyconfig.GlobalSettings{NbProc: 2},
+			},
+			DataPlaneClient: &mock.DataplaneAPIClient{
+				DoHAProxyVersion: func(ctx context.Context) (string, error) {
+					return "2.6.6", nil
+				},
+			},
+		}
+
+		err := m.checkHAProxyVersion(context.TODO())
+
+		assert.ErrorIs(t, err, errHAProxyVersionUnsupported)
+		assert.ErrorContains(t, err, "nbproc was removed in 2.5")
+	})
+
+	for _, tt := range tests {
+		tt := tt // linter
+
+		t.Run(tt.name, func(t *testing.T) {
+			m := Manager{
+				Logger:   zap.NewNop().Sugar(),
+				Settings: settings,
+				DataPlaneClient: &mock.DataplaneAPIClient{
+					DoHAProxyVersion: func(ctx context.Context) (string, error) {
+						return tt.version, tt.err
+					},
+				},
+				StrictVersionCheck: tt.strict,
+			}
+
+			err := m.checkHAProxyVersion(context.TODO())
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errHAProxyVersionUnsupported)
+				assert.ErrorContains(t, err, "http-check send requires 2.2")
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.infratographer.com/x/events"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultConnectBackoff    = time.Second
+	defaultMaxConnectBackoff = 30 * time.Second
+)
+
+// connector connects to the events broker, retrying with backoff while it is unavailable
+type connector struct {
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	logger     *zap.SugaredLogger
+
+	// connect is events.NewConnection, replaced in tests
+	connect func(config events.Config, options ...events.Option) (events.Connection, error)
+}
+
+// ConnectOption is a functional option for Connect
+type ConnectOption func(c *connector)
+
+// WithConnectRetries sets the number of times connecting is retried after the first attempt fails
+func WithConnectRetries(retries int) ConnectOption {
+	return func(c *connector) {
+		c.retries = retries
+	}
+}
+
+// WithConnectBackoff sets the wait before the first retry, which doubles with each retry up to max
+func WithConnectBackoff(backoff, max time.Duration) ConnectOption {
+	return func(c *connector) {
+		c.backoff = backoff
+		c.maxBackoff = max
+	}
+}
+
+// WithConnectLogger sets the logger for connection attempts
+func WithConnectLogger(l *zap.SugaredLogger) ConnectOption {
+	return func(c *connector) {
+		c.logger = l
+	}
+}
+
+// Connect creates the events connection, retrying with backoff so a broker which is briefly
+// unavailable at startup doesn't fail it. The error is returned once the retries are exhausted.
+func Connect(ctx context.Context, config events.Config, opts ...ConnectOption) (events.Connection, error) {
+	c := &connector{
+		backoff:    defaultConnectBackoff,
+		maxBackoff: defaultMaxConnectBackoff,
+		logger:     zap.NewNop().Sugar(),
+		connect:    events.NewConnection,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c.run(ctx, config)
+}
+
+func (c *connector) run(ctx context.Context, config events.Config) (events.Connection, error) {
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		conn, err := c.connect(config, events.WithLogger(c.logger))
+		if err == nil {
+			return conn, nil
+		}
+
+		if attempt >= c.retries {
+			return nil, fmt.Errorf("%w after %d attempts: %w", ErrConnectFailed, attempt+1, err)
+		}
+
+		c.logger.Warnw("failed to connect to events broker, retrying", zap.Error(err), zap.Int("attempt", attempt+1), zap.Duration("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(fmt.Errorf("%w: %w", ErrConnectFailed, err), ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if c.maxBackoff > 0 && backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/testing/eventtools"
+	"go.uber.org/zap"
+)
+
+var errBrokerUnavailable = errors.New("nats: no servers available for connection")
+
+func TestConnect(t *testing.T) {
+	natsSrv, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	t.Cleanup(natsSrv.Close)
+
+	conn, err := Connect(context.Background(), natsSrv.Config, WithConnectRetries(3))
+	require.NoError(t, err)
+
+	_ = conn.Shutdown(context.Background())
+}
+
+func TestConnectRetries(t *testing.T) {
+	natsSrv, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	t.Cleanup(natsSrv.Close)
+
+	// newConnector returns a connector to a broker which becomes available after a delay
+	newConnector := func(available time.Time, retries int) *connector {
+		return &connector{
+			retries:    retries,
+			backoff:    10 * time.Millisecond,
+			maxBackoff: 40 * time.Millisecond,
+			logger:     zap.NewNop().Sugar(),
+			connect: func(config events.Config, options ...events.Option) (events.Connection, error) {
+				if time.Now().Before(available) {
+					return nil, errBrokerUnavailable
+				}
+
+				return events.NewConnection(config, options...)
+			},
+		}
+	}
+
+	t.Run("broker available after a delay", func(t *testing.T) {
+		conn, err := newConnector(time.Now().Add(100*time.Millisecond), 10).run(context.Background(), natsSrv.Config)
+		require.NoError(t, err)
+
+		_ = conn.Shutdown(context.Background())
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		attempts := 0
+
+		c := newConnector(time.Now().Add(time.Hour), 2)
+		connect := c.connect
+		c.connect = func(config events.Config, options ...events.Option) (events.Connection, error) {
+			attempts++
+			return connect(config, options...)
+		}
+
+		_, err := c.run(context.Background(), natsSrv.Config)
+		require.ErrorIs(t, err, ErrConnectFailed)
+		assert.ErrorIs(t, err, errBrokerUnavailable)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := newConnector(time.Now().Add(time.Hour), 100).run(ctx, natsSrv.Config)
+		require.ErrorIs(t, err, ErrConnectFailed)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -5,4 +5,10 @@ import "errors"
 var (
 	// ErrMsgHandlerNotRegistered is returned when the message handler callback is not registered
 	ErrMsgHandlerNotRegistered = errors.New("nats message handler callback is not registered")
+
+	// ErrMsgHandlerPanic is returned when the message handler callback panics
+	ErrMsgHandlerPanic = errors.New("nats message handler callback panicked")
+
+	// ErrConnectFailed is returned when the events connection cannot be created within the retries
+	ErrConnectFailed = errors.New("failed to connect to events broker")
 )
@@ -0,0 +1,26 @@
+package pubsub
+
+// PermanentError wraps a message handler error which retrying the message won't resolve. The
+// subscriber terminates the message instead of naking it for redelivery.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err as a PermanentError, returning nil when err is nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &PermanentError{Err: err}
+}
+
+// Error returns the wrapped error message
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
@@ -2,6 +2,8 @@ package pubsub
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -11,17 +13,20 @@ import (
 
 const defaultNakDelay = 10 * time.Second
 
-// MsgHandler is a callback function that processes messages delivered to subscribers
-type MsgHandler func(msg events.Message[events.ChangeMessage]) error
+// MsgHandler is a callback function that processes messages delivered to subscribers. The context
+// is done when the subscriber's context is, or when the handler timeout is reached.
+type MsgHandler func(ctx context.Context, msg events.Message[events.ChangeMessage]) error
 
 // Subscriber is the subscriber client
 type Subscriber struct {
 	ctx                   context.Context
 	changeChannels        []<-chan events.Message[events.ChangeMessage]
+	topics                []string
 	msgHandler            MsgHandler
 	logger                *zap.SugaredLogger
 	connection            events.Connection
 	maxProcessMsgAttempts uint64
+	handlerTimeout        time.Duration
 }
 
 // SubscriberOption is a functional option for the Subscriber
@@ -48,6 +53,14 @@ func WithMaxMsgProcessAttempts(max uint64) SubscriberOption {
 	}
 }
 
+// WithHandlerTimeout sets the time a message handler has to process a message before its context
+// is cancelled and the message is naked for retry. Zero means no timeout.
+func WithHandlerTimeout(timeout time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.handlerTimeout = timeout
+	}
+}
+
 // NewSubscriber creates a new Subscriber
 func NewSubscriber(ctx context.Context, connection events.Connection, opts ...SubscriberOption) *Subscriber {
 	s := &Subscriber{
@@ -73,10 +86,16 @@ func (s *Subscriber) Subscribe(topic string) error {
 	}
 
 	s.changeChannels = append(s.changeChannels, msgChan)
+	s.topics = append(s.topics, topic)
 
 	return nil
 }
 
+// Topics returns the topics the subscriber is subscribed to
+func (s *Subscriber) Topics() []string {
+	return append([]string{}, s.topics...)
+}
+
 // Listen start listening for messages on registered subjects and calls the registered message handler
 func (s Subscriber) Listen() error {
 	wg := &sync.WaitGroup{}
@@ -110,9 +129,9 @@ func (s Subscriber) listen(messages <-chan events.Message[events.ChangeMessage],
 			"event.message.deliveries", msg.Deliveries(),
 		)
 
-		if err := s.msgHandler(msg); err != nil {
-			if s.maxProcessMsgAttempts != 0 && msg.Deliveries()+1 > s.maxProcessMsgAttempts {
-				slogger.Warnw("terminating event, too many attempts")
+		if err := s.handleMsg(msg); err != nil {
+			if s.terminate(err, msg.Deliveries()) {
+				slogger.Warnw("terminating event, permanent error or too many attempts", "error", err)
 
 				if termErr := msg.Term(); termErr != nil {
 					slogger.Warnw("error occurred while terminating event")
@@ -125,3 +144,38 @@ func (s Subscriber) listen(messages <-chan events.Message[events.ChangeMessage],
 		}
 	}
 }
+
+// terminate returns true when a failed message should be terminated rather than naked for
+// redelivery, because the error is permanent or the message has run out of attempts
+func (s Subscriber) terminate(err error, deliveries uint64) bool {
+	var permErr *PermanentError
+
+	if errors.As(err, &permErr) {
+		return true
+	}
+
+	return s.maxProcessMsgAttempts != 0 && deliveries+1 > s.maxProcessMsgAttempts
+}
+
+// handleMsg calls the registered message handler, recovering from a panic in the handler so the
+// message is returned as failed instead of stopping the listener for its channel
+func (s Subscriber) handleMsg(msg events.Message[events.ChangeMessage]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Errorw("recovered from panic in message handler", "panic", r)
+
+			err = fmt.Errorf("%w: %v", ErrMsgHandlerPanic, r)
+		}
+	}()
+
+	ctx := s.ctx
+
+	if s.handlerTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, s.handlerTimeout)
+		defer cancel()
+	}
+
+	return s.msgHandler(ctx, msg)
+}
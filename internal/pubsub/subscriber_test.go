@@ -0,0 +1,170 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.infratographer.com/x/events"
+	"go.infratographer.com/x/gidx"
+	"go.infratographer.com/x/testing/eventtools"
+)
+
+func newTestConnection(t *testing.T) events.Connection {
+	t.Helper()
+
+	natsSrv, err := eventtools.NewNatsServer()
+	require.NoError(t, err)
+
+	eventsConn, err := events.NewNATSConnection(natsSrv.Config.NATS)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		natsSrv.Close()
+
+		_ = eventsConn.Shutdown(context.Background())
+	})
+
+	return eventsConn
+}
+
+func TestTopics(t *testing.T) {
+	s := NewSubscriber(context.Background(), newTestConnection(t))
+
+	assert.Empty(t, s.Topics())
+
+	topics := []string{"load-balancer", "load-balancer-port", "load-balancer-pool"}
+
+	for _, topic := range topics {
+		require.NoError(t, s.Subscribe(topic))
+	}
+
+	assert.Equal(t, topics, s.Topics())
+
+	// the returned slice is a copy
+	s.Topics()[0] = "modified"
+	assert.Equal(t, topics, s.Topics())
+}
+
+func TestListenRecoversFromPanic(t *testing.T) {
+	ctx := context.Background()
+	conn := newTestConnection(t)
+
+	handled := make(chan gidx.PrefixedID, 1)
+
+	handler := func(ctx context.Context, msg events.Message[events.ChangeMessage]) error {
+		if msg.Message().SubjectID == "loadbal-poison" {
+			panic("poison message")
+		}
+
+		handled <- msg.Message().SubjectID
+
+		return nil
+	}
+
+	s := NewSubscriber(ctx, conn, WithMsgHandler(handler))
+	require.NoError(t, s.Subscribe(">"))
+
+	go func() {
+		_ = s.Listen()
+	}()
+
+	for _, id := range []gidx.PrefixedID{"loadbal-poison", "loadbal-test"} {
+		_, err := conn.PublishChange(ctx, "create.loadbalancer", events.ChangeMessage{
+			SubjectID: id,
+			EventType: string(events.CreateChangeType),
+		})
+		require.NoError(t, err)
+	}
+
+	select {
+	case id := <-handled:
+		assert.Equal(t, gidx.PrefixedID("loadbal-test"), id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener stopped handling messages after a panic")
+	}
+}
+
+func TestHandleMsgPanic(t *testing.T) {
+	s := NewSubscriber(context.Background(), nil, WithMsgHandler(func(ctx context.Context, msg events.Message[events.ChangeMessage]) error {
+		panic("poison message")
+	}))
+
+	err := s.handleMsg(nil)
+	assert.ErrorIs(t, err, ErrMsgHandlerPanic)
+}
+
+func TestTerminate(t *testing.T) {
+	errTransient := errors.New("lbapi unavailable")      // nolint:goerr113
+	errInvalid := errors.New("loadbalancer ID is empty") // nolint:goerr113
+
+	tests := []struct {
+		name        string
+		maxAttempts uint64
+		err         error
+		deliveries  uint64
+		terminate   bool
+	}{
+		{"transient error is retried", 0, errTransient, 10, false},
+		{"transient error within max attempts is retried", 3, errTransient, 1, false},
+		{"transient error past max attempts is terminated", 3, errTransient, 3, true},
+		{"permanent error is terminated", 0, Permanent(errInvalid), 1, true},
+		{"wrapped permanent error is terminated", 3, fmt.Errorf("update failed: %w", Permanent(errInvalid)), 1, true},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSubscriber(context.Background(), nil, WithMaxMsgProcessAttempts(tt.maxAttempts))
+
+			assert.Equal(t, tt.terminate, s.terminate(tt.err, tt.deliveries))
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	errInvalid := errors.New("loadbalancer ID is empty") // nolint:goerr113
+
+	assert.Nil(t, Permanent(nil))
+
+	err := Permanent(errInvalid)
+	assert.ErrorIs(t, err, errInvalid)
+	assert.Equal(t, errInvalid.Error(), err.Error())
+}
+
+func TestHandleMsgTimeout(t *testing.T) {
+	handler := func(ctx context.Context, msg events.Message[events.ChangeMessage]) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+
+	t.Run("handler exceeds the timeout", func(t *testing.T) {
+		s := NewSubscriber(context.Background(), nil, WithMsgHandler(handler), WithHandlerTimeout(10*time.Millisecond))
+
+		err := s.handleMsg(nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// a timed out message is retried
+		assert.False(t, s.terminate(err, 1))
+	})
+
+	t.Run("no timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := NewSubscriber(ctx, nil, WithMsgHandler(handler))
+
+		err := s.handleMsg(nil)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
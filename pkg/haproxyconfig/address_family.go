@@ -0,0 +1,45 @@
+package haproxyconfig
+
+import "fmt"
+
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+	familyDual = "dual"
+)
+
+// validateAddressFamily checks the address family is ipv4, ipv6 or dual, and is only set for tcp binds
+func (p PortSettings) validateAddressFamily() error {
+	switch p.AddressFamily {
+	case "":
+		return nil
+	case familyIPv4, familyIPv6, familyDual:
+	default:
+		return fmt.Errorf("%w: %q", ErrAddressFamilyInvalid, p.AddressFamily)
+	}
+
+	if p.SocketPath != "" {
+		return fmt.Errorf("%w: %q with socket path", ErrAddressFamilyInvalid, p.AddressFamily)
+	}
+
+	return nil
+}
+
+// addressFamilies returns the families the port's tcp bind is rendered for, both for a dual-stack
+// port, and the port's family, or ipv4 when it has none and dualStack is false
+func (p PortSettings) addressFamilies(dualStack bool) []string {
+	family := p.AddressFamily
+
+	if family == "" && dualStack {
+		family = familyDual
+	}
+
+	switch family {
+	case familyDual:
+		return []string{familyIPv4, familyIPv6}
+	case familyIPv6:
+		return []string{familyIPv6}
+	}
+
+	return []string{familyIPv4}
+}
@@ -0,0 +1,81 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// annotationPrefix starts the comments annotating generated sections
+const annotationPrefix = "# managed:"
+
+// Annotate prefixes the sections generated for the loadbalancer's ports and pools with a comment
+// naming what they were generated for, e.g. `# managed: port=https id=loadprt-... generated=...`,
+// so reviewers of rendered configs committed to git can tell the sections apart. Default backends
+// are annotated with their port, and backends of a single pool, routed or split, with their pool.
+func Annotate(config string, lb *lbapi.LoadBalancer, settings Settings, generated time.Time) string {
+	ts := generated.UTC().Format(time.RFC3339)
+	annotations := map[string]string{}
+
+	for _, p := range lb.Ports.Edges {
+		portAnnotation := annotation("port", p.Node.Name, p.Node.ID, ts)
+
+		annotations["frontend "+p.Node.ID] = portAnnotation
+
+		defaultBackend, routed := portBackends(p.Node, settings)
+
+		for _, b := range append([]backend{defaultBackend}, routed...) {
+			if b.label == p.Node.ID {
+				annotations["backend "+b.label] = portAnnotation
+				continue
+			}
+
+			annotations["backend "+b.label] = annotation("pool", b.pools[0].Name, b.pools[0].ID, ts)
+		}
+	}
+
+	lines := strings.Split(config, "\n")
+	out := make([]string, 0, len(lines)+len(annotations))
+
+	for _, line := range lines {
+		if a, ok := annotations[strings.TrimSpace(line)]; ok && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			out = append(out, a)
+		}
+
+		out = append(out, line)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// annotation returns the comment annotating a section generated for a port or pool, with the
+// whitespace of its name collapsed so it stays a single field
+func annotation(kind, name, id, generated string) string {
+	name = strings.Join(strings.Fields(name), "_")
+	if name == "" {
+		name = "-"
+	}
+
+	return fmt.Sprintf("%s %s=%s id=%s generated=%s", annotationPrefix, kind, name, id, generated)
+}
+
+// StripAnnotations removes the comments Annotate adds, e.g. to compare configs rendered at
+// different times
+func StripAnnotations(config string) string {
+	if !strings.Contains(config, annotationPrefix) {
+		return config
+	}
+
+	lines := strings.Split(config, "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, annotationPrefix) {
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,65 @@
+package haproxyconfig
+
+import (
+	"testing"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotate(t *testing.T) {
+	generated := time.Date(2023, 7, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	render := func(t *testing.T, settings Settings) string {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		lb := mergeTestData2
+
+		newCfg, err := Merge(cfg, &lb, settings)
+		require.Nil(t, err)
+
+		return Render(newCfg)
+	}
+
+	t.Run("ports", func(t *testing.T) {
+		config := render(t, Settings{})
+		annotated := Annotate(config, &mergeTestData2, Settings{}, generated)
+
+		assert.Contains(t, annotated, "# managed: port=ssh-service-a id=loadprt-test generated=2023-07-01T10:00:00Z\nfrontend loadprt-test\n")
+		assert.Contains(t, annotated, "# managed: port=ssh-service-a id=loadprt-test generated=2023-07-01T10:00:00Z\nbackend loadprt-test\n")
+		assert.NotContains(t, annotated, "# managed: port=ssh-service-a id=loadprt-test generated=2023-07-01T10:00:00Z\nglobal")
+	})
+
+	t.Run("routed pools", func(t *testing.T) {
+		settings := Settings{Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}}}
+
+		config := render(t, settings)
+		annotated := Annotate(config, &mergeTestData2, settings, generated)
+
+		assert.Contains(t, annotated, "# managed: port=ssh-service-a id=loadprt-test generated=2023-07-01T10:00:00Z\nbackend loadprt-test\n")
+		assert.Contains(t, annotated, "# managed: pool=ssh-service-b id=loadpol-test2 generated=2023-07-01T10:00:00Z\nbackend loadprt-test-loadpol-test2\n")
+	})
+
+	t.Run("canonical configs", func(t *testing.T) {
+		config := Canonicalize(render(t, Settings{}))
+		annotated := Annotate(config, &mergeTestData2, Settings{}, generated)
+
+		assert.Contains(t, annotated, "# managed: port=ssh-service-a id=loadprt-test generated=2023-07-01T10:00:00Z\nfrontend loadprt-test\n")
+	})
+
+	t.Run("stripped", func(t *testing.T) {
+		config := render(t, Settings{})
+
+		assert.Equal(t, config, StripAnnotations(Annotate(config, &mergeTestData2, Settings{}, generated)))
+		assert.Equal(t, config, StripAnnotations(config))
+	})
+}
+
+func TestAnnotation(t *testing.T) {
+	assert.Equal(t, "# managed: port=ssh_service id=loadprt-test generated=ts", annotation("port", " ssh  service ", "loadprt-test", "ts"))
+	assert.Equal(t, "# managed: pool=- id=loadpol-test generated=ts", annotation("pool", "", "loadpol-test", "ts"))
+}
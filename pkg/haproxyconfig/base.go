@@ -0,0 +1,76 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+)
+
+// baseFragmentExt is the extension of the base config fragments read from a directory
+const baseFragmentExt = ".cfg"
+
+// ParseBase parses the base config from a single file
+func ParseBase(path string) (parser.Parser, error) {
+	cfg, err := parser.New(options.Path(path), options.NoNamedDefaultsFrom)
+	if err != nil {
+		return nil, newAttrError(ErrBaseConfigInvalid, err)
+	}
+
+	return cfg, nil
+}
+
+// ParseBaseDir parses the base config from the .cfg fragments of a directory, e.g. global.cfg and
+// defaults.cfg, which are concatenated in sorted order
+func ParseBaseDir(dir string) (parser.Parser, error) {
+	base, err := readBaseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := parser.New(options.Reader(strings.NewReader(base)), options.NoNamedDefaultsFrom)
+	if err != nil {
+		return nil, newAttrError(ErrBaseConfigInvalid, err)
+	}
+
+	return cfg, nil
+}
+
+// readBaseDir concatenates the .cfg fragments of a directory in sorted order
+func readBaseDir(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", newAttrError(ErrBaseConfigInvalid, err)
+	}
+
+	names := []string{}
+
+	for _, e := range entries {
+		if !e.IsDir() && filepath.Ext(e.Name()) == baseFragmentExt {
+			names = append(names, e.Name())
+		}
+	}
+
+	if len(names) == 0 {
+		return "", fmt.Errorf("%w: no %s files in %q", ErrBaseConfigInvalid, baseFragmentExt, dir)
+	}
+
+	sort.Strings(names)
+
+	fragments := make([]string, 0, len(names))
+
+	for _, name := range names {
+		fragment, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", newAttrError(ErrBaseConfigInvalid, err)
+		}
+
+		fragments = append(fragments, strings.TrimSpace(string(fragment)))
+	}
+
+	return strings.Join(fragments, "\n\n") + "\n", nil
+}
@@ -0,0 +1,71 @@
+package haproxyconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBaseDir(t *testing.T) {
+	writeFragments := func(t *testing.T, fragments map[string]string) string {
+		dir := t.TempDir()
+
+		for name, contents := range fragments {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600))
+		}
+
+		return dir
+	}
+
+	t.Run("fragments assembled in sorted order", func(t *testing.T) {
+		dir := writeFragments(t, map[string]string{
+			"20-defaults.cfg":  "defaults\n  mode tcp\n  timeout connect 5s\n",
+			"10-global.cfg":    "global\n  maxconn 200\n",
+			"30-resolvers.cfg": "resolvers dns\n  nameserver dns1 1.1.1.1:53\n",
+			"README.md":        "not a fragment",
+		})
+
+		base, err := readBaseDir(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "global\n  maxconn 200\n\ndefaults\n  mode tcp\n  timeout connect 5s\n\nresolvers dns\n  nameserver dns1 1.1.1.1:53\n", base)
+
+		cfg, err := ParseBaseDir(dir)
+		require.NoError(t, err)
+
+		resolvers, err := cfg.SectionsGet(parser.Resolvers)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"dns"}, resolvers)
+
+		merged, err := Merge(cfg, &mergeTestData1, Settings{})
+		require.NoError(t, err)
+		assert.Contains(t, Render(merged), "  maxconn 200\n")
+		assert.Contains(t, Render(merged), "backend loadprt-test\n")
+	})
+
+	t.Run("same as a single file", func(t *testing.T) {
+		contents, err := os.ReadFile(testBaseCfgPath)
+		require.NoError(t, err)
+
+		fromDir, err := ParseBaseDir(writeFragments(t, map[string]string{"haproxy.cfg": string(contents)}))
+		require.NoError(t, err)
+
+		fromFile, err := ParseBase(testBaseCfgPath)
+		require.NoError(t, err)
+
+		assert.Equal(t, Render(fromFile), Render(fromDir))
+	})
+
+	t.Run("no fragments", func(t *testing.T) {
+		_, err := ParseBaseDir(writeFragments(t, map[string]string{"haproxy.conf": "global\n"}))
+		assert.ErrorIs(t, err, ErrBaseConfigInvalid)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := ParseBaseDir(filepath.Join(t.TempDir(), "missing"))
+		assert.ErrorIs(t, err, ErrBaseConfigInvalid)
+	})
+}
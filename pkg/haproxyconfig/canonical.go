@@ -0,0 +1,242 @@
+package haproxyconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// canonicalSectionOrder is the order of section types between defaults sections in a canonical
+// config. Types which aren't listed follow them, in the order they appear.
+var canonicalSectionOrder = []string{
+	"global",
+	"userlist",
+	"peers",
+	"mailers",
+	"resolvers",
+	"cache",
+	"ring",
+	"log-forward",
+	"http-errors",
+	"fcgi-app",
+	"program",
+	"frontend",
+	"listen",
+	"backend",
+}
+
+// canonicalSection is a section of a config, its header line and directives
+type canonicalSection struct {
+	header     string
+	directives []string
+}
+
+// Canonicalize normalizes a rendered config so configs with the same meaning have the same text,
+// and diffs between configs are minimal. Sections are ordered by type then name, directives are
+// one per line with their whitespace collapsed, and runs of consecutive servers are sorted by name
+// when their order doesn't matter. Sections aren't moved across defaults sections, as sections
+// use the defaults preceding them, and the order of other directives is kept as it can be
+// significant, e.g. for rules.
+func Canonicalize(config string) string {
+	preamble, sections := splitSections(config)
+
+	blocks := []string{}
+
+	if len(preamble) > 0 {
+		blocks = append(blocks, strings.Join(preamble, "\n"))
+	}
+
+	var defaults *canonicalSection
+
+	for start := 0; start < len(sections); {
+		// a defaults section leads the sections using it
+		end := start
+		if sectionKind(sections[start]) == "defaults" {
+			defaults = &sections[start]
+			end++
+		}
+
+		first := end
+
+		for end < len(sections) && sectionKind(sections[end]) != "defaults" {
+			end++
+		}
+
+		sortSections(sections[first:end])
+
+		for _, s := range sections[start:end] {
+			if serverOrderIndependent(s, defaults) {
+				sortServers(s.directives)
+			}
+
+			lines := []string{s.header}
+
+			for _, d := range s.directives {
+				lines = append(lines, "  "+d)
+			}
+
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+
+		start = end
+	}
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// sectionKind returns the type of a section, e.g. backend
+func sectionKind(s canonicalSection) string {
+	return strings.Fields(s.header)[0]
+}
+
+// sortSections orders sections by type then name. Sections of types which aren't in
+// canonicalSectionOrder keep their order, after the others.
+func sortSections(sections []canonicalSection) {
+	rank := func(s canonicalSection) int {
+		for i, k := range canonicalSectionOrder {
+			if k == sectionKind(s) {
+				return i
+			}
+		}
+
+		return len(canonicalSectionOrder)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		ri, rj := rank(sections[i]), rank(sections[j])
+		if ri != rj || ri >= len(canonicalSectionOrder) {
+			return ri < rj
+		}
+
+		return sections[i].header < sections[j].header
+	})
+}
+
+// splitSections splits a config into the lines preceding the first section and its sections,
+// collapsing the whitespace of each line and dropping blank ones
+func splitSections(config string) ([]string, []canonicalSection) {
+	preamble := []string{}
+	sections := []canonicalSection{}
+
+	for _, line := range strings.Split(config, "\n") {
+		text := collapseWhitespace(line)
+
+		switch {
+		case text == "":
+			continue
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(text, "#"):
+			sections = append(sections, canonicalSection{header: text})
+		case len(sections) == 0:
+			preamble = append(preamble, text)
+		default:
+			last := &sections[len(sections)-1]
+			last.directives = append(last.directives, text)
+		}
+	}
+
+	return preamble, sections
+}
+
+// collapseWhitespace trims a line and collapses runs of whitespace between its words to a single
+// space, leaving whitespace within quotes unchanged
+func collapseWhitespace(line string) string {
+	var (
+		b       strings.Builder
+		quote   rune
+		escaped bool
+		space   bool
+	)
+
+	for _, r := range strings.TrimSpace(line) {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			space = true
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// orderIndependentBalance are the balance algorithms which pick servers regardless of the order
+// they are declared in. The others, e.g. first and the hashes, pick them by id, which follows the
+// declaration order.
+var orderIndependentBalance = map[string]bool{
+	"roundrobin": true,
+	"static-rr":  true,
+	"leastconn":  true,
+}
+
+// serverOrderIndependent returns true when reordering the servers of the section doesn't change
+// which server is picked: it balances with an algorithm of orderIndependentBalance, as set on the
+// section or its defaults, and all backup servers are used, or there are none, as otherwise only
+// the first is.
+func serverOrderIndependent(s canonicalSection, defaults *canonicalSection) bool {
+	balance, allBackups, backups := "roundrobin", false, false
+
+	for _, section := range []*canonicalSection{defaults, &s} {
+		if section == nil {
+			continue
+		}
+
+		for _, d := range section.directives {
+			fields := strings.Fields(d)
+
+			switch {
+			case len(fields) > 1 && fields[0] == "balance":
+				balance = fields[1]
+			case len(fields) > 1 && fields[0] == "option" && fields[1] == "allbackups":
+				allBackups = true
+			case len(fields) > 2 && fields[0] == "no" && fields[1] == "option" && fields[2] == "allbackups":
+				allBackups = false
+			case len(fields) > 0 && fields[0] == "server":
+				for _, f := range fields {
+					backups = backups || f == "backup"
+				}
+			}
+		}
+	}
+
+	return orderIndependentBalance[balance] && (allBackups || !backups)
+}
+
+// sortServers sorts each run of consecutive server directives by name. Runs aren't sorted across
+// other directives, as default-server only applies to the servers following it.
+func sortServers(directives []string) {
+	isServer := func(d string) bool { return strings.HasPrefix(d, "server ") }
+
+	for start := 0; start < len(directives); start++ {
+		if !isServer(directives[start]) {
+			continue
+		}
+
+		end := start
+		for end < len(directives) && isServer(directives[end]) {
+			end++
+		}
+
+		run := directives[start:end]
+
+		sort.SliceStable(run, func(i, j int) bool {
+			return strings.Fields(run[i])[1] < strings.Fields(run[j])[1]
+		})
+
+		start = end
+	}
+}
@@ -0,0 +1,151 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const canonicalTestCfg = `global
+  maxconn 200
+
+defaults
+  mode tcp
+
+frontend loadprt-a
+  bind ipv4@:22
+  use_backend loadprt-a
+
+frontend loadprt-b
+  bind ipv4@:80
+  http-response set-header X-Frame-Options "SAMEORIGIN"
+  use_backend loadprt-b
+
+backend loadprt-a
+  server loadogn-1 1.2.3.4:22 check port 22
+  server loadogn-2 1.2.3.5:22 check port 22
+
+backend loadprt-b
+  server loadogn-3 1.2.3.6:80 check port 80
+`
+
+func TestCanonicalize(t *testing.T) {
+	shuffled := []struct {
+		name   string
+		config string
+	}{
+		{"canonical", canonicalTestCfg},
+		{"sections reordered", `global
+  maxconn 200
+
+defaults
+  mode tcp
+
+backend loadprt-b
+  server loadogn-3 1.2.3.6:80 check port 80
+
+frontend loadprt-b
+  bind ipv4@:80
+  http-response set-header X-Frame-Options "SAMEORIGIN"
+  use_backend loadprt-b
+
+backend loadprt-a
+  server loadogn-1 1.2.3.4:22 check port 22
+  server loadogn-2 1.2.3.5:22 check port 22
+
+frontend loadprt-a
+  bind ipv4@:22
+  use_backend loadprt-a
+`},
+		{"servers reordered", `global
+  maxconn 200
+defaults
+  mode tcp
+frontend loadprt-a
+  bind ipv4@:22
+  use_backend loadprt-a
+frontend loadprt-b
+  bind ipv4@:80
+  http-response set-header X-Frame-Options "SAMEORIGIN"
+  use_backend loadprt-b
+backend loadprt-a
+  server loadogn-2 1.2.3.5:22 check port 22
+  server loadogn-1 1.2.3.4:22 check port 22
+backend loadprt-b
+  server loadogn-3 1.2.3.6:80 check port 80
+`},
+		{"whitespace", "global\n\tmaxconn   200\n\n\ndefaults\n    mode tcp\n\nfrontend   loadprt-a\n  bind ipv4@:22\n  use_backend loadprt-a  \n\n" +
+			"frontend loadprt-b\n  bind\tipv4@:80\n  http-response set-header X-Frame-Options \"SAMEORIGIN\"\n  use_backend loadprt-b\n\n" +
+			"backend loadprt-a\n  server loadogn-1 1.2.3.4:22 check port 22\n  server loadogn-2  1.2.3.5:22 check port 22\n\n" +
+			"backend loadprt-b\n  server loadogn-3 1.2.3.6:80 check   port 80\n"},
+	}
+
+	for _, tt := range shuffled {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, canonicalTestCfg, Canonicalize(tt.config))
+		})
+	}
+
+	t.Run("idempotent", func(t *testing.T) {
+		assert.Equal(t, canonicalTestCfg, Canonicalize(Canonicalize(canonicalTestCfg)))
+	})
+
+	t.Run("defaults keep their order", func(t *testing.T) {
+		config := "defaults http-defaults\n  mode http\n\ndefaults tcp-defaults\n  mode tcp\n"
+
+		assert.Equal(t, config, Canonicalize(config))
+	})
+
+	t.Run("sections aren't moved across defaults", func(t *testing.T) {
+		config := "global\n  maxconn 200\n\n" +
+			"defaults a\n  mode tcp\n\n" +
+			"frontend x\n  bind ipv4@:22\n\n" +
+			"defaults b\n  mode http\n\n" +
+			"backend z\n  server loadogn-1 1.2.3.4:80\n\n" +
+			"frontend y\n  bind ipv4@:80\n"
+
+		expected := "global\n  maxconn 200\n\n" +
+			"defaults a\n  mode tcp\n\n" +
+			"frontend x\n  bind ipv4@:22\n\n" +
+			"defaults b\n  mode http\n\n" +
+			"frontend y\n  bind ipv4@:80\n\n" +
+			"backend z\n  server loadogn-1 1.2.3.4:80\n"
+
+		assert.Equal(t, expected, Canonicalize(config))
+	})
+
+	t.Run("server order is kept when it matters", func(t *testing.T) {
+		for _, config := range []string{
+			"backend loadprt-a\n  balance first\n  server loadogn-2 1.2.3.5:22\n  server loadogn-1 1.2.3.4:22\n",
+			"backend loadprt-a\n  balance source\n  hash-type consistent\n  server loadogn-2 1.2.3.5:22\n  server loadogn-1 1.2.3.4:22\n",
+			"backend loadprt-a\n  server loadogn-2 1.2.3.5:22 backup\n  server loadogn-1 1.2.3.4:22 backup\n",
+			"defaults\n  balance uri\n\nbackend loadprt-a\n  server loadogn-2 1.2.3.5:22\n  server loadogn-1 1.2.3.4:22\n",
+		} {
+			assert.Equal(t, config, Canonicalize(config))
+		}
+	})
+
+	t.Run("servers are sorted when all backups are used", func(t *testing.T) {
+		config := "backend loadprt-a\n  balance leastconn\n  option allbackups\n  server loadogn-2 1.2.3.5:22 backup\n  server loadogn-1 1.2.3.4:22 backup\n"
+		expected := "backend loadprt-a\n  balance leastconn\n  option allbackups\n  server loadogn-1 1.2.3.4:22 backup\n  server loadogn-2 1.2.3.5:22 backup\n"
+
+		assert.Equal(t, expected, Canonicalize(config))
+	})
+
+	t.Run("servers aren't sorted across default-server", func(t *testing.T) {
+		config := "backend loadprt-a\n  server loadogn-2 1.2.3.5:22\n  default-server inter 5s\n  server loadogn-1 1.2.3.4:22\n"
+
+		assert.Equal(t, config, Canonicalize(config))
+	})
+
+	t.Run("whitespace within quotes is kept", func(t *testing.T) {
+		config := "frontend loadprt-a\n  log-format \"%ci  %cp\"\n"
+
+		assert.Equal(t, config, Canonicalize(config))
+	})
+}
@@ -0,0 +1,26 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkTimeouts are the backend timeouts of health checks, and how they are read from a pool
+var checkTimeouts = []struct {
+	name string
+	get  func(PoolSettings) time.Duration
+}{
+	{"timeout check", func(p PoolSettings) time.Duration { return p.CheckTimeout }},
+	{"timeout connect", func(p PoolSettings) time.Duration { return p.ConnectTimeout }},
+}
+
+// validateCheckTimeouts checks the check and connect timeouts aren't negative and are whole milliseconds
+func (p PoolSettings) validateCheckTimeouts() error {
+	for _, t := range checkTimeouts {
+		if d := t.get(p); d < 0 || d%time.Millisecond != 0 {
+			return fmt.Errorf("%w: %s %s is not positive whole milliseconds", ErrCheckTimeoutInvalid, t.name, d)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package haproxyconfig
+
+import (
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/types"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// DefaultsModePredominant sets the mode of the defaults section to the mode of most ports
+const DefaultsModePredominant = "predominant"
+
+// mergeDefaultsMode sets the mode of the defaults sections when it is managed. Ports which inherited
+// the base config's mode are returned with it set explicitly when it differs from the new mode, so
+// their sections render with the same mode as before.
+func mergeDefaultsMode(cfg parser.Parser, lb *lbapi.LoadBalancer, settings Settings) (Settings, error) {
+	if settings.DefaultsMode == "" {
+		return settings, nil
+	}
+
+	if settings.DefaultsMode != modeTCP && settings.DefaultsMode != modeHTTP && settings.DefaultsMode != DefaultsModePredominant {
+		return settings, fmt.Errorf("%w: %q", ErrDefaultsModeInvalid, settings.DefaultsMode)
+	}
+
+	sections, err := cfg.SectionsGet(parser.Defaults)
+	if err != nil || len(sections) == 0 {
+		return settings, ErrDefaultsNotFound
+	}
+
+	baseMode := defaultsMode(cfg, sections[len(sections)-1])
+
+	mode := settings.DefaultsMode
+	if mode == DefaultsModePredominant {
+		mode = predominantMode(lb, settings, baseMode)
+	}
+
+	for _, section := range sections {
+		if err := cfg.Set(parser.Defaults, section, "mode", types.StringC{Value: mode}); err != nil {
+			return settings, newLabelError("mode", ErrDefaultsAttrFailure, err)
+		}
+	}
+
+	if mode == baseMode {
+		return settings, nil
+	}
+
+	// copy the port settings, so the caller's are left unchanged
+	ports := append([]PortSettings{}, settings.Ports...)
+
+	for _, p := range lb.Ports.Edges {
+		if settings.port(p.Node.ID).Mode != "" {
+			continue
+		}
+
+		found := false
+
+		for i := range ports {
+			if ports[i].ID == p.Node.ID {
+				ports[i].Mode, found = baseMode, true
+			}
+		}
+
+		if !found {
+			ports = append(ports, PortSettings{ID: p.Node.ID, Mode: baseMode})
+		}
+	}
+
+	settings.Ports = ports
+
+	return settings, nil
+}
+
+// defaultsMode returns the mode of a defaults section, which is tcp when it isn't set
+func defaultsMode(cfg parser.Parser, section string) string {
+	data, err := cfg.Get(parser.Defaults, section, "mode")
+	if err != nil {
+		return modeTCP
+	}
+
+	if mode, ok := data.(*types.StringC); ok && mode.Value != "" {
+		return mode.Value
+	}
+
+	return modeTCP
+}
+
+// backendMode returns the mode of a port's sections, which is the mode of the last defaults section
+// when the port doesn't set one
+func backendMode(cfg parser.Parser, portSettings PortSettings) string {
+	if portSettings.Mode != "" {
+		return portSettings.Mode
+	}
+
+	sections, err := cfg.SectionsGet(parser.Defaults)
+	if err != nil || len(sections) == 0 {
+		return modeTCP
+	}
+
+	return defaultsMode(cfg, sections[len(sections)-1])
+}
+
+// predominantMode returns the mode of most ports, where ports without one have the base mode. The
+// base mode is kept on a tie.
+func predominantMode(lb *lbapi.LoadBalancer, settings Settings, baseMode string) string {
+	counts := map[string]int{}
+
+	for _, p := range lb.Ports.Edges {
+		mode := settings.port(p.Node.ID).Mode
+		if mode == "" {
+			mode = baseMode
+		}
+
+		counts[mode]++
+	}
+
+	if counts[modeHTTP] > counts[modeTCP] {
+		return modeHTTP
+	}
+
+	if counts[modeTCP] > counts[modeHTTP] {
+		return modeTCP
+	}
+
+	return baseMode
+}
@@ -0,0 +1,93 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/haproxytech/config-parser/v4/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDefaultsMode(t *testing.T) {
+	// mode returns the mode set directly on a section, or nothing when it inherits one
+	mode := func(cfg parser.Parser, section parser.Section, label string) string {
+		data, err := cfg.Get(section, label, "mode")
+		if err != nil {
+			return ""
+		}
+
+		return data.(*types.StringC).Value
+	}
+
+	defaults := func(t *testing.T, cfg parser.Parser) string {
+		sections, err := cfg.SectionsGet(parser.Defaults)
+		require.NoError(t, err)
+		require.Len(t, sections, 1)
+
+		return mode(cfg, parser.Defaults, sections[0])
+	}
+
+	// the base config defaults to tcp, and the http port overrides it
+	mixed := []PortSettings{{ID: "loadprt-testhttp", Mode: "http"}}
+
+	tests := []struct {
+		name           string
+		settings       Settings
+		expDefaults    string
+		expHTTPMode    string
+		expHTTPSMode   string
+		expHTTPBackend string
+	}{
+		{"untouched", Settings{Ports: mixed}, "tcp", "http", "", "http"},
+		{"tcp", Settings{DefaultsMode: "tcp", Ports: mixed}, "tcp", "http", "", "http"},
+		{"http", Settings{DefaultsMode: "http", Ports: mixed}, "http", "http", "tcp", "http"},
+		{"predominant tie keeps the base mode", Settings{DefaultsMode: "predominant", Ports: mixed}, "tcp", "http", "", "http"},
+		{"predominant http", Settings{DefaultsMode: "predominant", Ports: []PortSettings{
+			{ID: "loadprt-testhttp", Mode: "http"},
+			{ID: "loadprt-testhttps", Mode: "http"},
+		}}, "http", "http", "http", "http"},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+			require.Nil(t, err)
+
+			newCfg, err := Merge(cfg, &mergeTestData3, tt.settings)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expDefaults, defaults(t, newCfg))
+			assert.Equal(t, tt.expHTTPMode, mode(newCfg, parser.Frontends, "loadprt-testhttp"))
+			assert.Equal(t, tt.expHTTPBackend, mode(newCfg, parser.Backends, "loadprt-testhttp"))
+			assert.Equal(t, tt.expHTTPSMode, mode(newCfg, parser.Frontends, "loadprt-testhttps"))
+			assert.Equal(t, tt.expHTTPSMode, mode(newCfg, parser.Backends, "loadprt-testhttps"))
+		})
+	}
+
+	t.Run("settings are left unchanged", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		settings := Settings{DefaultsMode: "http", Ports: append([]PortSettings{}, mixed...)}
+
+		_, err = Merge(cfg, &mergeTestData3, settings)
+		require.NoError(t, err)
+
+		assert.Equal(t, mixed, settings.Ports)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		_, err = Merge(cfg, &mergeTestData3, Settings{DefaultsMode: "udp"})
+		assert.ErrorIs(t, err, ErrDefaultsModeInvalid)
+	})
+}
@@ -0,0 +1,4 @@
+// Package haproxyconfig generates haproxy config for a loadbalancer, merging its ports, pools
+// and origins into a base config. It is used by the manager, and by other services needing to
+// render identical configs.
+package haproxyconfig
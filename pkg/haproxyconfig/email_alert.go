@@ -0,0 +1,159 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// MailersSection is the label of the mailers section generated for the mailers email alerts are
+// sent with
+const MailersSection = "alerts"
+
+var (
+	// mailerNameRegex matches the name of a mailer, a single word
+	mailerNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+	// emailAddressRegex matches an email address which can be rendered as a single word
+	emailAddressRegex = regexp.MustCompile(`^[^\s@"'#]+@[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+)
+
+// emailAlertLevels are the syslog levels haproxy accepts for email-alert level
+var emailAlertLevels = map[string]bool{
+	"emerg":   true,
+	"alert":   true,
+	"crit":    true,
+	"err":     true,
+	"warning": true,
+	"notice":  true,
+	"info":    true,
+	"debug":   true,
+}
+
+// Mailer is an smtp server email alerts are sent with, its address a host and port, e.g.
+// smtp.example.com:25
+type Mailer struct {
+	Name    string
+	Address string
+}
+
+// EmailAlertSettings email an address when the servers of a backend change state, through the
+// mailers of Settings.Mailers. Empty settings don't send alerts.
+type EmailAlertSettings struct {
+	// From and To are the sender and recipient addresses, both required
+	From string
+	To   string
+
+	// Level is the syslog level of the state changes emailed, e.g. notice to include servers
+	// coming back up. Empty keeps haproxy's default of alert.
+	Level string
+}
+
+// validateMailers checks the mailers have unique names which are single words, and addresses of
+// a host and port
+func validateMailers(mailers []Mailer) error {
+	names := map[string]bool{}
+
+	for _, m := range mailers {
+		if !mailerNameRegex.MatchString(m.Name) || names[m.Name] {
+			return fmt.Errorf("%w: name %q", ErrMailerInvalid, m.Name)
+		}
+
+		names[m.Name] = true
+
+		host, port, err := net.SplitHostPort(m.Address)
+		if err != nil || (!mailerNameRegex.MatchString(host) && net.ParseIP(host) == nil) {
+			return fmt.Errorf("%w %q: address %q", ErrMailerInvalid, m.Name, m.Address)
+		}
+
+		if number, err := strconv.ParseInt(port, 10, 64); err != nil || number < 1 || number > maxPortNumber {
+			return fmt.Errorf("%w %q: port %q", ErrMailerInvalid, m.Name, port)
+		}
+	}
+
+	return nil
+}
+
+// validate checks email alerts have a sender and recipient which can be rendered, a level haproxy
+// accepts, and mailers to send them with
+func (e EmailAlertSettings) validate(mailers []Mailer) error {
+	if e == (EmailAlertSettings{}) {
+		return nil
+	}
+
+	if len(mailers) == 0 {
+		return ErrEmailAlertRequiresMailers
+	}
+
+	if e.From == "" || e.To == "" {
+		return fmt.Errorf("%w: from and to are required", ErrEmailAlertInvalid)
+	}
+
+	for _, addr := range []string{e.From, e.To} {
+		if !emailAddressRegex.MatchString(addr) {
+			return fmt.Errorf("%w: address %q", ErrEmailAlertInvalid, addr)
+		}
+	}
+
+	if e.Level != "" && !emailAlertLevels[e.Level] {
+		return fmt.Errorf("%w: level %q", ErrEmailAlertInvalid, e.Level)
+	}
+
+	return nil
+}
+
+// rules returns the email-alert directives of a backend, or nothing when alerts aren't set
+func (e EmailAlertSettings) rules() []string {
+	if e == (EmailAlertSettings{}) {
+		return nil
+	}
+
+	rules := []string{
+		"email-alert mailers " + MailersSection,
+		"email-alert from " + e.From,
+		"email-alert to " + e.To,
+	}
+
+	if e.Level != "" {
+		rules = append(rules, "email-alert level "+e.Level)
+	}
+
+	return rules
+}
+
+// emailAlert returns the email alerts of a pool, for comparing pools sharing a backend
+func emailAlert(p PoolSettings) EmailAlertSettings {
+	return p.EmailAlert
+}
+
+// mergeMailers creates the mailers section when mailers are set. Its mailers aren't modeled by the
+// parser, so they are added with the raw directives.
+func mergeMailers(cfg parser.Parser, settings Settings) error {
+	if len(settings.Mailers) == 0 {
+		return nil
+	}
+
+	if err := prepareSection(cfg, parser.Mailers, MailersSection, settings.OverwriteBaseSections); err != nil {
+		return err
+	}
+
+	if err := cfg.SectionsCreate(parser.Mailers, MailersSection); err != nil {
+		return newLabelError(MailersSection, ErrMailersSectionLabelFailure, err)
+	}
+
+	return nil
+}
+
+// mailerRules returns the mailers of the mailers section, or nothing when there are none
+func (s Settings) mailerRules() []string {
+	rules := make([]string, 0, len(s.Mailers))
+
+	for _, m := range s.Mailers {
+		rules = append(rules, "mailer "+m.Name+" "+m.Address)
+	}
+
+	return rules
+}
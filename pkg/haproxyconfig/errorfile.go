@@ -0,0 +1,74 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// errorFileStatuses are the status codes haproxy accepts errorfile directives for
+var errorFileStatuses = map[int]bool{
+	200: true,
+	400: true,
+	401: true,
+	403: true,
+	404: true,
+	405: true,
+	407: true,
+	408: true,
+	410: true,
+	413: true,
+	425: true,
+	429: true,
+	500: true,
+	501: true,
+	502: true,
+	503: true,
+	504: true,
+}
+
+// ErrorFile replaces the response haproxy generates for a status code with the contents of a file,
+// a complete http response including its headers, e.g. a branded 503 page
+type ErrorFile struct {
+	Status int
+	Path   string
+}
+
+// validateErrorFiles checks error files are only set in http mode, for status codes haproxy
+// generates, with one absolute path per status code
+func (p PortSettings) validateErrorFiles() error {
+	if len(p.ErrorFiles) > 0 && p.Mode != modeHTTP {
+		return ErrErrorFilesRequireHTTP
+	}
+
+	seen := map[int]bool{}
+
+	for _, f := range p.ErrorFiles {
+		if !errorFileStatuses[f.Status] {
+			return fmt.Errorf("%w: %d", ErrErrorFileStatusInvalid, f.Status)
+		}
+
+		if seen[f.Status] {
+			return fmt.Errorf("%w: %d is set more than once", ErrErrorFileStatusInvalid, f.Status)
+		}
+
+		seen[f.Status] = true
+
+		if !path.IsAbs(f.Path) || strings.ContainsAny(f.Path, " \t\r\n\"'#") {
+			return fmt.Errorf("%w %d: %q", ErrErrorFilePathInvalid, f.Status, f.Path)
+		}
+	}
+
+	return nil
+}
+
+// errorFileRules returns the errorfile directives of the frontend
+func (p PortSettings) errorFileRules() []string {
+	rules := []string{}
+
+	for _, f := range p.ErrorFiles {
+		rules = append(rules, fmt.Sprintf("errorfile %d %s", f.Status, f.Path))
+	}
+
+	return rules
+}
@@ -0,0 +1,333 @@
+package haproxyconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBaseConfigInvalid is returned when the base config cannot be read or parsed
+	ErrBaseConfigInvalid = errors.New("invalid base config")
+
+	// ErrPortSettingsInvalid is returned when the settings for a port cannot be rendered
+	ErrPortSettingsInvalid = errors.New("invalid settings for port")
+
+	// ErrSocketPathNotAbsolute is returned when a unix socket bind path is relative
+	ErrSocketPathNotAbsolute = errors.New("socket path must be absolute")
+
+	// ErrBindScopeWithSocket is returned when an interface or namespace is set for a unix socket bind
+	ErrBindScopeWithSocket = errors.New("interface and namespace only apply to tcp binds")
+
+	// ErrInterfaceInvalid is returned when a bind interface name is invalid
+	ErrInterfaceInvalid = errors.New("invalid bind interface")
+
+	// ErrNamespaceInvalid is returned when a bind namespace name is invalid
+	ErrNamespaceInvalid = errors.New("invalid bind namespace")
+
+	// ErrAddressFamilyInvalid is returned when a bind address family isn't ipv4, ipv6 or dual, or is
+	// set for a unix socket bind
+	ErrAddressFamilyInvalid = errors.New("invalid bind address family")
+
+	// ErrThreadInvalid is returned when a bind thread range is malformed or exceeds nbthread
+	ErrThreadInvalid = errors.New("invalid bind thread range")
+
+	// ErrNbProcInvalid is returned when nbproc is negative, or set to several processes with several threads
+	ErrNbProcInvalid = errors.New("invalid nbproc")
+
+	// ErrProcessInvalid is returned when a bind process set is malformed, exceeds nbproc or is set without it
+	ErrProcessInvalid = errors.New("invalid bind process set")
+
+	// ErrExpectProxySourcesUnused is returned when PROXY protocol sources are set without expecting it
+	ErrExpectProxySourcesUnused = errors.New("expect-proxy sources require expect-proxy")
+
+	// ErrExpectProxySourceInvalid is returned when a PROXY protocol source is not a cidr
+	ErrExpectProxySourceInvalid = errors.New("invalid expect-proxy source cidr")
+
+	// ErrDefaultsModeInvalid is returned when the defaults mode is not tcp, http or predominant
+	ErrDefaultsModeInvalid = errors.New("defaults mode must be tcp, http or predominant")
+
+	// ErrDefaultsNotFound is returned when the defaults mode is managed but the base config has no defaults section
+	ErrDefaultsNotFound = errors.New("defaults section not found")
+
+	// ErrModeInvalid is returned when a port's proxy mode is neither tcp nor http
+	ErrModeInvalid = errors.New("mode must be tcp or http")
+
+	// ErrResponseHeadersRequireHTTP is returned when response header rules are set on a port not in http mode
+	ErrResponseHeadersRequireHTTP = errors.New("response headers require http mode")
+
+	// ErrHeaderActionInvalid is returned when a header rule action is not supported
+	ErrHeaderActionInvalid = errors.New("header action must be set-header or del-header")
+
+	// ErrHeaderRuleInvalid is returned when a header rule cannot be rendered
+	ErrHeaderRuleInvalid = errors.New("invalid header rule")
+
+	// ErrLogFormatInvalid is returned when a log format has a malformed token
+	ErrLogFormatInvalid = errors.New("invalid log format")
+
+	// ErrMetricsPortInvalid is returned when the metrics port is not a valid tcp port
+	ErrMetricsPortInvalid = errors.New("invalid metrics port")
+
+	// ErrMetricsPortConflict is returned when the metrics port is also a port of the loadbalancer
+	ErrMetricsPortConflict = errors.New("metrics port is used by loadbalancer port")
+
+	// ErrConnRateLimitRequiresTCP is returned when a connection rate limit is set on a port in http mode
+	ErrConnRateLimitRequiresTCP = errors.New("connection rate limit requires tcp mode")
+
+	// ErrConnRateLimitInvalid is returned when a connection rate limit has no limit or an invalid period
+	ErrConnRateLimitInvalid = errors.New("invalid connection rate limit")
+
+	// ErrUniqueIDRequiresHTTP is returned when a unique request id is generated on a port not in http mode
+	ErrUniqueIDRequiresHTTP = errors.New("unique request ids require http mode")
+
+	// ErrUniqueIDHeaderInvalid is returned when a unique id header is not a valid header name, or set without a format
+	ErrUniqueIDHeaderInvalid = errors.New("invalid unique id header")
+
+	// ErrErrorFilesRequireHTTP is returned when error files are set on a port not in http mode
+	ErrErrorFilesRequireHTTP = errors.New("error files require http mode")
+
+	// ErrErrorFileStatusInvalid is returned when an error file is set for a status code haproxy doesn't generate, or more than once
+	ErrErrorFileStatusInvalid = errors.New("invalid error file status code")
+
+	// ErrErrorFilePathInvalid is returned when an error file is not an absolute path
+	ErrErrorFilePathInvalid = errors.New("invalid error file path")
+
+	// ErrHAProxyVersionInvalid is returned when an haproxy version doesn't start with a numeric release
+	ErrHAProxyVersionInvalid = errors.New("invalid haproxy version")
+
+	// ErrMailerInvalid is returned when a mailer name isn't a unique word or its address isn't a host and port
+	ErrMailerInvalid = errors.New("invalid mailer")
+
+	// ErrEmailAlertRequiresMailers is returned when email alerts are set for a pool without mailers to send them with
+	ErrEmailAlertRequiresMailers = errors.New("email alerts require mailers")
+
+	// ErrEmailAlertInvalid is returned when email alerts lack a sender or recipient, or have an invalid address or level
+	ErrEmailAlertInvalid = errors.New("invalid email alert")
+
+	// ErrCrtListRequired is returned when certificates are set for a port without a crt-list to write them to
+	ErrCrtListRequired = errors.New("certs require a crt-list")
+
+	// ErrCrtListInvalid is returned when a crt-list is not a clean absolute path
+	ErrCrtListInvalid = errors.New("invalid crt-list path")
+
+	// ErrCrtDirInvalid is returned when a crt dir is not a clean absolute path
+	ErrCrtDirInvalid = errors.New("invalid crt dir path")
+
+	// ErrCrtDirConflict is returned when a port sets both a crt-list and a crt dir
+	ErrCrtDirConflict = errors.New("crt dir can't be set with a crt-list")
+
+	// ErrCertInvalid is returned when a certificate is not a clean path within the cert dir
+	ErrCertInvalid = errors.New("invalid cert path")
+
+	// ErrCertDirRequired is returned when a certificate is a relative path without a cert dir
+	ErrCertDirRequired = errors.New("relative cert path requires a cert dir")
+
+	// ErrSNIFilterInvalid is returned when a crt-list sni filter is not a hostname or wildcard
+	ErrSNIFilterInvalid = errors.New("invalid sni filter")
+
+	// ErrTLSOptionsRequireTLS is returned when tls options are set for a port without a crt-list or crt dir
+	ErrTLSOptionsRequireTLS = errors.New("tls options require a crt-list or crt dir")
+
+	// ErrTLSOptionInvalid is returned when an alpn protocol or cipher list can't be rendered
+	ErrTLSOptionInvalid = errors.New("invalid tls option")
+
+	// ErrEarlyDataRulesRequireHTTP is returned when early data rules are set on a port not in http mode
+	ErrEarlyDataRulesRequireHTTP = errors.New("early data rules require http mode")
+
+	// ErrTLSVersionInvalid is returned when a tls version is not supported, or the minimum is above the maximum
+	ErrTLSVersionInvalid = errors.New("invalid tls version")
+
+	// ErrOriginTargetInvalid is returned when an origin target is neither an ip address nor a hostname
+	ErrOriginTargetInvalid = errors.New("invalid target for origin")
+
+	// ErrPoolSettingsInvalid is returned when the settings for a pool cannot be rendered
+	ErrPoolSettingsInvalid = errors.New("invalid settings for pool")
+
+	// ErrPoolSettingsConflict is returned when pools sharing a backend disagree on a backend-level setting
+	ErrPoolSettingsConflict = errors.New("conflicting pool settings for backend")
+
+	// ErrBalanceAlgorithmInvalid is returned when the balance algorithm is not supported
+	ErrBalanceAlgorithmInvalid = errors.New("unsupported balance algorithm")
+
+	// ErrBalanceParamNotSupported is returned when a parameter is given to a balance algorithm which takes none
+	ErrBalanceParamNotSupported = errors.New("balance algorithm does not accept a parameter")
+
+	// ErrBalanceParamRequired is returned when a balance algorithm which requires a parameter is given none
+	ErrBalanceParamRequired = errors.New("balance algorithm requires a parameter")
+
+	// ErrBackupOriginNotFound is returned when a backup is not an origin of the pool
+	ErrBackupOriginNotFound = errors.New("backup is not an origin of the pool")
+
+	// ErrFailoverPoolNotFound is returned when a failover pool is not a pool of the port
+	ErrFailoverPoolNotFound = errors.New("failover pool is not a pool of the port")
+
+	// ErrFailoverPoolInvalid is returned when a failover pool is listed more than once or routed by a condition
+	ErrFailoverPoolInvalid = errors.New("invalid failover pool")
+
+	// ErrSplitPoolNotFound is returned when a split weight is set for a pool which is not a pool of the port
+	ErrSplitPoolNotFound = errors.New("split pool is not a pool of the port")
+
+	// ErrSplitInvalid is returned when pools are split by weights which aren't positive, along with failover pools, or
+	// weights are set for pools routed by a condition or without splitting pools
+	ErrSplitInvalid = errors.New("invalid pool split")
+
+	// ErrAllBackupsWithoutBackups is returned when all backups are to be used but the pool has none, and its port no failover pools
+	ErrAllBackupsWithoutBackups = errors.New("allbackups requires backup origins or failover pools")
+
+	// ErrResolversRequired is returned when init-addr or resolve-opts are set without resolvers
+	ErrResolversRequired = errors.New("init-addr and resolve-opts require resolvers")
+
+	// ErrAgentCheckRequired is returned when agent-addr, agent-port or agent-inter are set without agent-check
+	ErrAgentCheckRequired = errors.New("agent settings require agent-check")
+
+	// ErrAgentPortRequired is returned when agent-check is enabled without an agent-port
+	ErrAgentPortRequired = errors.New("agent-check requires agent-port")
+
+	// ErrAgentPortInvalid is returned when the agent-port is not a valid tcp port
+	ErrAgentPortInvalid = errors.New("invalid agent-port")
+
+	// ErrAgentAddrInvalid is returned when the agent-addr is neither an ip address nor a hostname
+	ErrAgentAddrInvalid = errors.New("invalid agent-addr")
+
+	// ErrAgentInterInvalid is returned when the agent-inter is negative
+	ErrAgentInterInvalid = errors.New("invalid agent-inter")
+
+	// ErrObserveInvalid is returned when servers are observed in an unsupported mode or with an unsupported on-error action or error limit
+	ErrObserveInvalid = errors.New("invalid server observation")
+
+	// ErrObserveLayer7RequiresHTTP is returned when servers of a backend not in http mode are observed at layer7
+	ErrObserveLayer7RequiresHTTP = errors.New("observing servers at layer7 requires http mode")
+
+	// ErrCheckTimeoutInvalid is returned when a check or connect timeout is negative or not whole milliseconds
+	ErrCheckTimeoutInvalid = errors.New("invalid check timeout")
+
+	// ErrQueueLimitInvalid is returned when a queue limit or timeout is negative
+	ErrQueueLimitInvalid = errors.New("invalid queue limit")
+
+	// ErrSlowStartInvalid is returned when a slowstart period is negative or not whole milliseconds
+	ErrSlowStartInvalid = errors.New("invalid slowstart")
+
+	// ErrRedispatchInvalid is returned when a redispatch interval is set without redispatch, or exceeds the retries
+	ErrRedispatchInvalid = errors.New("invalid redispatch interval")
+
+	// ErrForcePersistInvalid is returned when a force-persist condition is not a single line
+	ErrForcePersistInvalid = errors.New("invalid force-persist condition")
+
+	// ErrSpliceExclusive is returned when a pool sets automatic splicing along with explicit splicing
+	ErrSpliceExclusive = errors.New("splice-auto is exclusive with splice-request and splice-response")
+
+	// ErrRetriesInvalid is returned when the number of retries is negative
+	ErrRetriesInvalid = errors.New("retries must not be negative")
+
+	// ErrRetryOnInvalid is returned when a retry-on condition is not supported
+	ErrRetryOnInvalid = errors.New("unsupported retry-on condition")
+
+	// ErrHTTPReuseInvalid is returned when an http-reuse mode is not supported
+	ErrHTTPReuseInvalid = errors.New("unsupported http-reuse mode")
+
+	// ErrSourceAddressInvalid is returned when a pool's source address is not an ip address
+	ErrSourceAddressInvalid = errors.New("invalid source address")
+
+	// ErrHTTPCheckPathRequired is returned when an http check method, version or headers are set without a path
+	ErrHTTPCheckPathRequired = errors.New("http check requires a path")
+
+	// ErrHTTPCheckPathInvalid is returned when an http check path is not a single word starting with /
+	ErrHTTPCheckPathInvalid = errors.New("invalid http check path")
+
+	// ErrHTTPCheckMethodInvalid is returned when an http check method is not supported
+	ErrHTTPCheckMethodInvalid = errors.New("unsupported http check method")
+
+	// ErrHTTPCheckVersionInvalid is returned when an http check version is not supported
+	ErrHTTPCheckVersionInvalid = errors.New("unsupported http check version")
+
+	// ErrHTTPCheckHeaderInvalid is returned when an http check header can't be rendered
+	ErrHTTPCheckHeaderInvalid = errors.New("invalid http check header")
+
+	// ErrHTTPCheckWithExternalCheck is returned when a pool sets both an http check and an external check
+	ErrHTTPCheckWithExternalCheck = errors.New("http check and external check are exclusive")
+
+	// ErrExternalCheckCommandRequired is returned when an external check path is set without a command
+	ErrExternalCheckCommandRequired = errors.New("external-check path requires external-check command")
+
+	// ErrExternalCheckCommandInvalid is returned when the external check command is not a clean absolute path
+	ErrExternalCheckCommandInvalid = errors.New("invalid external-check command")
+
+	// ErrExternalCheckPathRequired is returned when an external check command is set without a path
+	ErrExternalCheckPathRequired = errors.New("external-check command requires external-check path")
+
+	// ErrExternalCheckPathInvalid is returned when the external check path is not a list of absolute directories
+	ErrExternalCheckPathInvalid = errors.New("invalid external-check path")
+
+	// ErrResolversNotFound is returned when the resolvers section is not in the base config
+	ErrResolversNotFound = errors.New("resolvers section not found")
+
+	// ErrInitAddrInvalid is returned when an init-addr method is not supported
+	ErrInitAddrInvalid = errors.New("unsupported init-addr method")
+
+	// ErrResolveOptInvalid is returned when a resolve-opts option is not supported
+	ErrResolveOptInvalid = errors.New("unsupported resolve-opts option")
+
+	// ErrRawDirectivesNotAllowed is returned when raw directives are configured but not allowed
+	ErrRawDirectivesNotAllowed = errors.New("raw directives are not allowed")
+
+	// ErrRawDirectiveInvalid is returned when a raw directive is empty or spans multiple lines
+	ErrRawDirectiveInvalid = errors.New("raw directive must be a single line")
+
+	// ErrRawDirectivesFailure is returned when the config with raw directives cannot be parsed
+	ErrRawDirectivesFailure = errors.New("failed to apply raw directives")
+
+	// ErrConfigTemplateInvalid is returned when a config template cannot be parsed
+	ErrConfigTemplateInvalid = errors.New("invalid config template")
+
+	// ErrConfigTemplateFailure is returned when a config template cannot be rendered, or its output parsed
+	ErrConfigTemplateFailure = errors.New("failed to apply config template")
+
+	// ErrGlobalAttrFailure is returned when a directive cannot be set in the global section
+	ErrGlobalAttrFailure = errors.New("failed to set global attr")
+
+	// ErrSectionConflict is returned when the base config already has a section the loadbalancer generates
+	ErrSectionConflict = errors.New("base config already has generated section")
+
+	// ErrStaleSectionRemoval is returned when a generated section the loadbalancer no longer has cannot be removed
+	ErrStaleSectionRemoval = errors.New("failed to remove stale section")
+
+	// ErrDefaultsAttrFailure is returned when an attribute cannot be applied to a defaults section
+	ErrDefaultsAttrFailure = errors.New("failed to set defaults attr")
+
+	// ErrFrontendSectionLabelFailure is returned when a frontend section cannot be created
+	ErrFrontendSectionLabelFailure = errors.New("failed to create frontend section with label")
+
+	// ErrUseBackendFailure is returned when the use_backend attr cannot be applied to a frontend
+	ErrUseBackendFailure = errors.New("failed to create frontend attr use_backend")
+
+	// ErrDefaultBackendFailure is returned when the default_backend attr cannot be applied to a frontend
+	ErrDefaultBackendFailure = errors.New("failed to create frontend attr default_backend")
+
+	// ErrFrontendModeFailure is returned when the mode attr cannot be applied to a frontend
+	ErrFrontendModeFailure = errors.New("failed to create frontend attr mode")
+
+	// ErrFrontendAttrFailure is returned when an attribute cannot be applied to a frontend
+	ErrFrontendAttrFailure = errors.New("failed to set frontend attr")
+
+	// ErrFrontendBindFailure is returned when the bind attribute cannot be applied to a frontend
+	ErrFrontendBindFailure = errors.New("failed to create frontend attr bind")
+
+	// ErrBackendSectionLabelFailure is returned when a backend section cannot be created
+	ErrBackendSectionLabelFailure = errors.New("failed to create section backend with label")
+
+	// ErrBackendAttrFailure is returned when an attribute cannot be applied to a backend
+	ErrBackendAttrFailure = errors.New("failed to set backend attr")
+
+	// ErrMailersSectionLabelFailure is returned when the mailers section cannot be created
+	ErrMailersSectionLabelFailure = errors.New("failed to create section mailers with label")
+
+	// ErrBackendServerFailure is returned when a server cannot be applied to a backend
+	ErrBackendServerFailure = errors.New("failed to add backend attr server: ")
+)
+
+func newLabelError(label string, err error, labelErr error) error {
+	return fmt.Errorf("%w %q: %w", err, label, labelErr)
+}
+
+func newAttrError(err error, attrErr error) error {
+	return fmt.Errorf("%w: %v", err, attrErr)
+}
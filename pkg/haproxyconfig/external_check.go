@@ -0,0 +1,88 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// externalCheckWarning is the comment rendered with external checks, as they weaken haproxy's
+// isolation: haproxy forks to run the command as its own user, for each check of each server
+const externalCheckWarning = "external checks fork haproxy to run commands as its user"
+
+// ExternalCheckSettings is an external check of a pool's servers, a command haproxy runs for each
+// check, with Path as its PATH environment variable
+type ExternalCheckSettings struct {
+	Command string
+	Path    string
+}
+
+// validate checks the command is an absolute path and the PATH is a list of absolute directories,
+// neither of which can break out of their directives
+func (e ExternalCheckSettings) validate() error {
+	if e.Command == "" {
+		if e.Path != "" {
+			return ErrExternalCheckCommandRequired
+		}
+
+		return nil
+	}
+
+	if !validExternalCheckPath(e.Command) {
+		return fmt.Errorf("%w: %q", ErrExternalCheckCommandInvalid, e.Command)
+	}
+
+	if e.Path == "" {
+		return ErrExternalCheckPathRequired
+	}
+
+	for _, dir := range strings.Split(e.Path, ":") {
+		if !validExternalCheckPath(dir) {
+			return fmt.Errorf("%w: %q", ErrExternalCheckPathInvalid, e.Path)
+		}
+	}
+
+	return nil
+}
+
+// validExternalCheckPath returns true when p is a clean absolute path without whitespace, quotes
+// or comments
+func validExternalCheckPath(p string) bool {
+	return path.IsAbs(p) && path.Clean(p) == p && !strings.ContainsAny(p, " \t\r\n\"'#:")
+}
+
+// rules returns the backend directives of the external check, or nothing when there is none
+func (e ExternalCheckSettings) rules() []string {
+	if e.Command == "" {
+		return nil
+	}
+
+	return []string{
+		"option external-check # " + externalCheckWarning,
+		"external-check path " + e.Path,
+		"external-check command " + e.Command,
+	}
+}
+
+// externalCheckGlobals returns the global directives external checks require, which the base
+// config doesn't already have
+func externalCheckGlobals(cfg parser.Parser) []string {
+	globals := []string{}
+
+	for _, d := range []string{"external-check", "insecure-fork-wanted"} {
+		if _, err := cfg.Get(parser.Global, parser.GlobalSectionName, d); err == nil {
+			continue
+		}
+
+		globals = append(globals, d+" # "+externalCheckWarning)
+	}
+
+	return globals
+}
+
+// externalCheck returns the external check of a pool, for comparing pools sharing a backend
+func externalCheck(p PoolSettings) ExternalCheckSettings {
+	return p.ExternalCheck
+}
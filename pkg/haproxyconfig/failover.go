@@ -0,0 +1,59 @@
+package haproxyconfig
+
+import (
+	"fmt"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// validateFailoverPools checks the failover pools are pools of the port's default backend, each
+// listed once
+func (p PortSettings) validateFailoverPools(port lbapi.PortNode, settings Settings) error {
+	seen := map[string]bool{}
+
+	for _, id := range p.FailoverPools {
+		if seen[id] {
+			return fmt.Errorf("%w %q: listed more than once", ErrFailoverPoolInvalid, id)
+		}
+
+		seen[id] = true
+
+		found := false
+
+		for _, pool := range port.Pools {
+			if pool.ID == id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %q", ErrFailoverPoolNotFound, id)
+		}
+
+		// routed pools have a backend of their own, without the other tiers to fail over from
+		if settings.pool(id).Condition != "" {
+			return fmt.Errorf("%w %q: routed by a condition", ErrFailoverPoolInvalid, id)
+		}
+	}
+
+	return nil
+}
+
+// failoverTier returns the position of a pool in the failover order: 0 for primary pools, which
+// are the first failover pool and those which aren't listed, and higher for backup pools
+func (p PortSettings) failoverTier(id string) int {
+	for i, pool := range p.FailoverPools {
+		if pool == id {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// backupPool returns true when the servers of the pool are backups, only receiving connections
+// when every primary server is down
+func (p PortSettings) backupPool(id string) bool {
+	return p.failoverTier(id) > 0
+}
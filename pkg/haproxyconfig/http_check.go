@@ -0,0 +1,116 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// httpCheckMethods are the methods of http health checks
+var httpCheckMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"POST":    true,
+	"PUT":     true,
+	"DELETE":  true,
+	"OPTIONS": true,
+	"PATCH":   true,
+}
+
+// httpCheckVersions are the http versions of http health checks
+var httpCheckVersions = map[string]bool{
+	"HTTP/1.0": true,
+	"HTTP/1.1": true,
+}
+
+// HTTPCheckSettings is an http health check of a pool's servers, requesting Path with Method and
+// Version, which default to haproxy's OPTIONS and HTTP/1.0, and Headers, e.g. a Host header for
+// servers with virtual hosts
+type HTTPCheckSettings struct {
+	Path    string
+	Method  string
+	Version string
+	Headers map[string]string
+}
+
+// validate checks the path is set when the check is, is a single word, and the method, version
+// and headers can be rendered
+func (h HTTPCheckSettings) validate() error {
+	if h.Path == "" {
+		if h.Method != "" || h.Version != "" || len(h.Headers) > 0 {
+			return ErrHTTPCheckPathRequired
+		}
+
+		return nil
+	}
+
+	if !strings.HasPrefix(h.Path, "/") || strings.ContainsAny(h.Path, " \t\r\n\"'#") {
+		return fmt.Errorf("%w: %q", ErrHTTPCheckPathInvalid, h.Path)
+	}
+
+	if h.Method != "" && !httpCheckMethods[h.Method] {
+		return fmt.Errorf("%w: %q", ErrHTTPCheckMethodInvalid, h.Method)
+	}
+
+	if h.Version != "" && !httpCheckVersions[h.Version] {
+		return fmt.Errorf("%w: %q", ErrHTTPCheckVersionInvalid, h.Version)
+	}
+
+	for name, value := range h.Headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n\":#") {
+			return fmt.Errorf("%w: invalid name %q", ErrHTTPCheckHeaderInvalid, name)
+		}
+
+		if value == "" || strings.ContainsAny(value, "\r\n\"") {
+			return fmt.Errorf("%w %q: invalid value %q", ErrHTTPCheckHeaderInvalid, name, value)
+		}
+	}
+
+	return nil
+}
+
+// rules returns the backend directives of the http check, or nothing when there is none. Headers
+// are sent sorted by name, so the config is stable.
+func (h HTTPCheckSettings) rules() []string {
+	if h.Path == "" {
+		return nil
+	}
+
+	send := "http-check send"
+
+	if h.Method != "" {
+		send += " meth " + h.Method
+	}
+
+	send += " uri " + h.Path
+
+	if h.Version != "" {
+		send += " ver " + h.Version
+	}
+
+	names := make([]string, 0, len(h.Headers))
+
+	for name := range h.Headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := h.Headers[name]
+
+		if strings.ContainsAny(value, " \t") {
+			value = `"` + value + `"`
+		}
+
+		send += " hdr " + name + " " + value
+	}
+
+	return []string{"option httpchk", send}
+}
+
+// httpCheck returns the http check of a pool as it is rendered, so pools sharing a backend can be
+// compared
+func httpCheck(p PoolSettings) string {
+	return strings.Join(p.HTTPCheck.rules(), "\n")
+}
@@ -0,0 +1,38 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCheckRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		check HTTPCheckSettings
+		rules []string
+	}{
+		{"no check", HTTPCheckSettings{}, nil},
+		{"path only", HTTPCheckSettings{Path: "/health"}, []string{"option httpchk", "http-check send uri /health"}},
+		{"headers sorted by name, values with spaces quoted", HTTPCheckSettings{
+			Path:    "/health",
+			Method:  "HEAD",
+			Headers: map[string]string{"X-Check": "1", "Authorization": "Basic dXNlcjpwd2Q=", "Host": "example.com"},
+		}, []string{
+			"option httpchk",
+			`http-check send meth HEAD uri /health hdr Authorization "Basic dXNlcjpwd2Q=" hdr Host example.com hdr X-Check 1`,
+		}},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.NoError(t, tt.check.validate())
+			assert.Equal(t, tt.rules, tt.check.rules())
+		})
+	}
+}
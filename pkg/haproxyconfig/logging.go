@@ -0,0 +1,123 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/types"
+)
+
+// validateLogFormats checks the log formats set for the frontends are well formed
+func (s Settings) validateLogFormats() error {
+	for _, f := range s.logFormats() {
+		if err := validateLogFormat(f.name, f.format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateLogFormat checks a log format is a single line, its quotes are escaped, and each % starts
+// a %% literal, a variable such as %ci, or a %[sample] expression, optionally preceded by %{flags}.
+// It doesn't check the variables and samples exist, which is left to haproxy.
+func validateLogFormat(name, format string) error {
+	if strings.ContainsAny(format, "\r\n") {
+		return fmt.Errorf("%w %q: must be a single line", ErrLogFormatInvalid, name)
+	}
+
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '\\':
+			i++
+		case '"':
+			return fmt.Errorf("%w %q: unescaped quote at offset %d", ErrLogFormatInvalid, name, i)
+		case '%':
+			end, err := logFormatToken(name, format, i)
+			if err != nil {
+				return err
+			}
+
+			i = end
+		}
+	}
+
+	return nil
+}
+
+// logFormatToken returns the offset of the last byte of the token starting with the % at start
+func logFormatToken(name, format string, start int) (int, error) {
+	i := start + 1
+
+	if i < len(format) && format[i] == '%' {
+		return i, nil
+	}
+
+	if i < len(format) && format[i] == '{' {
+		end := strings.IndexByte(format[i:], '}')
+		if end < 0 {
+			return 0, fmt.Errorf("%w %q: unterminated %%{ at offset %d", ErrLogFormatInvalid, name, start)
+		}
+
+		i += end + 1
+	}
+
+	switch {
+	case i < len(format) && format[i] == '[':
+		depth := 0
+
+		for ; i < len(format); i++ {
+			switch format[i] {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			}
+
+			if depth == 0 {
+				return i, nil
+			}
+		}
+
+		return 0, fmt.Errorf("%w %q: unterminated %%[ at offset %d", ErrLogFormatInvalid, name, start)
+	case i < len(format) && unicode.IsLetter(rune(format[i])):
+		for i+1 < len(format) && (unicode.IsLetter(rune(format[i+1])) || unicode.IsDigit(rune(format[i+1]))) {
+			i++
+		}
+
+		return i, nil
+	default:
+		return 0, fmt.Errorf("%w %q: %% without a variable at offset %d", ErrLogFormatInvalid, name, start)
+	}
+}
+
+// logFormat is a log format directive and its format
+type logFormat struct {
+	name   string
+	format string
+}
+
+// logFormats returns the log format directives set for the frontends
+func (s Settings) logFormats() []logFormat {
+	return []logFormat{
+		{"log-format", s.LogFormat},
+		{"log-format-sd", s.LogFormatSD},
+	}
+}
+
+// mergeLogFormats sets the log formats of a frontend, quoted so spaces within them are kept
+func mergeLogFormats(cfg parser.Parser, label string, settings Settings) error {
+	for _, f := range settings.logFormats() {
+		if f.format == "" {
+			continue
+		}
+
+		if err := cfg.Set(parser.Frontends, label, f.name, types.StringC{Value: `"` + f.format + `"`}); err != nil {
+			return newLabelError(f.name, ErrFrontendAttrFailure, err)
+		}
+	}
+
+	return nil
+}
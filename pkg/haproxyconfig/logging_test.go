@@ -0,0 +1,46 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLogFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		valid  bool
+	}{
+		{"empty", "", true},
+		{"variables", "%ci:%cp [%tr] %ft %b/%s %ST %B", true},
+		{"flagged variable", "%{+Q}r", true},
+		{"sample expression", "%[req.hdr(host),lower] %{+Q}[var(txn.id)]", true},
+		{"literal percent", "%ci 100%%", true},
+		{"escaped quotes", `bytes=\"%B\"`, true},
+		{"trailing percent", "%ci 100%", false},
+		{"percent before a space", "% ci", false},
+		{"unterminated sample", "%[req.hdr(host)", false},
+		{"unterminated flags", "%{+Q", false},
+		{"unescaped quote", `bytes="%B"`, false},
+		{"multiple lines", "%ci\n%cp", false},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateLogFormat("log-format", tt.format)
+
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrLogFormatInvalid)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package haproxyconfig
+
+import (
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// LoadBalancerDisabled returns true when the settings of the loadbalancer put it into maintenance
+func (s Settings) LoadBalancerDisabled(id string) bool {
+	for _, lb := range s.LoadBalancers {
+		if lb.ID == id {
+			return lb.Disabled
+		}
+	}
+
+	return false
+}
+
+// maintenance returns a copy of the loadbalancer without pools, and the settings with placeholder
+// backends, so its ports stay bound but answer http requests with a 503 and reject tcp connections
+func maintenance(lb *lbapi.LoadBalancer, settings Settings) (*lbapi.LoadBalancer, Settings) {
+	disabled := *lb
+	disabled.Ports.Edges = make([]lbapi.PortEdges, 0, len(lb.Ports.Edges))
+
+	for _, p := range lb.Ports.Edges {
+		p.Node.Pools = []lbapi.Pool{}
+		disabled.Ports.Edges = append(disabled.Ports.Edges, p)
+	}
+
+	settings.PlaceholderBackends = true
+
+	return &disabled, settings
+}
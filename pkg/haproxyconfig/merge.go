@@ -0,0 +1,739 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/haproxytech/config-parser/v4/params"
+	"github.com/haproxytech/config-parser/v4/types"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// StatsSocketPath is the path of the stats socket added to the global section by GlobalSettings.StatsSocket
+const StatsSocketPath = "/var/run/haproxy.sock"
+
+// backend is a backend section generated for a port, and the pools whose origins are its servers
+type backend struct {
+	label string
+	cond  string
+	pools []lbapi.Pool
+}
+
+// Merge takes a loadbalancer from the lb api, merges it with the base haproxy config and returns the
+// result. The base config is modified in place, so it shouldn't be reused for another loadbalancer.
+func Merge(cfg parser.Parser, lb *lbapi.LoadBalancer, settings Settings) (parser.Parser, error) {
+	if err := validateOrigins(lb); err != nil {
+		return nil, err
+	}
+
+	if err := settings.validateLogFormats(); err != nil {
+		return nil, err
+	}
+
+	if err := validateMetricsPort(lb, settings); err != nil {
+		return nil, err
+	}
+
+	if err := validateMailers(settings.Mailers); err != nil {
+		return nil, err
+	}
+
+	// settings are validated against the pools of a disabled loadbalancer, which it drops, so they
+	// stay valid once it is enabled again
+	enabled := lb
+
+	if settings.LoadBalancerDisabled(lb.ID) {
+		lb, settings = maintenance(lb, settings)
+	}
+
+	if err := mergeGlobal(cfg, settings.Global); err != nil {
+		return nil, err
+	}
+
+	settings, err := mergeDefaultsMode(cfg, lb, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	nbThread := configuredNbThread(cfg)
+
+	if err := settings.Global.validateNbProc(nbThread); err != nil {
+		return nil, err
+	}
+
+	for i, p := range lb.Ports.Edges {
+		if err := settings.validate(enabled.Ports.Edges[i].Node); err != nil {
+			return nil, err
+		}
+
+		if err := settings.port(p.Node.ID).validateThread(nbThread); err != nil {
+			return nil, newLabelError(p.Node.ID, ErrPortSettingsInvalid, err)
+		}
+
+		if err := settings.port(p.Node.ID).validateProcess(settings.Global.NbProc); err != nil {
+			return nil, newLabelError(p.Node.ID, ErrPortSettingsInvalid, err)
+		}
+
+		// settings are validated against all origins, so ones referring to origins in other regions
+		// stay valid for the managers of those regions
+		port := regionPort(p.Node, lb.Location.ID, settings)
+
+		defaultBackend, routed := portBackends(port, settings)
+
+		if err := prepareSection(cfg, parser.Frontends, port.ID, settings.OverwriteBaseSections); err != nil {
+			return nil, err
+		}
+
+		if err := mergeFrontend(cfg, port, settings.port(port.ID), settings, defaultBackend, routed); err != nil {
+			return nil, err
+		}
+
+		for _, b := range append([]backend{defaultBackend}, routed...) {
+			if err := prepareSection(cfg, parser.Backends, b.label, settings.OverwriteBaseSections); err != nil {
+				return nil, err
+			}
+
+			if err := mergeBackend(cfg, b, settings.port(port.ID), settings); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := mergeMetrics(cfg, settings); err != nil {
+		return nil, err
+	}
+
+	if err := mergeMailers(cfg, settings); err != nil {
+		return nil, err
+	}
+
+	return mergeRawDirectives(cfg, lb, settings)
+}
+
+// mergeGlobal sets the tuning directives of the global section, leaving the rest of it unchanged
+func mergeGlobal(cfg parser.Parser, global GlobalSettings) error {
+	directives := []struct {
+		name  string
+		value int64
+	}{
+		{"nbthread", global.NbThread},
+		{"tune.bufsize", global.TuneBufSize},
+		{"tune.ssl.cachesize", global.TuneSSLCacheSize},
+		{"tune.ssl.default-dh-param", global.TuneSSLDefaultDHParam},
+	}
+
+	for _, d := range directives {
+		if d.value <= 0 {
+			continue
+		}
+
+		if err := cfg.Set(parser.Global, parser.GlobalSectionName, d.name, types.Int64C{Value: d.value}); err != nil {
+			return newLabelError(d.name, ErrGlobalAttrFailure, err)
+		}
+	}
+
+	if global.StatsSocket {
+		return mergeStatsSocket(cfg)
+	}
+
+	return nil
+}
+
+// mergeStatsSocket adds an admin level stats socket at StatsSocketPath, unless the base config
+// already has a stats socket there
+func mergeStatsSocket(cfg parser.Parser) error {
+	if data, err := cfg.Get(parser.Global, parser.GlobalSectionName, "stats socket"); err == nil {
+		if sockets, ok := data.([]types.Socket); ok {
+			for _, s := range sockets {
+				if s.Path == StatsSocketPath {
+					return nil
+				}
+			}
+		}
+	}
+
+	socket := types.Socket{
+		Path: StatsSocketPath,
+		Params: []params.BindOption{
+			&params.BindOptionValue{Name: "mode", Value: "660"},
+			&params.BindOptionValue{Name: "level", Value: "admin"},
+		},
+	}
+
+	if err := cfg.Set(parser.Global, parser.GlobalSectionName, "stats socket", socket); err != nil {
+		return newLabelError("stats socket", ErrGlobalAttrFailure, err)
+	}
+
+	return nil
+}
+
+// prepareSection checks a section about to be generated isn't already in the base config, deleting
+// the base config's section first when base sections are overwritten
+func prepareSection(cfg parser.Parser, section parser.Section, label string, overwrite bool) error {
+	labels, err := cfg.SectionsGet(section)
+	if err != nil {
+		// the base config has no sections of this type
+		return nil
+	}
+
+	for _, l := range labels {
+		if l != label {
+			continue
+		}
+
+		if !overwrite {
+			return fmt.Errorf("%w: %s %q", ErrSectionConflict, section, label)
+		}
+
+		if err := cfg.SectionsDelete(section, label); err != nil {
+			return newLabelError(label, ErrSectionConflict, err)
+		}
+	}
+
+	return nil
+}
+
+// configuredNbThread returns the nbthread of the global section, or zero when it isn't set and
+// haproxy picks the number of threads
+func configuredNbThread(cfg parser.Parser) int64 {
+	data, err := cfg.Get(parser.Global, parser.GlobalSectionName, "nbthread")
+	if err != nil {
+		return 0
+	}
+
+	if nbThread, ok := data.(*types.Int64C); ok {
+		return nbThread.Value
+	}
+
+	return 0
+}
+
+// portBackends returns the backends for a port: the default backend, labeled by the port ID,
+// and a backend for each pool routed to by a condition, labeled by the port and pool IDs.
+// The default backend is always returned, so the frontend's fallback exists even when every
+// pool is routed, with its pools in failover order. A port splitting its pools has a backend for
+// each of them instead of the default backend, see splitBackends.
+func portBackends(port lbapi.PortNode, settings Settings) (backend, []backend) {
+	defaultBackend := backend{label: port.ID}
+	routed := []backend{}
+
+	for _, pool := range port.Pools {
+		cond := settings.pool(pool.ID).Condition
+
+		if cond == "" {
+			defaultBackend.pools = append(defaultBackend.pools, pool)
+			continue
+		}
+
+		routed = append(routed, backend{
+			label: fmt.Sprintf("%s-%s", port.ID, pool.ID),
+			cond:  cond,
+			pools: []lbapi.Pool{pool},
+		})
+	}
+
+	// servers of primary pools come before those of the backup pools they fail over to
+	portSettings := settings.port(port.ID)
+
+	sort.SliceStable(defaultBackend.pools, func(i, j int) bool {
+		return portSettings.failoverTier(defaultBackend.pools[i].ID) < portSettings.failoverTier(defaultBackend.pools[j].ID)
+	})
+
+	if portSettings.SplitPools && len(defaultBackend.pools) > 0 {
+		splitDefault, split := splitBackends(port, defaultBackend.pools, portSettings)
+
+		return splitDefault, append(routed, split...)
+	}
+
+	return defaultBackend, routed
+}
+
+// mergeFrontend creates the frontend section for a port. A port with a single backend uses it
+// unconditionally, otherwise conditional use_backend rules fall back to the default backend.
+func mergeFrontend(cfg parser.Parser, port lbapi.PortNode, portSettings PortSettings, settings Settings, defaultBackend backend, routed []backend) error {
+	// create port
+	if err := cfg.SectionsCreate(parser.Frontends, port.ID); err != nil {
+		return newLabelError(port.ID, ErrFrontendSectionLabelFailure, err)
+	}
+
+	if portSettings.Mode != "" {
+		if err := cfg.Set(parser.Frontends, port.ID, "mode", types.StringC{Value: portSettings.Mode}); err != nil {
+			return newAttrError(ErrFrontendModeFailure, err)
+		}
+	}
+
+	for _, path := range bindPaths(port, portSettings, settings.DualStack) {
+		if err := cfg.Insert(parser.Frontends, port.ID, "bind", types.Bind{Path: path}); err != nil {
+			return newAttrError(ErrFrontendBindFailure, err)
+		}
+	}
+
+	frontendOptions := []struct {
+		name    string
+		enabled bool
+	}{
+		{"tcpka", portSettings.TCPKA},
+		{"clitcpka", portSettings.CliTCPKA},
+		{"dontlognull", settings.DontLogNull},
+	}
+
+	for _, o := range frontendOptions {
+		if !o.enabled {
+			continue
+		}
+
+		if err := cfg.Set(parser.Frontends, port.ID, "option "+o.name, types.SimpleOption{}); err != nil {
+			return newLabelError("option "+o.name, ErrFrontendAttrFailure, err)
+		}
+	}
+
+	if err := mergeLogFormats(cfg, port.ID, settings); err != nil {
+		return err
+	}
+
+	// map frontend to backend
+	if len(routed) == 0 {
+		if err := cfg.Set(parser.Frontends, port.ID, "use_backend", types.UseBackend{Name: defaultBackend.label}); err != nil {
+			return newAttrError(ErrUseBackendFailure, err)
+		}
+
+		return nil
+	}
+
+	for _, b := range routed {
+		useBackend := types.UseBackend{Name: b.label, Cond: "if", CondTest: b.cond}
+
+		if err := cfg.Set(parser.Frontends, port.ID, "use_backend", useBackend); err != nil {
+			return newAttrError(ErrUseBackendFailure, err)
+		}
+	}
+
+	if err := cfg.Set(parser.Frontends, port.ID, "default_backend", types.StringC{Value: defaultBackend.label}); err != nil {
+		return newAttrError(ErrDefaultBackendFailure, err)
+	}
+
+	return nil
+}
+
+// mergeBackend creates a backend section, with a server for each origin of its pools
+func mergeBackend(cfg parser.Parser, b backend, portSettings PortSettings, settings Settings) error {
+	// create backend
+	if err := cfg.SectionsCreate(parser.Backends, b.label); err != nil {
+		return newLabelError(b.label, ErrBackendSectionLabelFailure, err)
+	}
+
+	balance, err := backendSetting(settings, b, "balance", func(p PoolSettings) BalanceSettings { return p.Balance })
+	if err != nil {
+		return err
+	}
+
+	if portSettings.Mode != "" {
+		if err := cfg.Set(parser.Backends, b.label, "mode", types.StringC{Value: portSettings.Mode}); err != nil {
+			return newLabelError("mode", ErrBackendAttrFailure, err)
+		}
+	}
+
+	if balance.Algorithm != "" && !(settings.OmitSingleServerBalance && activeServers(b) == 1) {
+		if err := cfg.Set(parser.Backends, b.label, "balance", types.Balance{Algorithm: balance.String()}); err != nil {
+			return newLabelError("balance", ErrBackendAttrFailure, err)
+		}
+	}
+
+	backendOptions := []struct {
+		name string
+		get  func(PoolSettings) bool
+	}{
+		{"abortonclose", func(p PoolSettings) bool { return p.AbortOnClose }},
+		{"allbackups", func(p PoolSettings) bool { return p.AllBackups }},
+		{"log-health-checks", func(p PoolSettings) bool { return p.LogHealthChecks }},
+		{"srvtcpka", func(p PoolSettings) bool { return p.SrvTCPKA }},
+		{"splice-auto", func(p PoolSettings) bool { return p.SpliceAuto }},
+		{"splice-request", func(p PoolSettings) bool { return p.SpliceRequest }},
+		{"splice-response", func(p PoolSettings) bool { return p.SpliceResponse }},
+	}
+
+	for _, o := range backendOptions {
+		enabled, err := backendSetting(settings, b, o.name, o.get)
+		if err != nil {
+			return err
+		}
+
+		if enabled {
+			if err := cfg.Set(parser.Backends, b.label, "option "+o.name, types.SimpleOption{}); err != nil {
+				return newLabelError("option "+o.name, ErrBackendAttrFailure, err)
+			}
+		}
+	}
+
+	if portSettings.TCPKA {
+		if err := cfg.Set(parser.Backends, b.label, "option tcpka", types.SimpleOption{}); err != nil {
+			return newLabelError("option tcpka", ErrBackendAttrFailure, err)
+		}
+	}
+
+	retries, err := backendSetting(settings, b, "retries", func(p PoolSettings) int64 { return p.Retries })
+	if err != nil {
+		return err
+	}
+
+	if retries > 0 {
+		if err := cfg.Set(parser.Backends, b.label, "retries", types.Int64C{Value: retries}); err != nil {
+			return newLabelError("retries", ErrBackendAttrFailure, err)
+		}
+	}
+
+	// retry-on, http-reuse, source, email alerts, persistence, http and external checks aren't
+	// modeled by the parser, they are added with the raw directives
+	if _, err := backendSetting(settings, b, "retry-on", retryOn); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "http-reuse", httpReuse); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "source", sourceAddress); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "email-alert", emailAlert); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "external-check", externalCheck); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "http-check", httpCheck); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "option persist", persist); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "option redispatch", redispatch); err != nil {
+		return err
+	}
+
+	if _, err := backendSetting(settings, b, "force-persist", forcePersist); err != nil {
+		return err
+	}
+
+	if portSettings.TunnelTimeout > 0 {
+		timeout := types.SimpleTimeout{Value: haproxyDuration(portSettings.TunnelTimeout)}
+
+		if err := cfg.Set(parser.Backends, b.label, "timeout tunnel", timeout); err != nil {
+			return newLabelError("timeout tunnel", ErrBackendAttrFailure, err)
+		}
+	}
+
+	queue, err := backendSetting(settings, b, "timeout queue", queueTimeout)
+	if err != nil {
+		return err
+	}
+
+	if queue > 0 {
+		if err := cfg.Set(parser.Backends, b.label, "timeout queue", types.SimpleTimeout{Value: haproxyDuration(queue)}); err != nil {
+			return newLabelError("timeout queue", ErrBackendAttrFailure, err)
+		}
+	}
+
+	for _, t := range checkTimeouts {
+		timeout, err := backendSetting(settings, b, t.name, t.get)
+		if err != nil {
+			return err
+		}
+
+		if timeout > 0 {
+			if err := cfg.Set(parser.Backends, b.label, t.name, types.SimpleTimeout{Value: haproxyDuration(timeout)}); err != nil {
+				return newLabelError(t.name, ErrBackendAttrFailure, err)
+			}
+		}
+	}
+
+	for _, pool := range b.pools {
+		poolSettings := settings.pool(pool.ID)
+
+		if err := resolversExist(cfg, poolSettings.Resolvers); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+
+		if err := poolSettings.validateObserveMode(backendMode(cfg, portSettings)); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+
+		for _, origin := range pool.Origins.Edges {
+			srvAddr := fmt.Sprintf("%s:%d check port %d", origin.Node.Target, origin.Node.PortNumber, origin.Node.PortNumber)
+
+			if net.ParseIP(origin.Node.Target) == nil {
+				srvAddr += poolSettings.resolution()
+			}
+
+			srvAddr += poolSettings.Agent.params()
+			srvAddr += poolSettings.queueParams()
+			srvAddr += poolSettings.slowStartParams()
+			srvAddr += poolSettings.Observe.params()
+
+			if poolSettings.backup(origin.Node.ID) || portSettings.backupPool(pool.ID) {
+				srvAddr += " backup"
+			}
+
+			if !origin.Node.Active {
+				srvAddr += " disabled"
+			}
+
+			srvr := types.Server{
+				Name:    origin.Node.ID,
+				Address: srvAddr,
+			}
+
+			if err := cfg.Set(parser.Backends, b.label, "server", srvr); err != nil {
+				return newLabelError(b.label, ErrBackendServerFailure, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sourceAddress returns the source address of a pool, for comparing pools sharing a backend
+func sourceAddress(p PoolSettings) string {
+	return p.SourceAddress
+}
+
+// activeServers returns the number of active origins of the backend's pools
+func activeServers(b backend) int {
+	active := 0
+
+	for _, pool := range b.pools {
+		for _, origin := range pool.Origins.Edges {
+			if origin.Node.Active {
+				active++
+			}
+		}
+	}
+
+	return active
+}
+
+// resolversExist checks the named resolvers section is in the config, when one is named
+func resolversExist(cfg parser.Parser, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	resolvers, err := cfg.SectionsGet(parser.Resolvers)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrResolversNotFound, name)
+	}
+
+	for _, r := range resolvers {
+		if r == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", ErrResolversNotFound, name)
+}
+
+// bindPaths returns the addresses the frontend for a port binds to, one for each address family
+// of a tcp bind
+func bindPaths(port lbapi.PortNode, settings PortSettings, dualStack bool) []string {
+	if settings.SocketPath != "" {
+		return []string{bindPath("unix@"+settings.SocketPath, settings)}
+	}
+
+	families := settings.addressFamilies(dualStack)
+	paths := make([]string, 0, len(families))
+
+	for _, family := range families {
+		bind := fmt.Sprintf("%s@:%d", family, port.Number)
+
+		if settings.Interface != "" {
+			bind += " interface " + settings.Interface
+		}
+
+		if settings.Namespace != "" {
+			bind += " namespace " + settings.Namespace
+		}
+
+		paths = append(paths, bindPath(bind, settings))
+	}
+
+	return paths
+}
+
+// bindPath returns a bind of the address with the thread, process and tls options of the port
+func bindPath(address string, settings PortSettings) string {
+	bind := address
+
+	if settings.Thread != "" {
+		bind += " thread " + settings.Thread
+	}
+
+	if settings.Process != "" {
+		bind += " process " + settings.Process
+	}
+
+	bind += settings.tlsBind()
+
+	return bind
+}
+
+// mergeRawDirectives appends the directives the parser doesn't model, and the raw directives of
+// ports and pools, to the end of their sections. The parser has no way to insert unmodeled lines,
+// so they are added to the rendered config, which is then parsed again.
+func mergeRawDirectives(cfg parser.Parser, lb *lbapi.LoadBalancer, settings Settings) (parser.Parser, error) {
+	raw := map[string][]string{}
+	externalChecks := false
+
+	for _, p := range lb.Ports.Edges {
+		frontend := "frontend " + p.Node.ID
+
+		if settings.Descriptions {
+			raw[frontend] = append(raw[frontend], description(p.Node.Name)...)
+		}
+
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).expectProxyRule()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).connRateLimitRules()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).earlyDataRules()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).splitRule(backendMode(cfg, settings.port(p.Node.ID)), p.Node, settings)...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).UniqueID.rules()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).responseHeaderRules()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).errorFileRules()...)
+		raw[frontend] = append(raw[frontend], settings.port(p.Node.ID).RawDirectives...)
+
+		defaultBackend, routed := portBackends(p.Node, settings)
+
+		for _, b := range append([]backend{defaultBackend}, routed...) {
+			section := "backend " + b.label
+
+			if settings.Descriptions {
+				raw[section] = append(raw[section], description(backendName(p.Node, b))...)
+			}
+
+			raw[section] = append(raw[section], retryOnRule(cfg, b, settings.port(p.Node.ID), settings)...)
+			raw[section] = append(raw[section], httpReuseRule(cfg, b, settings.port(p.Node.ID), settings)...)
+			raw[section] = append(raw[section], placeholderRule(backendMode(cfg, settings.port(p.Node.ID)), b, settings)...)
+			raw[section] = append(raw[section], persistenceRules(b, settings)...)
+
+			// conflicting source addresses are returned as an error by mergeBackend
+			if source, err := backendSetting(settings, b, "source", sourceAddress); err == nil && source != "" {
+				raw[section] = append(raw[section], "source "+source)
+			}
+
+			// conflicting email alerts are returned as an error by mergeBackend
+			if alert, err := backendSetting(settings, b, "email-alert", emailAlert); err == nil {
+				raw[section] = append(raw[section], alert.rules()...)
+			}
+
+			// conflicting http checks are returned as an error by mergeBackend
+			if check, err := backendSetting(settings, b, "http-check", httpCheck); err == nil && check != "" {
+				raw[section] = append(raw[section], strings.Split(check, "\n")...)
+			}
+
+			// conflicting external checks are returned as an error by mergeBackend
+			if check, err := backendSetting(settings, b, "external-check", externalCheck); err == nil && check.Command != "" {
+				raw[section] = append(raw[section], check.rules()...)
+				externalChecks = true
+			}
+
+			for _, pool := range b.pools {
+				raw[section] = append(raw[section], settings.pool(pool.ID).RawDirectives...)
+			}
+		}
+	}
+
+	raw["frontend "+MetricsFrontend] = append(raw["frontend "+MetricsFrontend], settings.metricsRules()...)
+	raw["mailers "+MailersSection] = append(raw["mailers "+MailersSection], settings.mailerRules()...)
+
+	raw["global"] = append(raw["global"], settings.Global.nbProcRules()...)
+
+	if externalChecks {
+		raw["global"] = append(raw["global"], externalCheckGlobals(cfg)...)
+	}
+
+	rendered := cfg.String()
+
+	withRaw := appendRawDirectives(rendered, raw)
+	if withRaw == rendered {
+		return cfg, nil
+	}
+
+	rawCfg, err := parser.New(options.Reader(strings.NewReader(withRaw)), options.NoNamedDefaultsFrom)
+	if err != nil {
+		return nil, newAttrError(ErrRawDirectivesFailure, err)
+	}
+
+	return rawCfg, nil
+}
+
+// backendName returns the human friendly name of a backend: the port name for the backend labeled
+// by the port ID, and the pool name for the backend of a pool, routed or split
+func backendName(port lbapi.PortNode, b backend) string {
+	if b.label == port.ID || len(b.pools) == 0 {
+		return port.Name
+	}
+
+	return b.pools[0].Name
+}
+
+// description returns the description directive for a section name, collapsed to a single line,
+// or nothing when the name is empty
+func description(name string) []string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil
+	}
+
+	return []string{"description " + name}
+}
+
+// appendRawDirectives appends directives to the end of the sections they are keyed by, where the
+// key is the section header line, e.g. "backend loadprt-test"
+func appendRawDirectives(cfg string, raw map[string][]string) string {
+	lines := strings.Split(cfg, "\n")
+	out := make([]string, 0, len(lines))
+	section := ""
+
+	flush := func() {
+		if len(raw[section]) == 0 {
+			return
+		}
+
+		// keep the blank lines separating sections after the appended directives
+		end := len(out)
+		for end > 0 && strings.TrimSpace(out[end-1]) == "" {
+			end--
+		}
+
+		tail := append([]string{}, out[end:]...)
+		out = out[:end]
+
+		for _, d := range raw[section] {
+			out = append(out, "  "+strings.TrimSpace(d))
+		}
+
+		out = append(out, tail...)
+	}
+
+	for _, line := range lines {
+		if line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "#") {
+			flush()
+
+			section = strings.TrimSpace(line)
+		}
+
+		out = append(out, line)
+	}
+
+	flush()
+
+	return strings.Join(out, "\n")
+}
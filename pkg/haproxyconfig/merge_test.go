@@ -0,0 +1,1223 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/haproxytech/config-parser/v4/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+const (
+	testDataBaseDir = "testdata"
+	testBaseCfgPath = "../../.devcontainer/config/haproxy.cfg"
+)
+
+func TestMerge(t *testing.T) {
+	MergeConfigTests := []struct {
+		name                string
+		testInput           lbapi.LoadBalancer
+		settings            Settings
+		expectedCfgFilename string
+	}{
+		{"ssh service one pool", mergeTestData1, Settings{}, "lb-ex-1-exp.cfg"},
+		{"ssh service two pools", mergeTestData2, Settings{}, "lb-ex-2-exp.cfg"},
+		{"http and https", mergeTestData3, Settings{}, "lb-ex-3-exp.cfg"},
+		{"ssh service with tunnel timeout", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", TunnelTimeout: 2 * time.Hour}},
+		}, "lb-ex-4-exp.cfg"},
+		{"ssh service bound to unix socket", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SocketPath: "/var/run/haproxy/app.sock"}},
+		}, "lb-ex-5-exp.cfg"},
+		{"ssh service balanced by url param", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "url_param", Param: "sessionid"}}},
+		}, "lb-ex-6-exp.cfg"},
+		{"two pools balanced by header", mergeTestData2, Settings{
+			Pools: []PoolSettings{
+				{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "hdr", Param: "Host"}},
+				{ID: "loadpol-test2", Balance: BalanceSettings{Algorithm: "hdr", Param: "Host"}},
+			},
+		}, "lb-ex-7-exp.cfg"},
+		{"two pools with one routed by condition", mergeTestData2, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}},
+		}, "lb-ex-8-exp.cfg"},
+		{"ssh service with abortonclose", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", AbortOnClose: true}},
+		}, "lb-ex-9-exp.cfg"},
+		{"ssh service with a backup using allbackups", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Backups: []string{"loadogn-test2"}, AllBackups: true}},
+		}, "lb-ex-10-exp.cfg"},
+		{"ssh service bound to an interface", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Interface: "eth1"}},
+		}, "lb-ex-11-exp.cfg"},
+		{"ssh service logging health checks", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", LogHealthChecks: true}},
+		}, "lb-ex-12-exp.cfg"},
+		{"ssh service expecting proxy protocol from a cidr", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ExpectProxy: true, ExpectProxySources: []string{"10.0.0.0/8"}}},
+		}, "lb-ex-13-exp.cfg"},
+		{"http service with response headers", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http", ResponseHeaders: []HeaderRule{
+				{Action: "set-header", Name: "Strict-Transport-Security", Value: "max-age=31536000; includeSubDomains"},
+				{Action: "set-header", Name: "X-Frame-Options", Value: "DENY"},
+				{Action: "del-header", Name: "Server"},
+			}}},
+		}, "lb-ex-14-exp.cfg"},
+		{"ssh service bound to a thread range", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Thread: "1-2"}},
+		}, "lb-ex-15-exp.cfg"},
+		{"ssh service with tcp keepalives", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", TCPKA: true}},
+		}, "lb-ex-16-exp.cfg"},
+		{"ssh service with client tcp keepalives", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CliTCPKA: true}},
+		}, "lb-ex-17-exp.cfg"},
+		{"ssh service with server tcp keepalives", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SrvTCPKA: true}},
+		}, "lb-ex-18-exp.cfg"},
+		{"ssh service without logging null connections", mergeTestData1, Settings{
+			DontLogNull: true,
+		}, "lb-ex-19-exp.cfg"},
+		{"ssh service with custom log formats", mergeTestData1, Settings{
+			LogFormat:   "%ci:%cp [%tr] %ft %b/%s %TR/%Tw/%Tc/%Tr/%Ta %ST %B",
+			LogFormatSD: `[exampleSDID@1234 bytes=\"%B\" status=\"%ST\"]`,
+		}, "lb-ex-20-exp.cfg"},
+		{"ssh service with an agent check", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Agent: AgentSettings{Check: true, Addr: "10.0.0.1", Port: 9999, Inter: 5 * time.Second}}},
+		}, "lb-ex-21-exp.cfg"},
+		{"ssh service with a metrics frontend", mergeTestData1, Settings{
+			MetricsPort: 9101,
+		}, "lb-ex-22-exp.cfg"},
+		{"http service retrying on failures", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http"}},
+			Pools: []PoolSettings{{ID: "loadpol-test", Retries: 2, RetryOn: []string{"conn-failure", "empty-response", "503"}}},
+		}, "lb-ex-23-exp.cfg"},
+		{"tcp port without pools with a placeholder backend", mergeTestDataNoPools, Settings{
+			PlaceholderBackends: true,
+		}, "lb-ex-24-exp.cfg"},
+		{"http port without pools with a placeholder backend", mergeTestDataNoPools, Settings{
+			PlaceholderBackends: true,
+			Ports:               []PortSettings{{ID: "loadprt-test", Mode: "http"}},
+		}, "lb-ex-25-exp.cfg"},
+		{"disabled ssh service in maintenance", mergeTestData1, Settings{
+			LoadBalancers: []LoadBalancerSettings{{ID: "loadbal-test", Disabled: true}},
+		}, "lb-ex-24-exp.cfg"},
+		{"enabled ssh service", mergeTestData1, Settings{
+			LoadBalancers: []LoadBalancerSettings{{ID: "loadbal-test"}, {ID: "loadbal-other", Disabled: true}},
+		}, "lb-ex-1-exp.cfg"},
+		{"placeholder backends leave ports with pools unchanged", mergeTestData1, Settings{
+			PlaceholderBackends: true,
+		}, "lb-ex-1-exp.cfg"},
+		{"ssh service with an external check", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", ExternalCheck: ExternalCheckSettings{Command: "/usr/local/bin/check-ssh", Path: "/usr/bin:/bin"}}},
+		}, "lb-ex-26-exp.cfg"},
+		{"ssh service terminating tls with a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt"}},
+		}, "lb-ex-27-exp.cfg"},
+		{"http service with custom error pages", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http", ErrorFiles: []ErrorFile{
+				{Status: 500, Path: "/etc/haproxy/errors/500.http"},
+				{Status: 503, Path: "/etc/haproxy/errors/503.http"},
+			}}},
+		}, "lb-ex-28-exp.cfg"},
+		{"ssh service connecting to servers from a source address", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SourceAddress: "10.0.0.5"}},
+		}, "lb-ex-29-exp.cfg"},
+		{"http service with an http check of a virtual host", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http"}},
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPCheck: HTTPCheckSettings{
+				Path:    "/health",
+				Method:  "GET",
+				Version: "HTTP/1.1",
+				Headers: map[string]string{"Host": "example.com"},
+			}}},
+		}, "lb-ex-30-exp.cfg"},
+		{"ssh service behind proxies with a connection rate limit", mergeTestData1, Settings{
+			Ports: []PortSettings{{
+				ID:                 "loadprt-test",
+				ExpectProxy:        true,
+				ExpectProxySources: []string{"10.0.0.0/8"},
+				ConnRateLimit:      ConnRateLimitSettings{Limit: 20, Period: time.Minute},
+			}},
+		}, "lb-ex-31-exp.cfg"},
+		{"ssh service failing over to a backup pool", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", FailoverPools: []string{"loadpol-test2", "loadpol-test"}}},
+			Pools: []PoolSettings{{ID: "loadpol-test", AllBackups: true}, {ID: "loadpol-test2", AllBackups: true}},
+		}, "lb-ex-32-exp.cfg"},
+		{"ssh service with queue limits", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", MaxQueue: 50, QueueTimeout: 5 * time.Second}},
+		}, "lb-ex-33-exp.cfg"},
+		{"ssh service redispatching on the last retry and persisting to down servers", mergeTestData1, Settings{
+			Pools: []PoolSettings{{
+				ID:           "loadpol-test",
+				Retries:      3,
+				Redispatch:   RedispatchSettings{Enabled: true, Interval: -1},
+				Persist:      true,
+				ForcePersist: "{ src 10.0.0.0/8 }",
+			}},
+		}, "lb-ex-34-exp.cfg"},
+		{"ssh service terminating tls with alpn and a minimum version", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{
+				ALPN:       []string{"h2", "http/1.1"},
+				MinVersion: "TLSv1.2",
+			}}},
+		}, "lb-ex-35-exp.cfg"},
+		{"bulk transfer service splicing automatically", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SpliceAuto: true}},
+		}, "lb-ex-36-exp.cfg"},
+		{"bulk transfer service splicing requests and responses", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SpliceRequest: true, SpliceResponse: true}},
+		}, "lb-ex-37-exp.cfg"},
+		{"https service accepting early data on safe paths", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttps", Mode: "http", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{
+				ALPN:                 []string{"h2", "http/1.1"},
+				EarlyData:            true,
+				WaitForHandshake:     true,
+				EarlyDataRejectPaths: []string{"/admin", "/api/payments"},
+			}}},
+		}, "lb-ex-38-exp.cfg"},
+		{"http service marking servers down on observed errors", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http"}},
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{Mode: "layer7", ErrorLimit: 10, OnError: "mark-down"}}},
+		}, "lb-ex-39-exp.cfg"},
+		{"ssh service ramping up servers with slowstart", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SlowStart: 30 * time.Second}},
+		}, "lb-ex-40-exp.cfg"},
+		{"http service reusing server connections safely", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http"}},
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPReuse: "safe"}},
+		}, "lb-ex-41-exp.cfg"},
+		{"http service with routed pools reusing server connections differently", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http"}},
+			Pools: []PoolSettings{
+				{ID: "loadpol-test", HTTPReuse: "aggressive"},
+				{ID: "loadpol-test2", HTTPReuse: "never", Condition: "{ src 10.0.0.0/8 }"},
+			},
+		}, "lb-ex-42-exp.cfg"},
+		{"tcp service ignores http-reuse", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPReuse: "always"}},
+		}, "lb-ex-1-exp.cfg"},
+		{"ssh service with health check and connect timeouts", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", CheckTimeout: 10 * time.Second, ConnectTimeout: 3 * time.Second}},
+		}, "lb-ex-43-exp.cfg"},
+		{"two pools split evenly", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true}},
+		}, "lb-ex-44-exp.cfg"},
+		{"http service with a canary pool split by weight", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", SplitPools: true, SplitWeights: map[string]int64{
+				"loadpol-test":  90,
+				"loadpol-test2": 10,
+			}}},
+		}, "lb-ex-45-exp.cfg"},
+		{"http service forwarding unique request ids", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http", UniqueID: UniqueIDSettings{
+				Format: `%{+X}o\ %ci:%cp_%fi:%fp_%Ts_%rt:%pid`,
+				Header: "X-Request-ID",
+			}}},
+		}, "lb-ex-46-exp.cfg"},
+		{"https service selecting certificates from a directory by sni", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttps", Mode: "http", CrtDir: "/etc/haproxy/certs", TLS: TLSSettings{
+				ALPN: []string{"h2", "http/1.1"},
+			}}},
+		}, "lb-ex-47-exp.cfg"},
+		{"ssh service bound to odd processes", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: 4},
+			Ports:  []PortSettings{{ID: "loadprt-test", Process: "odd"}},
+		}, "lb-ex-48-exp.cfg"},
+		{"ssh service on every process", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: 2},
+		}, "lb-ex-49-exp.cfg"},
+		{"ssh service emailing server state changes", mergeTestData1, Settings{
+			Mailers: []Mailer{
+				{Name: "smtp1", Address: "10.0.0.25:25"},
+				{Name: "smtp2", Address: "smtp.example.com:587"},
+			},
+			Pools: []PoolSettings{{ID: "loadpol-test", EmailAlert: EmailAlertSettings{
+				From:  "haproxy@example.com",
+				To:    "oncall@example.com",
+				Level: "notice",
+			}}},
+		}, "lb-ex-50-exp.cfg"},
+		{"ssh service bound on ipv4 and ipv6", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", AddressFamily: "dual", Interface: "eth1", TCPKA: true}},
+		}, "lb-ex-51-exp.cfg"},
+		{"dual-stack http and https with http bound on ipv4", mergeTestData3, Settings{
+			DualStack: true,
+			Ports:     []PortSettings{{ID: "loadprt-testhttp", AddressFamily: "ipv4"}},
+		}, "lb-ex-52-exp.cfg"},
+	}
+
+	for _, tt := range MergeConfigTests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := parser.New(options.Path("../../.devcontainer/config/haproxy.cfg"), options.NoNamedDefaultsFrom)
+			require.Nil(t, err)
+
+			newCfg, err := Merge(cfg, &tt.testInput, tt.settings)
+			assert.Nil(t, err)
+
+			t.Log("Generated config ===> ", Render(newCfg))
+
+			expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, tt.expectedCfgFilename))
+			require.Nil(t, err)
+
+			assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(Render(newCfg)))
+		})
+	}
+}
+
+func TestMergeInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		testInput lbapi.LoadBalancer
+		settings  Settings
+		expErr    error
+	}{
+		{"relative socket path", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SocketPath: "app.sock"}},
+		}, ErrSocketPathNotAbsolute},
+		{"unsupported balance algorithm", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "fastest"}}},
+		}, ErrBalanceAlgorithmInvalid},
+		{"balance param for algorithm without one", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "roundrobin", Param: "sessionid"}}},
+		}, ErrBalanceParamNotSupported},
+		{"balance algorithm missing param", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "url_param"}}},
+		}, ErrBalanceParamRequired},
+		{"pools sharing a backend disagree on balance", mergeTestData2, Settings{
+			Pools: []PoolSettings{
+				{ID: "loadpol-test", Balance: BalanceSettings{Algorithm: "roundrobin"}},
+				{ID: "loadpol-test2", Balance: BalanceSettings{Algorithm: "leastconn"}},
+			},
+		}, ErrPoolSettingsConflict},
+		{"raw directives not allowed", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", RawDirectives: []string{"hash-type consistent"}}},
+		}, ErrRawDirectivesNotAllowed},
+		{"log format with a stray percent", mergeTestData1, Settings{
+			LogFormat: "%ci:%cp 100%",
+		}, ErrLogFormatInvalid},
+		{"agent check without a port", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Agent: AgentSettings{Check: true}}},
+		}, ErrAgentPortRequired},
+		{"agent port without agent check", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Agent: AgentSettings{Port: 9999}}},
+		}, ErrAgentCheckRequired},
+		{"metrics port used by a loadbalancer port", mergeTestData1, Settings{
+			MetricsPort: 22,
+		}, ErrMetricsPortConflict},
+		{"unsupported retry-on condition", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", RetryOn: []string{"conn-failure", "418"}}},
+		}, ErrRetryOnInvalid},
+		{"unsupported http-reuse mode", mergeTestData3, Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttp", Mode: "http"}},
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPReuse: "sometimes"}},
+		}, ErrHTTPReuseInvalid},
+		{"source address not an ip address", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SourceAddress: "10.0.0.0/8"}},
+		}, ErrSourceAddressInvalid},
+		{"pools sharing a backend disagree on source address", mergeTestData2, Settings{
+			Pools: []PoolSettings{
+				{ID: "loadpol-test", SourceAddress: "10.0.0.5"},
+				{ID: "loadpol-test2", SourceAddress: "10.0.0.6"},
+			},
+		}, ErrPoolSettingsConflict},
+		{"unsupported http check method", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPCheck: HTTPCheckSettings{Path: "/health", Method: "FETCH"}}},
+		}, ErrHTTPCheckMethodInvalid},
+		{"http check headers without a path", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPCheck: HTTPCheckSettings{Headers: map[string]string{"Host": "example.com"}}}},
+		}, ErrHTTPCheckPathRequired},
+		{"http check header value with a quote", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", HTTPCheck: HTTPCheckSettings{Path: "/health", Headers: map[string]string{"Host": `example.com"`}}}},
+		}, ErrHTTPCheckHeaderInvalid},
+		{"external check command with arguments", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", ExternalCheck: ExternalCheckSettings{Command: "/usr/local/bin/check-ssh --fast", Path: "/usr/bin"}}},
+		}, ErrExternalCheckCommandInvalid},
+		{"external check without a path", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", ExternalCheck: ExternalCheckSettings{Command: "/usr/local/bin/check-ssh"}}},
+		}, ErrExternalCheckPathRequired},
+		{"multi-line raw directive", mergeTestData1, Settings{
+			AllowRawDirectives: true,
+			Pools:              []PoolSettings{{ID: "loadpol-test", RawDirectives: []string{"hash-type consistent\nbackend injected"}}},
+		}, ErrRawDirectiveInvalid},
+		{"backup not an origin of the pool", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Backups: []string{"loadogn-missing"}}},
+		}, ErrBackupOriginNotFound},
+		{"allbackups without backups", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", AllBackups: true}},
+		}, ErrAllBackupsWithoutBackups},
+		{"negative maxqueue", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", MaxQueue: -1}},
+		}, ErrQueueLimitInvalid},
+		{"queue timeout shorter than a millisecond", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", QueueTimeout: time.Microsecond}},
+		}, ErrQueueLimitInvalid},
+		{"pools sharing a backend disagree on queue timeout", mergeTestData2, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", QueueTimeout: 5 * time.Second}, {ID: "loadpol-test2", QueueTimeout: time.Second}},
+		}, ErrPoolSettingsConflict},
+		{"automatic and explicit splicing", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SpliceAuto: true, SpliceResponse: true}},
+		}, ErrSpliceExclusive},
+		{"redispatch interval without redispatch", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Redispatch: RedispatchSettings{Interval: 2}}},
+		}, ErrRedispatchInvalid},
+		{"redispatch interval beyond the retries", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Retries: 2, Redispatch: RedispatchSettings{Enabled: true, Interval: -3}}},
+		}, ErrRedispatchInvalid},
+		{"multi-line force-persist condition", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", ForcePersist: "{ src 10.0.0.0/8 }\nlisten x"}},
+		}, ErrForcePersistInvalid},
+		{"pools sharing a backend disagree on redispatch", mergeTestData2, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Redispatch: RedispatchSettings{Enabled: true}}, {ID: "loadpol-test2", Redispatch: RedispatchSettings{Enabled: true, Interval: 2}}},
+		}, ErrPoolSettingsConflict},
+		{"failover pool not a pool of the port", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", FailoverPools: []string{"loadpol-test", "loadpol-missing"}}},
+		}, ErrFailoverPoolNotFound},
+		{"failover pool listed twice", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", FailoverPools: []string{"loadpol-test", "loadpol-test2", "loadpol-test"}}},
+		}, ErrFailoverPoolInvalid},
+		{"failover pool routed by a condition", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", FailoverPools: []string{"loadpol-test", "loadpol-test2"}}},
+			Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}},
+		}, ErrFailoverPoolInvalid},
+		{"interface on a unix socket bind", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SocketPath: "/var/run/haproxy/app.sock", Interface: "eth1"}},
+		}, ErrBindScopeWithSocket},
+		{"interface name too long", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Interface: "averyveryverylongname"}},
+		}, ErrInterfaceInvalid},
+		{"namespace with whitespace", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Namespace: "blue green"}},
+		}, ErrNamespaceInvalid},
+		{"unknown address family", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", AddressFamily: "ipv5"}},
+		}, ErrAddressFamilyInvalid},
+		{"address family on a unix socket bind", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SocketPath: "/var/run/haproxy/app.sock", AddressFamily: "dual"}},
+		}, ErrAddressFamilyInvalid},
+		{"expect-proxy sources without expect-proxy", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ExpectProxySources: []string{"10.0.0.0/8"}}},
+		}, ErrExpectProxySourcesUnused},
+		{"expect-proxy source not a cidr", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ExpectProxy: true, ExpectProxySources: []string{"10.0.0.1"}}},
+		}, ErrExpectProxySourceInvalid},
+		{"unsupported mode", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "health"}},
+		}, ErrModeInvalid},
+		{"malformed thread range", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Thread: "1-two"}},
+		}, ErrThreadInvalid},
+		{"descending thread range", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Thread: "4-1"}},
+		}, ErrThreadInvalid},
+		{"thread range beyond nbthread", mergeTestData1, Settings{
+			Global: GlobalSettings{NbThread: 2},
+			Ports:  []PortSettings{{ID: "loadprt-test", Thread: "1-4"}},
+		}, ErrThreadInvalid},
+		{"email alerts without mailers", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", EmailAlert: EmailAlertSettings{From: "haproxy@example.com", To: "oncall@example.com"}}},
+		}, ErrEmailAlertRequiresMailers},
+		{"email alerts without a recipient", mergeTestData1, Settings{
+			Mailers: []Mailer{{Name: "smtp1", Address: "10.0.0.25:25"}},
+			Pools:   []PoolSettings{{ID: "loadpol-test", EmailAlert: EmailAlertSettings{From: "haproxy@example.com"}}},
+		}, ErrEmailAlertInvalid},
+		{"email alerts to an invalid address", mergeTestData1, Settings{
+			Mailers: []Mailer{{Name: "smtp1", Address: "10.0.0.25:25"}},
+			Pools:   []PoolSettings{{ID: "loadpol-test", EmailAlert: EmailAlertSettings{From: "haproxy@example.com", To: "on call@example.com"}}},
+		}, ErrEmailAlertInvalid},
+		{"email alerts at an unknown level", mergeTestData1, Settings{
+			Mailers: []Mailer{{Name: "smtp1", Address: "10.0.0.25:25"}},
+			Pools:   []PoolSettings{{ID: "loadpol-test", EmailAlert: EmailAlertSettings{From: "haproxy@example.com", To: "oncall@example.com", Level: "error"}}},
+		}, ErrEmailAlertInvalid},
+		{"mailer without a port", mergeTestData1, Settings{
+			Mailers: []Mailer{{Name: "smtp1", Address: "10.0.0.25"}},
+		}, ErrMailerInvalid},
+		{"mailers with the same name", mergeTestData1, Settings{
+			Mailers: []Mailer{{Name: "smtp1", Address: "10.0.0.25:25"}, {Name: "smtp1", Address: "10.0.0.26:25"}},
+		}, ErrMailerInvalid},
+		{"negative nbproc", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: -1},
+		}, ErrNbProcInvalid},
+		{"several processes with several threads", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: 2, NbThread: 4},
+		}, ErrNbProcInvalid},
+		{"bind process without nbproc", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Process: "1"}},
+		}, ErrProcessInvalid},
+		{"malformed bind process", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: 2},
+			Ports:  []PortSettings{{ID: "loadprt-test", Process: "first"}},
+		}, ErrProcessInvalid},
+		{"bind process beyond nbproc", mergeTestData1, Settings{
+			Global: GlobalSettings{NbProc: 2},
+			Ports:  []PortSettings{{ID: "loadprt-test", Process: "2-4"}},
+		}, ErrProcessInvalid},
+		{"response headers on a tcp port", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ResponseHeaders: []HeaderRule{{Action: "del-header", Name: "Server"}}}},
+		}, ErrResponseHeadersRequireHTTP},
+		{"unsupported header action", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ResponseHeaders: []HeaderRule{{Action: "add-header", Name: "X-Test", Value: "1"}}}},
+		}, ErrHeaderActionInvalid},
+		{"set-header without a value", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ResponseHeaders: []HeaderRule{{Action: "set-header", Name: "X-Frame-Options"}}}},
+		}, ErrHeaderRuleInvalid},
+		{"header value with a quote", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ResponseHeaders: []HeaderRule{{Action: "set-header", Name: "X-Test", Value: `a" b`}}}},
+		}, ErrHeaderRuleInvalid},
+		{"error files on a tcp port", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ErrorFiles: []ErrorFile{{Status: 503, Path: "/etc/haproxy/errors/503.http"}}}},
+		}, ErrErrorFilesRequireHTTP},
+		{"error file for a status haproxy doesn't generate", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ErrorFiles: []ErrorFile{{Status: 418, Path: "/etc/haproxy/errors/418.http"}}}},
+		}, ErrErrorFileStatusInvalid},
+		{"error file set twice for a status", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ErrorFiles: []ErrorFile{
+				{Status: 503, Path: "/etc/haproxy/errors/503.http"},
+				{Status: 503, Path: "/etc/haproxy/errors/busy.http"},
+			}}},
+		}, ErrErrorFileStatusInvalid},
+		{"relative error file", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ErrorFiles: []ErrorFile{{Status: 503, Path: "errors/503.http"}}}},
+		}, ErrErrorFilePathInvalid},
+		{"connection rate limit on an http port", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", ConnRateLimit: ConnRateLimitSettings{Limit: 20}}},
+		}, ErrConnRateLimitRequiresTCP},
+		{"connection rate period without a limit", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ConnRateLimit: ConnRateLimitSettings{Period: time.Minute}}},
+		}, ErrConnRateLimitInvalid},
+		{"connection rate period shorter than a millisecond", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", ConnRateLimit: ConnRateLimitSettings{Limit: 20, Period: time.Microsecond}}},
+		}, ErrConnRateLimitInvalid},
+		{"certs without a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Certs: []CertRef{{Cert: "/etc/haproxy/certs/example.pem"}}}},
+		}, ErrCrtListRequired},
+		{"relative crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "crt-list.txt"}},
+		}, ErrCrtListInvalid},
+		{"relative cert without a cert dir", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", Certs: []CertRef{{Cert: "example.pem"}}}},
+		}, ErrCertDirRequired},
+		{"relative crt dir", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtDir: "certs/"}},
+		}, ErrCrtDirInvalid},
+		{"root crt dir", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtDir: "/"}},
+		}, ErrCrtDirInvalid},
+		{"crt dir with a space", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtDir: "/etc/haproxy/my certs/"}},
+		}, ErrCrtDirInvalid},
+		{"crt dir with a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", CrtDir: "/etc/haproxy/certs/"}},
+		}, ErrCrtDirConflict},
+		{"tls options without a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", TLS: TLSSettings{MinVersion: "TLSv1.2"}}},
+		}, ErrTLSOptionsRequireTLS},
+		{"alpn protocol with a comma", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{ALPN: []string{"h2,http/1.1"}}}},
+		}, ErrTLSOptionInvalid},
+		{"ciphers with a space", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{Ciphers: "ECDHE+AESGCM !aNULL"}}},
+		}, ErrTLSOptionInvalid},
+		{"unsupported tls version", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{MinVersion: "TLS1.2"}}},
+		}, ErrTLSVersionInvalid},
+		{"minimum tls version above the maximum", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{MinVersion: "TLSv1.3", MaxVersion: "TLSv1.2"}}},
+		}, ErrTLSVersionInvalid},
+		{"early data without a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", TLS: TLSSettings{EarlyData: true}}},
+		}, ErrTLSOptionsRequireTLS},
+		{"waiting for the handshake without a crt-list", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", TLS: TLSSettings{WaitForHandshake: true}}},
+		}, ErrTLSOptionsRequireTLS},
+		{"waiting for the handshake in tcp mode", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{EarlyData: true, WaitForHandshake: true}}},
+		}, ErrEarlyDataRulesRequireHTTP},
+		{"early data reject path in tcp mode", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{EarlyDataRejectPaths: []string{"/admin"}}}},
+		}, ErrEarlyDataRulesRequireHTTP},
+		{"relative early data reject path", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{EarlyDataRejectPaths: []string{"admin"}}}},
+		}, ErrTLSOptionInvalid},
+		{"early data reject path closing the condition", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{EarlyDataRejectPaths: []string{"/admin }"}}}},
+		}, ErrTLSOptionInvalid},
+		{"unsupported observe mode", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{Mode: "layer3"}}},
+		}, ErrObserveInvalid},
+		{"unsupported on-error action", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{Mode: "layer4", OnError: "restart"}}},
+		}, ErrObserveInvalid},
+		{"negative error limit", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{Mode: "layer4", ErrorLimit: -1}}},
+		}, ErrObserveInvalid},
+		{"on-error action without an observe mode", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{OnError: "mark-down"}}},
+		}, ErrObserveInvalid},
+		{"observing layer7 in tcp mode", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", Observe: ObserveSettings{Mode: "layer7"}}},
+		}, ErrObserveLayer7RequiresHTTP},
+		{"negative slowstart", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SlowStart: -time.Second}},
+		}, ErrSlowStartInvalid},
+		{"negative check timeout", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", CheckTimeout: -time.Second}},
+		}, ErrCheckTimeoutInvalid},
+		{"unique id in tcp mode", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", UniqueID: UniqueIDSettings{Format: "%ci:%cp_%Ts"}}},
+		}, ErrUniqueIDRequiresHTTP},
+		{"unique id header without a format", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", UniqueID: UniqueIDSettings{Header: "X-Request-ID"}}},
+		}, ErrUniqueIDHeaderInvalid},
+		{"unique id header with a space", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", UniqueID: UniqueIDSettings{Format: "%ci:%cp_%Ts", Header: "X Request ID"}}},
+		}, ErrUniqueIDHeaderInvalid},
+		{"unique id format with an unescaped quote", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", Mode: "http", UniqueID: UniqueIDSettings{Format: `%ci"%cp`}}},
+		}, ErrLogFormatInvalid},
+		{"split weight without splitting pools", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitWeights: map[string]int64{"loadpol-test": 90}}},
+		}, ErrSplitInvalid},
+		{"zero split weight", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true, SplitWeights: map[string]int64{"loadpol-test": 0}}},
+		}, ErrSplitInvalid},
+		{"split weight of another port's pool", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true, SplitWeights: map[string]int64{"loadpol-other": 10}}},
+		}, ErrSplitPoolNotFound},
+		{"split weight of a routed pool", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true, SplitWeights: map[string]int64{"loadpol-test2": 10}}},
+			Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}},
+		}, ErrSplitInvalid},
+		{"split pools with failover pools", mergeTestData2, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true, FailoverPools: []string{"loadpol-test", "loadpol-test2"}}},
+		}, ErrSplitInvalid},
+		{"connect timeout shorter than a millisecond", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", ConnectTimeout: time.Microsecond}},
+		}, ErrCheckTimeoutInvalid},
+		{"pools sharing a backend disagree on check timeout", mergeTestData2, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", CheckTimeout: 5 * time.Second}, {ID: "loadpol-test2", CheckTimeout: time.Second}},
+		}, ErrPoolSettingsConflict},
+		{"slowstart shorter than a millisecond", mergeTestData1, Settings{
+			Pools: []PoolSettings{{ID: "loadpol-test", SlowStart: time.Microsecond}},
+		}, ErrSlowStartInvalid},
+		{"cert outside the cert dir", mergeTestData1, Settings{
+			CertDir: "/etc/haproxy/certs",
+			Ports:   []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", Certs: []CertRef{{Cert: "../example.pem"}}}},
+		}, ErrCertInvalid},
+		{"sni filter with a space", mergeTestData1, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", Certs: []CertRef{{Cert: "/etc/haproxy/certs/example.pem", SNIFilters: []string{"example.com other"}}}}},
+		}, ErrSNIFilterInvalid},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+			require.Nil(t, err)
+
+			_, err = Merge(cfg, &tt.testInput, tt.settings)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.expErr)
+		})
+	}
+}
+
+func TestPortBackends(t *testing.T) {
+	port := mergeTestData2.Ports.Edges[0].Node
+
+	t.Run("single backend", func(t *testing.T) {
+		defaultBackend, routed := portBackends(port, Settings{})
+
+		assert.Equal(t, "loadprt-test", defaultBackend.label)
+		assert.Len(t, defaultBackend.pools, 2)
+		assert.Empty(t, routed)
+	})
+
+	t.Run("every pool routed keeps the default backend", func(t *testing.T) {
+		defaultBackend, routed := portBackends(port, Settings{
+			Pools: []PoolSettings{
+				{ID: "loadpol-test", Condition: "{ src 10.0.0.0/8 }"},
+				{ID: "loadpol-test2", Condition: "{ src 192.168.0.0/16 }"},
+			},
+		})
+
+		assert.Equal(t, "loadprt-test", defaultBackend.label)
+		assert.Empty(t, defaultBackend.pools)
+
+		require.Len(t, routed, 2)
+		assert.Equal(t, "loadprt-test-loadpol-test", routed[0].label)
+		assert.Equal(t, "loadprt-test-loadpol-test2", routed[1].label)
+	})
+
+	t.Run("split pools have a backend each", func(t *testing.T) {
+		defaultBackend, routed := portBackends(port, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true, SplitWeights: map[string]int64{"loadpol-test": 3}}},
+		})
+
+		assert.Equal(t, "loadprt-test-loadpol-test2", defaultBackend.label)
+		assert.Empty(t, defaultBackend.cond)
+
+		require.Len(t, routed, 1)
+		assert.Equal(t, "loadprt-test-loadpol-test", routed[0].label)
+		assert.Equal(t, "{ var(txn.split) -m int lt 3 }", routed[0].cond)
+	})
+
+	t.Run("routed pools aren't split", func(t *testing.T) {
+		defaultBackend, routed := portBackends(port, Settings{
+			Ports: []PortSettings{{ID: "loadprt-test", SplitPools: true}},
+			Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}},
+		})
+
+		// a single split pool keeps the label of its pool, so adding a canary doesn't rename it
+		assert.Equal(t, "loadprt-test-loadpol-test", defaultBackend.label)
+
+		require.Len(t, routed, 1)
+		assert.Equal(t, "{ src 10.0.0.0/8 }", routed[0].cond)
+	})
+}
+
+func TestSplitRule(t *testing.T) {
+	port := mergeTestData2.Ports.Edges[0].Node
+
+	assert.Empty(t, PortSettings{}.splitRule(modeTCP, port, Settings{}))
+	assert.Equal(t, []string{"tcp-request content set-var(txn.split) rand(2)"}, PortSettings{SplitPools: true}.splitRule(modeTCP, port, Settings{}))
+	assert.Equal(t, []string{"http-request set-var(txn.split) rand(11)"},
+		PortSettings{SplitPools: true, SplitWeights: map[string]int64{"loadpol-test2": 10}}.splitRule(modeHTTP, port, Settings{}))
+
+	// a single split pool needs no random number
+	assert.Empty(t, PortSettings{SplitPools: true}.splitRule(modeTCP, port, Settings{
+		Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}},
+	}))
+}
+
+func TestMergeRawDirectives(t *testing.T) {
+	cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+	require.Nil(t, err)
+
+	settings := Settings{
+		AllowRawDirectives: true,
+		Pools:              []PoolSettings{{ID: "loadpol-test", RawDirectives: []string{"hash-type consistent"}}},
+	}
+
+	newCfg, err := Merge(cfg, &mergeTestData1, settings)
+	require.NoError(t, err)
+
+	hashType, err := newCfg.Get(parser.Backends, "loadprt-test", "hash-type")
+	require.NoError(t, err)
+	assert.NotNil(t, hashType)
+
+	_, err = newCfg.Get(parser.Frontends, "loadprt-test", "hash-type")
+	assert.Error(t, err)
+}
+
+func TestMergeResolution(t *testing.T) {
+	lb := lbapi.LoadBalancer{
+		ID: "loadbal-test",
+		Ports: lbapi.Ports{Edges: []lbapi.PortEdges{{Node: lbapi.PortNode{
+			ID:     "loadprt-test",
+			Number: 22,
+			Pools: []lbapi.Pool{{
+				ID: "loadpol-test",
+				Origins: lbapi.Origins{Edges: []lbapi.OriginEdges{
+					{Node: lbapi.OriginNode{ID: "loadogn-test1", Target: "origin.example.com", PortNumber: 22, Active: true}},
+					{Node: lbapi.OriginNode{ID: "loadogn-test2", Target: "1.2.3.4", PortNumber: 22, Active: true}},
+				}},
+			}},
+		}}}},
+	}
+
+	settings := Settings{
+		Pools: []PoolSettings{{
+			ID:          "loadpol-test",
+			Resolvers:   "dns",
+			InitAddr:    []string{"last", "libc", "none"},
+			ResolveOpts: []string{"allow-dup-ip"},
+		}},
+	}
+
+	t.Run("hostname targets are resolved", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		require.NoError(t, cfg.SectionsCreate(parser.Resolvers, "dns"))
+
+		newCfg, err := Merge(cfg, &lb, settings)
+		require.NoError(t, err)
+
+		assert.Contains(t, newCfg.String(),
+			"server loadogn-test1 origin.example.com:22 check port 22 resolvers dns init-addr last,libc,none resolve-opts allow-dup-ip\n")
+		assert.Contains(t, newCfg.String(), "server loadogn-test2 1.2.3.4:22 check port 22\n")
+	})
+
+	t.Run("resolvers section missing", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		_, err = Merge(cfg, &lb, settings)
+		assert.ErrorIs(t, err, ErrResolversNotFound)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			settings PoolSettings
+			expErr   error
+		}{
+			{"init-addr without resolvers", PoolSettings{InitAddr: []string{"none"}}, ErrResolversRequired},
+			{"unsupported init-addr", PoolSettings{Resolvers: "dns", InitAddr: []string{"dns"}}, ErrInitAddrInvalid},
+			{"init-addr ip address", PoolSettings{Resolvers: "dns", InitAddr: []string{"10.0.0.1"}}, nil},
+			{"unsupported resolve-opts", PoolSettings{Resolvers: "dns", ResolveOpts: []string{"prefer-ipv6"}}, ErrResolveOptInvalid},
+		}
+
+		for _, tt := range tests {
+			assert.ErrorIs(t, tt.settings.validate(), tt.expErr, tt.name)
+		}
+	})
+}
+
+func TestMergeGlobal(t *testing.T) {
+	cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+	require.Nil(t, err)
+
+	settings := Settings{Global: GlobalSettings{NbThread: 4, TuneBufSize: 32768}}
+
+	newCfg, err := Merge(cfg, &mergeTestData1, settings)
+	require.NoError(t, err)
+
+	nbthread, err := newCfg.Get(parser.Global, parser.GlobalSectionName, "nbthread")
+	require.NoError(t, err)
+	assert.Equal(t, &types.Int64C{Value: 4}, nbthread)
+
+	assert.Contains(t, newCfg.String(), "  tune.bufsize 32768\n")
+	assert.NotContains(t, newCfg.String(), "tune.ssl")
+
+	// the rest of the global section is preserved
+	for _, line := range []string{"  master-worker\n", "  maxconn 200\n", "  pidfile /var/run/haproxy/haproxy.pid\n", "  log 127.0.0.1 local0\n"} {
+		assert.Contains(t, newCfg.String(), line)
+	}
+}
+
+func TestMergeSingleServerBalance(t *testing.T) {
+	// a copy of mergeTestData1 whose pool only has its first origin
+	singleOrigin := func() lbapi.LoadBalancer {
+		lb := mergeTestData1
+		port := lb.Ports.Edges[0].Node
+		pool := port.Pools[0]
+
+		pool.Origins.Edges = pool.Origins.Edges[:1]
+		port.Pools = []lbapi.Pool{pool}
+		lb.Ports.Edges = []lbapi.PortEdges{{Node: port}}
+
+		return lb
+	}()
+
+	balance := BalanceSettings{Algorithm: "leastconn"}
+
+	tests := []struct {
+		name       string
+		testInput  lbapi.LoadBalancer
+		omit       bool
+		expBalance bool
+	}{
+		{"single server kept by default", singleOrigin, false, true},
+		{"single server omitted", singleOrigin, true, false},
+		{"multiple servers kept", mergeTestData1, true, true},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+			require.Nil(t, err)
+
+			newCfg, err := Merge(cfg, &tt.testInput, Settings{
+				OmitSingleServerBalance: tt.omit,
+				Pools:                   []PoolSettings{{ID: "loadpol-test", Balance: balance}},
+			})
+			require.NoError(t, err)
+
+			if tt.expBalance {
+				assert.Contains(t, Render(newCfg), "  balance leastconn\n")
+			} else {
+				assert.NotContains(t, Render(newCfg), "balance")
+			}
+
+			assert.Contains(t, Render(newCfg), "server loadogn-test1 ")
+		})
+	}
+}
+
+func TestMergeStatsSocket(t *testing.T) {
+	statsSockets := func(cfg parser.Parser) []string {
+		paths := []string{}
+
+		data, err := cfg.Get(parser.Global, parser.GlobalSectionName, "stats socket")
+		if err != nil {
+			return paths
+		}
+
+		for _, s := range data.([]types.Socket) {
+			paths = append(paths, s.Path)
+		}
+
+		return paths
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		newCfg, err := Merge(cfg, &mergeTestData1, Settings{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"/var/run/haproxy/haproxy.sock"}, statsSockets(newCfg))
+	})
+
+	t.Run("added when absent", func(t *testing.T) {
+		cfg, err := parser.New(options.Reader(strings.NewReader("global\n  maxconn 200\n")), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		newCfg, err := Merge(cfg, &mergeTestData1, Settings{Global: GlobalSettings{StatsSocket: true}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{StatsSocketPath}, statsSockets(newCfg))
+		assert.Contains(t, newCfg.String(), "  stats socket /var/run/haproxy.sock mode 660 level admin\n")
+	})
+
+	t.Run("added alongside another socket", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		newCfg, err := Merge(cfg, &mergeTestData1, Settings{Global: GlobalSettings{StatsSocket: true}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"/var/run/haproxy/haproxy.sock", StatsSocketPath}, statsSockets(newCfg))
+	})
+
+	t.Run("not duplicated when present", func(t *testing.T) {
+		base := "global\n  stats socket /var/run/haproxy.sock mode 600 level admin\n"
+
+		cfg, err := parser.New(options.Reader(strings.NewReader(base)), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		newCfg, err := Merge(cfg, &mergeTestData1, Settings{Global: GlobalSettings{StatsSocket: true}})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{StatsSocketPath}, statsSockets(newCfg))
+		assert.Contains(t, newCfg.String(), "mode 600")
+	})
+}
+
+func TestMergeBaseSectionConflict(t *testing.T) {
+	contents, err := os.ReadFile(testBaseCfgPath)
+	require.NoError(t, err)
+
+	base := string(contents) + `
+frontend loadprt-test
+  bind ipv4@:2222
+  use_backend loadprt-test
+
+backend loadprt-test
+  server example 10.0.0.1:22
+`
+
+	newCfg := func(settings Settings) (parser.Parser, error) {
+		cfg, err := parser.New(options.Reader(strings.NewReader(base)), options.NoNamedDefaultsFrom)
+		require.NoError(t, err)
+
+		return Merge(cfg, &mergeTestData1, settings)
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := newCfg(Settings{})
+		require.ErrorIs(t, err, ErrSectionConflict)
+		assert.ErrorContains(t, err, `frontend "loadprt-test"`)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		cfg, err := newCfg(Settings{OverwriteBaseSections: true})
+		require.NoError(t, err)
+
+		expCfg, err := os.ReadFile(fmt.Sprintf("%s/%s", testDataBaseDir, "lb-ex-1-exp.cfg"))
+		require.NoError(t, err)
+
+		assert.Equal(t, strings.TrimSpace(string(expCfg)), strings.TrimSpace(Render(cfg)))
+	})
+}
+
+func TestMergeDescriptions(t *testing.T) {
+	newCfg := func(settings Settings) string {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		merged, err := Merge(cfg, &mergeTestData1, settings)
+		require.NoError(t, err)
+
+		return merged.String()
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		assert.NotContains(t, newCfg(Settings{}), "description")
+	})
+
+	t.Run("port and pool names", func(t *testing.T) {
+		cfg := newCfg(Settings{
+			Descriptions: true,
+			Pools:        []PoolSettings{{ID: "loadpol-test", Condition: "{ src 10.0.0.0/8 }"}},
+		})
+
+		assert.Regexp(t, `frontend loadprt-test\n(  .*\n)*  description ssh-service\n`, cfg)
+		assert.Regexp(t, `backend loadprt-test\n(  .*\n)*  description ssh-service\n`, cfg)
+		assert.Regexp(t, `backend loadprt-test-loadpol-test\n(  .*\n)*  description ssh-service-a\n`, cfg)
+	})
+}
+
+func TestDescription(t *testing.T) {
+	assert.Equal(t, []string{"description ssh service"}, description(" ssh\nservice "))
+	assert.Empty(t, description(""))
+}
+
+func TestAppendRawDirectives(t *testing.T) {
+	cfg := "frontend loadprt-test\n  bind ipv4@:22\n  use_backend loadprt-test\n\nbackend loadprt-test\n  server loadogn-test1 1.2.3.4:2222\n"
+
+	expected := "frontend loadprt-test\n  bind ipv4@:22\n  use_backend loadprt-test\n\n" +
+		"backend loadprt-test\n  server loadogn-test1 1.2.3.4:2222\n  hash-type consistent\n  option splice-auto\n"
+
+	raw := map[string][]string{
+		"backend loadprt-test": {"hash-type consistent", " option splice-auto "},
+	}
+
+	assert.Equal(t, expected, appendRawDirectives(cfg, raw))
+	assert.Equal(t, cfg, appendRawDirectives(cfg, map[string][]string{}))
+}
+
+var mergeTestData1 = lbapi.LoadBalancer{
+	ID:   "loadbal-test",
+	Name: "test",
+	Ports: lbapi.Ports{
+		Edges: []lbapi.PortEdges{
+			{
+				Node: lbapi.PortNode{
+					// TODO - @rizzza - AddressFamily?
+					ID:     "loadprt-test",
+					Name:   "ssh-service",
+					Number: 22,
+					Pools: []lbapi.Pool{
+						{
+							ID:       "loadpol-test",
+							Name:     "ssh-service-a",
+							Protocol: "tcp",
+							Origins: lbapi.Origins{
+								Edges: []lbapi.OriginEdges{
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test1",
+											Name:       "svr1-2222",
+											Target:     "1.2.3.4",
+											PortNumber: 2222,
+											Active:     true,
+										},
+									},
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test2",
+											Name:       "svr1-222",
+											Target:     "1.2.3.4",
+											PortNumber: 222,
+											Active:     true,
+										},
+									},
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test3",
+											Name:       "svr2",
+											Target:     "4.3.2.1",
+											PortNumber: 2222,
+											Active:     false,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var mergeTestData2 = lbapi.LoadBalancer{
+	ID:   "loadbal-test",
+	Name: "test",
+	Ports: lbapi.Ports{
+		Edges: []lbapi.PortEdges{
+			{
+				Node: lbapi.PortNode{
+					// TODO - @rizzza - AddressFamily?
+					ID:     "loadprt-test",
+					Name:   "ssh-service-a",
+					Number: 22,
+					Pools: []lbapi.Pool{
+						{
+							ID:       "loadpol-test",
+							Name:     "ssh-service-a",
+							Protocol: "tcp",
+							Origins: lbapi.Origins{
+								Edges: []lbapi.OriginEdges{
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test1",
+											Name:       "svr1-2222",
+											Target:     "1.2.3.4",
+											PortNumber: 2222,
+											Active:     true,
+										},
+									},
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test2",
+											Name:       "svr1-222",
+											Target:     "1.2.3.4",
+											PortNumber: 222,
+											Active:     true,
+										},
+									},
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test3",
+											Name:       "svr2",
+											Target:     "4.3.2.1",
+											PortNumber: 2222,
+											Active:     false,
+										},
+									},
+								},
+							},
+						},
+						{
+							ID:       "loadpol-test2",
+							Name:     "ssh-service-b",
+							Protocol: "tcp",
+							Origins: lbapi.Origins{
+								Edges: []lbapi.OriginEdges{
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test4",
+											Name:       "svr1-2222",
+											Target:     "7.8.9.0",
+											PortNumber: 2222,
+											Active:     true,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var mergeTestData3 = lbapi.LoadBalancer{
+	ID:   "loadbal-test",
+	Name: "http/https",
+	Ports: lbapi.Ports{
+		Edges: []lbapi.PortEdges{
+			{
+				Node: lbapi.PortNode{
+					// TODO - @rizzza - AddressFamily?
+					ID:     "loadprt-testhttp",
+					Name:   "http",
+					Number: 80,
+					Pools: []lbapi.Pool{
+						{
+							ID:       "loadpol-test",
+							Name:     "ssh-service-a",
+							Protocol: "tcp",
+							Origins: lbapi.Origins{
+								Edges: []lbapi.OriginEdges{
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test1",
+											Name:       "svr1",
+											Target:     "3.1.4.1",
+											PortNumber: 80,
+											Active:     true,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			{
+				Node: lbapi.PortNode{
+					// TODO - @rizzza - AddressFamily?
+					ID:     "loadprt-testhttps",
+					Name:   "https",
+					Number: 443,
+					Pools: []lbapi.Pool{
+						{
+							ID:       "loadpol-test",
+							Name:     "ssh-service-a",
+							Protocol: "tcp",
+							Origins: lbapi.Origins{
+								Edges: []lbapi.OriginEdges{
+									{
+										Node: lbapi.OriginNode{
+											ID:         "loadogn-test2",
+											Name:       "svr1",
+											Target:     "3.1.4.1",
+											PortNumber: 443,
+											Active:     true,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+var mergeTestDataNoPools = lbapi.LoadBalancer{
+	ID:   "loadbal-test",
+	Name: "test",
+	Ports: lbapi.Ports{
+		Edges: []lbapi.PortEdges{
+			{
+				Node: lbapi.PortNode{
+					ID:     "loadprt-test",
+					Name:   "ssh-service",
+					Number: 22,
+					Pools:  []lbapi.Pool{},
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,72 @@
+package haproxyconfig
+
+import (
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/types"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// MetricsFrontend is the label of the frontend generated for haproxy's prometheus exporter
+const MetricsFrontend = "metrics"
+
+// metricsRule serves haproxy's metrics from the metrics frontend
+const metricsRule = "http-request use-service prometheus-exporter if { path /metrics }"
+
+// validateMetricsPort checks the metrics port is a tcp port which isn't one of the loadbalancer's
+func validateMetricsPort(lb *lbapi.LoadBalancer, settings Settings) error {
+	if settings.MetricsPort == 0 {
+		return nil
+	}
+
+	if settings.MetricsPort < 1 || settings.MetricsPort > maxPortNumber {
+		return fmt.Errorf("%w: %d", ErrMetricsPortInvalid, settings.MetricsPort)
+	}
+
+	for _, p := range lb.Ports.Edges {
+		if p.Node.Number == settings.MetricsPort {
+			return fmt.Errorf("%w %d: %q", ErrMetricsPortConflict, settings.MetricsPort, p.Node.ID)
+		}
+	}
+
+	return nil
+}
+
+// mergeMetrics creates the metrics frontend when a metrics port is set. Its use-service rule
+// isn't modeled by the parser, so it is added with the raw directives.
+func mergeMetrics(cfg parser.Parser, settings Settings) error {
+	if settings.MetricsPort == 0 {
+		return nil
+	}
+
+	if err := prepareSection(cfg, parser.Frontends, MetricsFrontend, settings.OverwriteBaseSections); err != nil {
+		return err
+	}
+
+	if err := cfg.SectionsCreate(parser.Frontends, MetricsFrontend); err != nil {
+		return newLabelError(MetricsFrontend, ErrFrontendSectionLabelFailure, err)
+	}
+
+	if err := cfg.Set(parser.Frontends, MetricsFrontend, "mode", types.StringC{Value: modeHTTP}); err != nil {
+		return newAttrError(ErrFrontendModeFailure, err)
+	}
+
+	bind := types.Bind{Path: fmt.Sprintf("ipv4@:%d", settings.MetricsPort)}
+
+	if err := cfg.Insert(parser.Frontends, MetricsFrontend, "bind", bind); err != nil {
+		return newAttrError(ErrFrontendBindFailure, err)
+	}
+
+	return nil
+}
+
+// metricsRules returns the raw directives of the metrics frontend, or nothing when there is none
+func (s Settings) metricsRules() []string {
+	if s.MetricsPort == 0 {
+		return nil
+	}
+
+	return []string{metricsRule}
+}
@@ -0,0 +1,79 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	observeLayer4 = "layer4"
+	observeLayer7 = "layer7"
+)
+
+// observeOnErrorActions are the actions haproxy takes on servers reaching the error limit
+var observeOnErrorActions = map[string]bool{
+	"fastinter":    true,
+	"fail-check":   true,
+	"sudden-death": true,
+	"mark-down":    true,
+}
+
+// ObserveSettings passively check the servers of a pool by observing their traffic, e.g. to mark
+// them down on errors between health checks. Mode is layer4 to observe connection errors, or layer7
+// to also observe http errors, which requires http mode. ErrorLimit is the number of consecutive
+// errors triggering OnError, e.g. mark-down. Zero values keep haproxy's defaults.
+type ObserveSettings struct {
+	Mode       string
+	ErrorLimit int64
+	OnError    string
+}
+
+// validate checks the observe mode and on-error action are supported, the error limit isn't
+// negative, and the error limit and action are only set along with a mode
+func (o ObserveSettings) validate() error {
+	if o.Mode != "" && o.Mode != observeLayer4 && o.Mode != observeLayer7 {
+		return fmt.Errorf("%w: mode %q", ErrObserveInvalid, o.Mode)
+	}
+
+	if o.ErrorLimit < 0 {
+		return fmt.Errorf("%w: error limit %d is negative", ErrObserveInvalid, o.ErrorLimit)
+	}
+
+	if o.OnError != "" && !observeOnErrorActions[o.OnError] {
+		return fmt.Errorf("%w: on-error action %q", ErrObserveInvalid, o.OnError)
+	}
+
+	if o.Mode == "" && (o.ErrorLimit != 0 || o.OnError != "") {
+		return fmt.Errorf("%w: error limit and on-error action require a mode", ErrObserveInvalid)
+	}
+
+	return nil
+}
+
+// validateObserveMode checks servers are only observed at layer7 in http mode
+func (p PoolSettings) validateObserveMode(mode string) error {
+	if p.Observe.Mode == observeLayer7 && mode != modeHTTP {
+		return ErrObserveLayer7RequiresHTTP
+	}
+
+	return nil
+}
+
+// params returns the server params observing the servers' traffic, or nothing when it isn't observed
+func (o ObserveSettings) params() string {
+	if o.Mode == "" {
+		return ""
+	}
+
+	params := " observe " + o.Mode
+
+	if o.ErrorLimit > 0 {
+		params += " error-limit " + strconv.FormatInt(o.ErrorLimit, 10)
+	}
+
+	if o.OnError != "" {
+		params += " on-error " + o.OnError
+	}
+
+	return params
+}
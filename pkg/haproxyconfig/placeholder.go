@@ -0,0 +1,24 @@
+package haproxyconfig
+
+const (
+	// placeholderHTTPRule answers requests to a backend without pools with a 503
+	placeholderHTTPRule = "http-request deny deny_status 503"
+
+	// placeholderTCPRule closes connections to a backend without pools
+	placeholderTCPRule = "tcp-request content reject"
+)
+
+// placeholderRule returns the rule rejecting connections to a backend without pools, so clients get
+// an error instead of waiting on a backend without servers. It returns nothing when placeholders
+// are disabled or the backend has pools.
+func placeholderRule(mode string, b backend, settings Settings) []string {
+	if !settings.PlaceholderBackends || len(b.pools) > 0 {
+		return nil
+	}
+
+	if mode == modeHTTP {
+		return []string{placeholderHTTPRule}
+	}
+
+	return []string{placeholderTCPRule}
+}
@@ -0,0 +1,69 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// validateNbProc checks nbproc isn't negative, and isn't combined with several threads, which
+// haproxy rejects
+func (g GlobalSettings) validateNbProc(nbThread int64) error {
+	if g.NbProc < 0 {
+		return fmt.Errorf("%w: %d", ErrNbProcInvalid, g.NbProc)
+	}
+
+	if g.NbProc > 1 && nbThread > 1 {
+		return fmt.Errorf("%w: %d processes with nbthread %d", ErrNbProcInvalid, g.NbProc, nbThread)
+	}
+
+	return nil
+}
+
+// nbProcRules returns the global directive running several processes, or nothing for haproxy's
+// default of a single process
+func (g GlobalSettings) nbProcRules() []string {
+	if g.NbProc <= 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("nbproc %d", g.NbProc)}
+}
+
+// validateProcess checks the bind process is all, odd, even, a process or a range of processes,
+// and is only set with nbproc, within it
+func (p PortSettings) validateProcess(nbProc int64) error {
+	if p.Process == "" {
+		return nil
+	}
+
+	if nbProc <= 0 {
+		return fmt.Errorf("%w: %q without nbproc", ErrProcessInvalid, p.Process)
+	}
+
+	switch p.Process {
+	case "all", "odd", "even":
+		return nil
+	}
+
+	first, last, found := strings.Cut(p.Process, "-")
+	if !found {
+		last = first
+	}
+
+	from, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || from < 1 {
+		return fmt.Errorf("%w: %q", ErrProcessInvalid, p.Process)
+	}
+
+	to, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || to < from {
+		return fmt.Errorf("%w: %q", ErrProcessInvalid, p.Process)
+	}
+
+	if to > nbProc {
+		return fmt.Errorf("%w: %q exceeds nbproc %d", ErrProcessInvalid, p.Process, nbProc)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// validateQueue checks the queue limits aren't negative and the queue timeout is whole milliseconds
+func (p PoolSettings) validateQueue() error {
+	if p.MaxQueue < 0 {
+		return fmt.Errorf("%w: maxqueue %d is negative", ErrQueueLimitInvalid, p.MaxQueue)
+	}
+
+	if p.QueueTimeout < 0 || p.QueueTimeout%time.Millisecond != 0 {
+		return fmt.Errorf("%w: queue timeout %s is not positive whole milliseconds", ErrQueueLimitInvalid, p.QueueTimeout)
+	}
+
+	return nil
+}
+
+// queueParams returns the server params limiting the queue of each server, or nothing when the
+// pool doesn't limit it
+func (p PoolSettings) queueParams() string {
+	if p.MaxQueue == 0 {
+		return ""
+	}
+
+	return " maxqueue " + strconv.FormatInt(p.MaxQueue, 10)
+}
+
+// queueTimeout returns the queue timeout of a pool, for comparing pools sharing a backend
+func queueTimeout(p PoolSettings) time.Duration {
+	return p.QueueTimeout
+}
@@ -0,0 +1,69 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultConnRatePeriod is the period connection rates are measured over when it isn't set
+	defaultConnRatePeriod = 10 * time.Second
+
+	// connRateTableSize is the number of client addresses tracked by a connection rate limit
+	connRateTableSize = "100k"
+)
+
+// ConnRateLimitSettings rejects connections from client addresses which open more than Limit
+// connections within Period, e.g. to protect ssh services from connection floods. Period defaults
+// to 10s, and a zero Limit doesn't limit connections.
+type ConnRateLimitSettings struct {
+	Limit  int64
+	Period time.Duration
+}
+
+// validateConnRateLimit checks the connection rate limit is only set on tcp ports, with a positive
+// limit and a period of whole milliseconds
+func (p PortSettings) validateConnRateLimit() error {
+	limit := p.ConnRateLimit
+
+	if limit.Limit == 0 && limit.Period == 0 {
+		return nil
+	}
+
+	if p.Mode == modeHTTP {
+		return ErrConnRateLimitRequiresTCP
+	}
+
+	if limit.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive, got %d", ErrConnRateLimitInvalid, limit.Limit)
+	}
+
+	if limit.Period < 0 || limit.Period%time.Millisecond != 0 {
+		return fmt.Errorf("%w: period must be positive whole milliseconds, got %s", ErrConnRateLimitInvalid, limit.Period)
+	}
+
+	return nil
+}
+
+// connRateLimitRules returns the stick table tracking the connection rate of client addresses and
+// the tcp-request rules rejecting them past the limit, or nothing when connections aren't limited.
+// They follow the PROXY protocol rule, so the addresses tracked are those of the clients rather
+// than the proxies.
+func (p PortSettings) connRateLimitRules() []string {
+	limit := p.ConnRateLimit
+	if limit.Limit <= 0 {
+		return nil
+	}
+
+	period := limit.Period
+	if period == 0 {
+		period = defaultConnRatePeriod
+	}
+
+	return []string{
+		fmt.Sprintf("stick-table type ip size %s expire %s store conn_rate(%s)", connRateTableSize, haproxyDuration(period), haproxyDuration(period)),
+		"tcp-request connection track-sc0 src",
+		"tcp-request connection reject if { sc_conn_rate(0) gt " + strconv.FormatInt(limit.Limit, 10) + " }",
+	}
+}
@@ -0,0 +1,55 @@
+package haproxyconfig
+
+import (
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// managedLabelPrefix is the prefix of the labels of generated frontends and backends, the gidx
+// prefix of ports
+const managedLabelPrefix = "loadprt-"
+
+// RemoveStaleSections removes the generated frontends and backends of a previously merged config
+// which the loadbalancer no longer generates, e.g. after one of its ports is deleted or a pool
+// stops being routed. Configs merged from the base config don't need it, it is for updating a
+// merged config in place. Sections of the base config are kept, as only generated labels are
+// removed.
+func RemoveStaleSections(cfg parser.Parser, lb *lbapi.LoadBalancer, settings Settings) error {
+	desired := map[parser.Section]map[string]bool{
+		parser.Frontends: {},
+		parser.Backends:  {},
+	}
+
+	for _, p := range lb.Ports.Edges {
+		desired[parser.Frontends][p.Node.ID] = true
+
+		defaultBackend, routed := portBackends(p.Node, settings)
+
+		for _, b := range append([]backend{defaultBackend}, routed...) {
+			desired[parser.Backends][b.label] = true
+		}
+	}
+
+	for _, section := range []parser.Section{parser.Frontends, parser.Backends} {
+		labels, err := cfg.SectionsGet(section)
+		if err != nil {
+			// the config has no sections of this type
+			continue
+		}
+
+		for _, label := range labels {
+			if !strings.HasPrefix(label, managedLabelPrefix) || desired[section][label] {
+				continue
+			}
+
+			if err := cfg.SectionsDelete(section, label); err != nil {
+				return newLabelError(label, ErrStaleSectionRemoval, err)
+			}
+		}
+	}
+
+	return nil
+}
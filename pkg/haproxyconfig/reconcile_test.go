@@ -0,0 +1,65 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+func TestRemoveStaleSections(t *testing.T) {
+	t.Run("deleted port", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		cfg, err = Merge(cfg, &mergeTestData3, Settings{})
+		require.NoError(t, err)
+
+		// the loadbalancer without its https port
+		lb := mergeTestData3
+		lb.Ports.Edges = []lbapi.PortEdges{mergeTestData3.Ports.Edges[0]}
+
+		require.NoError(t, RemoveStaleSections(cfg, &lb, Settings{}))
+
+		frontends, err := cfg.SectionsGet(parser.Frontends)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"loadprt-testhttp", "stats"}, frontends)
+
+		backends, err := cfg.SectionsGet(parser.Backends)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"loadprt-testhttp"}, backends)
+	})
+
+	t.Run("pool no longer routed", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		routed := Settings{Pools: []PoolSettings{{ID: "loadpol-test2", Condition: "{ src 10.0.0.0/8 }"}}}
+
+		cfg, err = Merge(cfg, &mergeTestData2, routed)
+		require.NoError(t, err)
+
+		require.NoError(t, RemoveStaleSections(cfg, &mergeTestData2, Settings{}))
+
+		backends, err := cfg.SectionsGet(parser.Backends)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"loadprt-test"}, backends)
+	})
+
+	t.Run("unchanged loadbalancer", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		cfg, err = Merge(cfg, &mergeTestData3, Settings{})
+		require.NoError(t, err)
+
+		before := cfg.String()
+
+		require.NoError(t, RemoveStaleSections(cfg, &mergeTestData3, Settings{}))
+		assert.Equal(t, before, cfg.String())
+	})
+}
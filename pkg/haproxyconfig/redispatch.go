@@ -0,0 +1,96 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RedispatchSettings redispatches connections to another server when retrying a failed server.
+// Interval redispatches on every Interval-th retry when positive, and on the last -Interval-th
+// retry when negative. Zero redispatches on every retry.
+type RedispatchSettings struct {
+	Enabled  bool
+	Interval int64
+}
+
+// validate checks the interval is only set when redispatching, and is within the retries when
+// the pool sets them, as connections would otherwise never be redispatched
+func (r RedispatchSettings) validate(retries int64) error {
+	if !r.Enabled {
+		if r.Interval != 0 {
+			return fmt.Errorf("%w: interval %d set without redispatch", ErrRedispatchInvalid, r.Interval)
+		}
+
+		return nil
+	}
+
+	interval := r.Interval
+	if interval < 0 {
+		interval = -interval
+	}
+
+	if retries > 0 && interval > retries {
+		return fmt.Errorf("%w: interval %d exceeds the %d retries", ErrRedispatchInvalid, r.Interval, retries)
+	}
+
+	return nil
+}
+
+// rule returns the option redispatch directive, or nothing when connections aren't redispatched
+func (r RedispatchSettings) rule() []string {
+	if !r.Enabled {
+		return nil
+	}
+
+	if r.Interval == 0 {
+		return []string{"option redispatch"}
+	}
+
+	return []string{"option redispatch " + strconv.FormatInt(r.Interval, 10)}
+}
+
+// validatePersist checks the force-persist condition is a single line
+func (p PoolSettings) validatePersist() error {
+	if strings.ContainsAny(p.ForcePersist, "\r\n") {
+		return fmt.Errorf("%w: %q", ErrForcePersistInvalid, p.ForcePersist)
+	}
+
+	return nil
+}
+
+// redispatch returns the redispatch settings of a pool, for comparing pools sharing a backend
+func redispatch(p PoolSettings) RedispatchSettings {
+	return p.Redispatch
+}
+
+// persist returns whether a pool persists connections to down servers, for comparing pools
+// sharing a backend
+func persist(p PoolSettings) bool {
+	return p.Persist
+}
+
+// forcePersist returns the force-persist condition of a pool, for comparing pools sharing a backend
+func forcePersist(p PoolSettings) string {
+	return p.ForcePersist
+}
+
+// persistenceRules returns the option persist, option redispatch and force-persist directives of a
+// backend. Conflicting settings are returned as an error by mergeBackend.
+func persistenceRules(b backend, settings Settings) []string {
+	rules := []string{}
+
+	if enabled, err := backendSetting(settings, b, "option persist", persist); err == nil && enabled {
+		rules = append(rules, "option persist")
+	}
+
+	if r, err := backendSetting(settings, b, "option redispatch", redispatch); err == nil {
+		rules = append(rules, r.rule()...)
+	}
+
+	if cond, err := backendSetting(settings, b, "force-persist", forcePersist); err == nil && cond != "" {
+		rules = append(rules, "force-persist if "+cond)
+	}
+
+	return rules
+}
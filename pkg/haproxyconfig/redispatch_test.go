@@ -0,0 +1,31 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedispatchRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		redispatch RedispatchSettings
+		expRule    []string
+	}{
+		{"disabled", RedispatchSettings{}, nil},
+		{"every retry", RedispatchSettings{Enabled: true}, []string{"option redispatch"}},
+		{"every second retry", RedispatchSettings{Enabled: true, Interval: 2}, []string{"option redispatch 2"}},
+		{"last retry", RedispatchSettings{Enabled: true, Interval: -1}, []string{"option redispatch -1"}},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expRule, tt.redispatch.rule())
+		})
+	}
+}
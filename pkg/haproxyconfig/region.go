@@ -0,0 +1,42 @@
+package haproxyconfig
+
+import (
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// regionPort returns a copy of the port whose pools only have the origins in the region of the
+// settings, or the port unchanged when no region is set. lbRegion is the region of the
+// loadbalancer, which origins are in unless their settings say otherwise.
+func regionPort(port lbapi.PortNode, lbRegion string, settings Settings) lbapi.PortNode {
+	if settings.Region == "" {
+		return port
+	}
+
+	pools := make([]lbapi.Pool, 0, len(port.Pools))
+
+	for _, pool := range port.Pools {
+		edges := []lbapi.OriginEdges{}
+
+		for _, origin := range pool.Origins.Edges {
+			if settings.originRegion(origin.Node.ID, lbRegion) == settings.Region {
+				edges = append(edges, origin)
+			}
+		}
+
+		pool.Origins.Edges = edges
+		pools = append(pools, pool)
+	}
+
+	port.Pools = pools
+
+	return port
+}
+
+// originRegion returns the region of an origin, which is the loadbalancer's unless set in its settings
+func (s Settings) originRegion(id, lbRegion string) string {
+	if region := s.origin(id).Region; region != "" {
+		return region
+	}
+
+	return lbRegion
+}
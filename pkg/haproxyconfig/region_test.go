@@ -0,0 +1,79 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+func TestRegionPort(t *testing.T) {
+	originIDs := func(port lbapi.PortNode) []string {
+		ids := []string{}
+
+		for _, pool := range port.Pools {
+			for _, origin := range pool.Origins.Edges {
+				ids = append(ids, origin.Node.ID)
+			}
+		}
+
+		return ids
+	}
+
+	origins := []OriginSettings{
+		{ID: "loadogn-test2", Region: "lctnloc-west"},
+		{ID: "loadogn-test3", Region: "lctnloc-east"},
+	}
+
+	tests := []struct {
+		name   string
+		region string
+		expIDs []string
+	}{
+		{"no region includes all origins", "", []string{"loadogn-test1", "loadogn-test2", "loadogn-test3"}},
+		{"loadbalancer region includes origins without a region", "lctnloc-east", []string{"loadogn-test1", "loadogn-test3"}},
+		{"other region only includes its origins", "lctnloc-west", []string{"loadogn-test2"}},
+		{"region without origins", "lctnloc-north", []string{}},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			port := mergeTestData1.Ports.Edges[0].Node
+			settings := Settings{Origins: origins, Region: tt.region}
+
+			assert.Equal(t, tt.expIDs, originIDs(regionPort(port, "lctnloc-east", settings)))
+
+			// the loadbalancer is left unchanged
+			require.Len(t, originIDs(port), 3)
+		})
+	}
+}
+
+func TestMergeRegion(t *testing.T) {
+	cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+	require.Nil(t, err)
+
+	lb := mergeTestData1
+	lb.Location = lbapi.LocationNode{ID: "lctnloc-east"}
+
+	newCfg, err := Merge(cfg, &lb, Settings{
+		Region:  "lctnloc-west",
+		Origins: []OriginSettings{{ID: "loadogn-test2", Region: "lctnloc-west"}},
+	})
+	require.Nil(t, err)
+
+	rendered := Render(newCfg)
+
+	assert.Contains(t, rendered, "server loadogn-test2 ")
+	assert.NotContains(t, rendered, "server loadogn-test1 ")
+	assert.NotContains(t, rendered, "server loadogn-test3 ")
+}
@@ -0,0 +1,17 @@
+package haproxyconfig
+
+import (
+	"regexp"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// unnamedDefaultsRegex matches the labels the parser gives unnamed defaults sections, which it
+// renders even with options.NoNamedDefaultsFrom
+var unnamedDefaultsRegex = regexp.MustCompile(`(?m)^defaults unnamed_defaults_\d+[ \t]*$`)
+
+// Render returns the config as it is applied to haproxy, with the labels the parser gives unnamed
+// defaults sections removed so they render as they were written in the base config
+func Render(cfg parser.Parser) string {
+	return unnamedDefaultsRegex.ReplaceAllString(cfg.String(), "defaults")
+}
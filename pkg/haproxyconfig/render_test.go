@@ -0,0 +1,31 @@
+package haproxyconfig
+
+import (
+	"strings"
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Run("unnamed defaults", func(t *testing.T) {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		assert.Contains(t, cfg.String(), "unnamed_defaults_1")
+		assert.NotContains(t, Render(cfg), "unnamed_defaults")
+		assert.Contains(t, Render(cfg), "\ndefaults\n")
+	})
+
+	t.Run("named defaults are kept", func(t *testing.T) {
+		base := "defaults tcp-defaults\n  mode tcp\n\nfrontend stats\n  bind 127.0.0.1:29782\n"
+
+		cfg, err := parser.New(options.Reader(strings.NewReader(base)), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		assert.Contains(t, Render(cfg), "defaults tcp-defaults\n")
+	})
+}
@@ -0,0 +1,64 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strings"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// retryOnConditions are the retry-on conditions haproxy accepts
+var retryOnConditions = map[string]bool{
+	"none":                 true,
+	"conn-failure":         true,
+	"empty-response":       true,
+	"junk-response":        true,
+	"response-timeout":     true,
+	"0rtt-rejected":        true,
+	"all-retryable-errors": true,
+	"401":                  true,
+	"403":                  true,
+	"404":                  true,
+	"408":                  true,
+	"425":                  true,
+	"500":                  true,
+	"501":                  true,
+	"502":                  true,
+	"503":                  true,
+	"504":                  true,
+}
+
+// validateRetries checks the retries aren't negative and the retry-on conditions are ones haproxy accepts
+func (p PoolSettings) validateRetries() error {
+	if p.Retries < 0 {
+		return fmt.Errorf("%w: %d", ErrRetriesInvalid, p.Retries)
+	}
+
+	for _, c := range p.RetryOn {
+		if !retryOnConditions[c] {
+			return fmt.Errorf("%w: %q", ErrRetryOnInvalid, c)
+		}
+	}
+
+	return nil
+}
+
+// retryOnRule returns the retry-on directive of a backend in http mode, or nothing when its pools
+// don't set conditions. Conflicting conditions are returned as an error by mergeBackend.
+func retryOnRule(cfg parser.Parser, b backend, portSettings PortSettings, settings Settings) []string {
+	if backendMode(cfg, portSettings) != modeHTTP {
+		return nil
+	}
+
+	conditions, err := backendSetting(settings, b, "retry-on", retryOn)
+	if err != nil || conditions == "" {
+		return nil
+	}
+
+	return []string{"retry-on " + conditions}
+}
+
+// retryOn returns the retry-on conditions of a pool as they are rendered, so pools can be compared
+func retryOn(p PoolSettings) string {
+	return strings.Join(p.RetryOn, " ")
+}
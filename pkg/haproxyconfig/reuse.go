@@ -0,0 +1,44 @@
+package haproxyconfig
+
+import (
+	"fmt"
+
+	parser "github.com/haproxytech/config-parser/v4"
+)
+
+// httpReuseModes are the http-reuse modes haproxy accepts
+var httpReuseModes = map[string]bool{
+	"never":      true,
+	"safe":       true,
+	"aggressive": true,
+	"always":     true,
+}
+
+// validateHTTPReuse checks the http-reuse mode is one haproxy accepts
+func (p PoolSettings) validateHTTPReuse() error {
+	if p.HTTPReuse != "" && !httpReuseModes[p.HTTPReuse] {
+		return fmt.Errorf("%w: %q", ErrHTTPReuseInvalid, p.HTTPReuse)
+	}
+
+	return nil
+}
+
+// httpReuseRule returns the http-reuse directive of a backend in http mode, or nothing when its
+// pools don't set a mode. Conflicting modes are returned as an error by mergeBackend.
+func httpReuseRule(cfg parser.Parser, b backend, portSettings PortSettings, settings Settings) []string {
+	if backendMode(cfg, portSettings) != modeHTTP {
+		return nil
+	}
+
+	mode, err := backendSetting(settings, b, "http-reuse", httpReuse)
+	if err != nil || mode == "" {
+		return nil
+	}
+
+	return []string{"http-reuse " + mode}
+}
+
+// httpReuse returns the http-reuse mode of a pool, for comparing pools sharing a backend
+func httpReuse(p PoolSettings) string {
+	return p.HTTPReuse
+}
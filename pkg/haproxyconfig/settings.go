@@ -0,0 +1,940 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// Settings contains haproxy settings for the generated config which are not part of the
+// loadbalancer api model. Port and pool settings are matched to the loadbalancer by ID.
+type Settings struct {
+	Global        GlobalSettings
+	LoadBalancers []LoadBalancerSettings
+	Ports         []PortSettings
+	Pools         []PoolSettings
+	Origins       []OriginSettings
+
+	// CertDir is the directory of the certificates of ports which aren't absolute paths
+	CertDir string `mapstructure:"-"`
+
+	// Region limits the servers to origins in the region, the ID of a location. Origins are in the
+	// region of the loadbalancer unless their settings place them in another one. Empty includes
+	// all origins.
+	Region string `mapstructure:"-"`
+
+	// DefaultsMode manages the mode of the base config's defaults sections: tcp, http, or predominant
+	// for the mode of most ports. Empty leaves it untouched. Ports inheriting a mode which changes
+	// have it set on their sections.
+	DefaultsMode string
+
+	// AllowRawDirectives permits ports and pools to carry raw directives, which bypass validation.
+	// It is only set by flag, so a settings file can't opt itself in.
+	AllowRawDirectives bool `mapstructure:"-"`
+
+	// Descriptions adds a description directive with the port or pool name to each frontend and
+	// backend, so sections can be identified in the stats page
+	Descriptions bool `mapstructure:"-"`
+
+	// OverwriteBaseSections replaces frontends and backends of the base config which have the labels
+	// of generated sections, instead of failing on the conflict
+	OverwriteBaseSections bool `mapstructure:"-"`
+
+	// DontLogNull sets `option dontlognull` on each frontend, so connections which send no data,
+	// such as health checks, aren't logged
+	DontLogNull bool `mapstructure:"-"`
+
+	// LogFormat and LogFormatSD set the log-format and log-format-sd of each frontend. They are
+	// rendered quoted, so double quotes within them must be escaped.
+	LogFormat   string `mapstructure:"-"`
+	LogFormatSD string `mapstructure:"-"`
+
+	// OmitSingleServerBalance leaves the balance directive out of backends with a single active
+	// server, where it has no effect, keeping their config minimal
+	OmitSingleServerBalance bool `mapstructure:"-"`
+
+	// PlaceholderBackends rejects connections to backends without pools, e.g. of ports provisioned
+	// before their pools, with a 503 in http mode and by closing them in tcp mode, instead of
+	// leaving clients waiting on a backend without servers
+	PlaceholderBackends bool `mapstructure:"-"`
+
+	// Mailers are the smtp servers the email alerts of pools are sent with, generated as the
+	// MailersSection mailers section. None doesn't generate it.
+	Mailers []Mailer
+
+	// DualStack binds the ports without an address family on both ipv4 and ipv6
+	DualStack bool `mapstructure:"-"`
+
+	// MetricsPort generates the MetricsFrontend, serving haproxy's prometheus metrics on the port
+	// at /metrics. Zero doesn't generate it.
+	MetricsPort int64 `mapstructure:"-"`
+}
+
+// GlobalSettings are performance tuning directives set in the global section of the base config.
+// Zero values leave the base config unchanged.
+type GlobalSettings struct {
+	NbThread              int64
+	TuneBufSize           int64
+	TuneSSLCacheSize      int64
+	TuneSSLDefaultDHParam int64
+
+	// NbProc runs several haproxy processes, for legacy builds using processes rather than threads.
+	// It can't be combined with several threads, and the base config must not set it. haproxy 2.5
+	// removed it, so the startup version check rejects it with newer versions.
+	NbProc int64
+
+	// StatsSocket ensures an admin level stats socket at StatsSocketPath, for driving the runtime api
+	StatsSocket bool
+}
+
+// PortSettings contains haproxy settings for the sections generated for a port
+type PortSettings struct {
+	ID string
+
+	// TunnelTimeout sets `timeout tunnel` on the backend, for long-lived connections
+	// such as ssh sessions and websockets which outlive the client/server timeouts
+	TunnelTimeout time.Duration
+
+	// SocketPath binds the frontend to a unix socket instead of the port's tcp address
+	SocketPath string
+
+	// Interface restricts the frontend's tcp bind to a network interface, e.g. eth1
+	Interface string
+
+	// Namespace binds the frontend's tcp address in a network namespace
+	Namespace string
+
+	// AddressFamily is the family of the frontend's tcp bind: ipv4, ipv6, or dual to bind the port
+	// on both. Empty binds ipv4, or both with Settings.DualStack.
+	AddressFamily string
+
+	// Thread pins the frontend's bind to a thread or range of threads, e.g. 1-4, within nbthread
+	Thread string
+
+	// Process pins the frontend's bind to processes within nbproc: all, odd, even, a process or a
+	// range of processes, e.g. 1-2. Like nbproc, it was removed in haproxy 2.5.
+	Process string
+
+	// ExpectProxy requires connections to start with a PROXY protocol header, from the
+	// ExpectProxySources cidrs when set, or from any source otherwise
+	ExpectProxy        bool
+	ExpectProxySources []string
+
+	// ConnRateLimit rejects connections from clients opening connections faster than its limit.
+	// It requires tcp mode.
+	ConnRateLimit ConnRateLimitSettings
+
+	// TCPKA enables tcp keepalives to clients and servers, with option tcpka on the frontend and
+	// its backends. CliTCPKA only enables them to clients.
+	TCPKA    bool
+	CliTCPKA bool
+
+	// Mode is the proxy mode of the frontend and backends, tcp or http. Empty keeps the mode of the
+	// base config's defaults.
+	Mode string
+
+	// ResponseHeaders are rules setting or deleting response headers on the frontend, e.g. to add
+	// security headers or strip Server. They require http mode, and are rendered after use_backend,
+	// which haproxy accepts as response rules are processed after the backend is chosen.
+	ResponseHeaders []HeaderRule
+
+	// UniqueID generates a unique id for each request on the frontend and forwards it to the
+	// servers in a header. It requires http mode.
+	UniqueID UniqueIDSettings
+
+	// ErrorFiles replace the responses haproxy generates for status codes on the frontend, e.g. the
+	// 503 returned when no server is available. They require http mode.
+	ErrorFiles []ErrorFile
+
+	// CrtList terminates tls on the frontend's bind with the certificates of a crt-list file, which
+	// allows a certificate per hostname. With Certs the manager writes the file, which requires a
+	// single dataplaneapi as the file is written where the manager runs, otherwise it must already
+	// exist.
+	CrtList string
+	Certs   []CertRef
+
+	// CrtDir terminates tls on the frontend's bind with every certificate in a directory instead of
+	// a crt-list, haproxy picking the certificate by the hostname clients send by sni. It must be
+	// an absolute path, and can't be set with CrtList.
+	CrtDir string
+
+	// TLS tunes the tls terminated with CrtList or CrtDir, e.g. its alpn protocols and minimum version
+	TLS TLSSettings
+
+	// FailoverPools tiers the pools of the port's default backend for failover, by their IDs. The
+	// servers of the first pool are primaries and those of the following pools are backups, which
+	// only receive connections when every primary server is down, as pools which aren't listed
+	// are primaries. Servers are ordered by tier, and AllBackups balances across all backup pools.
+	FailoverPools []string
+
+	// SplitPools generates a backend for each pool of the port, labeled by the port and pool IDs,
+	// and splits requests in http mode, or connections in tcp mode, between them at random by
+	// SplitWeights, keyed by pool ID, e.g. to send a share of the traffic to a canary pool. Pools
+	// without a weight have a weight of 1, and pools routed by a condition aren't split. Without
+	// it, the pools share the port's default backend.
+	SplitPools   bool
+	SplitWeights map[string]int64
+
+	// RawDirectives are appended verbatim to the frontend
+	RawDirectives []string
+}
+
+// PoolSettings contains haproxy settings for the servers generated for a pool. The pools of
+// a port share a backend, so backend-level settings must agree between them.
+type PoolSettings struct {
+	ID string
+
+	// Balance sets the load balancing algorithm of the backend
+	Balance BalanceSettings
+
+	// RawDirectives are appended verbatim to the backend
+	RawDirectives []string
+
+	// Condition is an acl condition, e.g. `{ src 10.0.0.0/8 }`, which routes connections to a
+	// backend of their own for the pool. Pools without one share the port's default backend.
+	Condition string
+
+	// Backups are the IDs of origins in the pool which only receive connections when the
+	// other servers of the backend are down
+	Backups []string
+
+	// AbortOnClose drops queued connections when the client aborts, `option abortonclose`
+	AbortOnClose bool
+
+	// AllBackups balances across all backup servers instead of only the first, `option allbackups`
+	AllBackups bool
+
+	// LogHealthChecks logs the health check state transitions of servers, `option log-health-checks`
+	LogHealthChecks bool
+
+	// SrvTCPKA enables tcp keepalives to the servers, `option srvtcpka`
+	SrvTCPKA bool
+
+	// SpliceAuto lets haproxy decide when to forward data with kernel splicing, reducing the cpu
+	// used for bulk transfers on linux, `option splice-auto`. SpliceRequest and SpliceResponse
+	// instead always splice in one direction, and are exclusive with it.
+	SpliceAuto     bool
+	SpliceRequest  bool
+	SpliceResponse bool
+
+	// Resolvers names a resolvers section of the base config used to resolve hostname targets.
+	// InitAddr and ResolveOpts set how they are resolved, e.g. `init-addr none` starts haproxy
+	// before a name resolves. Targets which are ip addresses are not resolved.
+	Resolvers   string
+	InitAddr    []string
+	ResolveOpts []string
+
+	// Retries sets `retries` on the backend, the number of times a failed connection to a server
+	// is retried. Zero keeps the defaults.
+	Retries int64
+
+	// RetryOn sets `retry-on` on backends in http mode, the failures which are retried, e.g.
+	// conn-failure, empty-response or a status code. Backends in tcp mode ignore it.
+	RetryOn []string
+
+	// HTTPReuse sets `http-reuse` on backends in http mode, how idle connections to the servers
+	// are shared between requests: never, safe, aggressive or always. Backends in tcp mode ignore
+	// it, and empty keeps the default.
+	HTTPReuse string
+
+	// EmailAlert emails an address when the servers of the backend change state, through the
+	// mailers of the settings
+	EmailAlert EmailAlertSettings
+
+	// ExternalCheck checks the servers by running a command, which also enables external checks in
+	// the global section
+	ExternalCheck ExternalCheckSettings
+
+	// HTTPCheck checks the servers with an http request instead of a connection
+	HTTPCheck HTTPCheckSettings
+
+	// CheckTimeout sets `timeout check` on the backend, how long a health check waits for the
+	// server to respond once connected, e.g. longer for slow servers which are otherwise marked
+	// down. ConnectTimeout sets `timeout connect`, how long connections to the servers, including
+	// those of health checks, wait to be established. Zero values keep the defaults.
+	CheckTimeout   time.Duration
+	ConnectTimeout time.Duration
+
+	// Agent configures an agent check of the servers, through which origins report their state
+	// and weight, e.g. to drain themselves
+	Agent AgentSettings
+
+	// Observe passively checks the servers by observing their traffic, marking them down on errors
+	Observe ObserveSettings
+
+	// Redispatch sets `option redispatch` on the backend, retrying connections to failed servers
+	// on other servers, e.g. when a server of a persistent session is down
+	Redispatch RedispatchSettings
+
+	// Persist keeps connections persistent to servers which are down, `option persist`, and
+	// ForcePersist is an acl condition, e.g. `{ src 10.0.0.0/8 }`, for which connections stay
+	// persistent to servers in maintenance, `force-persist`
+	Persist      bool
+	ForcePersist string
+
+	// MaxQueue sets `maxqueue` on the servers, the number of connections queued for a server
+	// beyond which they are redispatched to other servers, or rejected when all are saturated.
+	// QueueTimeout sets `timeout queue` on the backend, how long a connection waits in the queue
+	// before it is rejected. Zero values keep the defaults.
+	MaxQueue     int64
+	QueueTimeout time.Duration
+
+	// SlowStart sets `slowstart` on the servers, the period over which the weight of a server
+	// ramps up after it comes up, so new or recovered servers don't take their full load at once.
+	// Zero keeps the default of taking it immediately.
+	SlowStart time.Duration
+
+	// SourceAddress sets `source` on the backend, the ip address connections to the servers are
+	// made from, e.g. one allowed by the origins' firewalls. Empty uses the system's choice.
+	SourceAddress string
+}
+
+// AgentSettings is the agent check of a pool's servers. Addr defaults to the origin target and
+// Inter to the health check interval.
+type AgentSettings struct {
+	Check bool
+	Addr  string
+	Port  int64
+	Inter time.Duration
+}
+
+// LoadBalancerSettings contains settings for a loadbalancer which are not part of the loadbalancer
+// api model
+type LoadBalancerSettings struct {
+	ID string
+
+	// Disabled puts the loadbalancer into maintenance without deleting it. Its ports stay bound,
+	// but have no servers: http requests are answered with a 503 and tcp connections are rejected.
+	Disabled bool
+}
+
+// OriginSettings contains settings for an origin which are not part of the loadbalancer api model
+type OriginSettings struct {
+	ID string
+
+	// Region is the ID of the location the origin is in, when it isn't the loadbalancer's
+	Region string
+}
+
+// BalanceSettings is a load balancing algorithm and, for algorithms which accept one, its parameter
+type BalanceSettings struct {
+	Algorithm string
+	Param     string
+}
+
+// HeaderRule is an http-response rule setting or deleting a header
+type HeaderRule struct {
+	// Action is set-header or del-header
+	Action string
+	Name   string
+
+	// Value is the header value for set-header, which may use haproxy's log-format
+	Value string
+}
+
+const (
+	modeTCP  = "tcp"
+	modeHTTP = "http"
+
+	headerActionSet = "set-header"
+	headerActionDel = "del-header"
+)
+
+const (
+	// maxInterfaceNameLength is the longest network interface name linux allows
+	maxInterfaceNameLength = 15
+
+	// maxNamespaceNameLength is the longest network namespace name, which is a file name
+	maxNamespaceNameLength = 255
+
+	// maxPortNumber is the highest tcp port
+	maxPortNumber = 65535
+)
+
+const (
+	paramNone = iota
+	paramOptional
+	paramRequired
+)
+
+// initAddrMethods are the init-addr methods haproxy accepts, besides an ip address
+var initAddrMethods = map[string]bool{
+	"last": true,
+	"libc": true,
+	"none": true,
+}
+
+// resolveOpts are the resolve-opts haproxy accepts
+var resolveOpts = map[string]bool{
+	"allow-dup-ip":   true,
+	"ignore-weight":  true,
+	"prevent-dup-ip": true,
+}
+
+// balanceAlgorithms maps the supported algorithms to whether they take a parameter
+var balanceAlgorithms = map[string]int{
+	"roundrobin": paramNone,
+	"static-rr":  paramNone,
+	"leastconn":  paramNone,
+	"first":      paramNone,
+	"source":     paramNone,
+	"uri":        paramNone,
+	"random":     paramNone,
+	"url_param":  paramRequired,
+	"hdr":        paramRequired,
+	"rdp-cookie": paramOptional,
+}
+
+// validate checks the settings for a port and its pools can be rendered
+func (s Settings) validate(port lbapi.PortNode) error {
+	portSettings := s.port(port.ID)
+
+	if err := portSettings.validate(); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := s.validateRawDirectives(portSettings.RawDirectives); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := portSettings.validateCrtList(s.CertDir); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := portSettings.validateCrtDir(); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := portSettings.validateTLS(); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := portSettings.validateFailoverPools(port, s); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	if err := portSettings.validateSplit(port, s); err != nil {
+		return newLabelError(port.ID, ErrPortSettingsInvalid, err)
+	}
+
+	for _, pool := range port.Pools {
+		poolSettings := s.pool(pool.ID)
+
+		if err := poolSettings.validate(); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+
+		if err := s.validateRawDirectives(poolSettings.RawDirectives); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+
+		if err := poolSettings.EmailAlert.validate(s.Mailers); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+
+		if err := poolSettings.validateBackups(pool, len(portSettings.FailoverPools) > 1); err != nil {
+			return newLabelError(pool.ID, ErrPoolSettingsInvalid, err)
+		}
+	}
+
+	return nil
+}
+
+// validateRawDirectives checks raw directives are allowed and are each a single line
+func (s Settings) validateRawDirectives(directives []string) error {
+	if len(directives) == 0 {
+		return nil
+	}
+
+	if !s.AllowRawDirectives {
+		return ErrRawDirectivesNotAllowed
+	}
+
+	for _, d := range directives {
+		if strings.TrimSpace(d) == "" || strings.ContainsAny(d, "\r\n") {
+			return fmt.Errorf("%w: %q", ErrRawDirectiveInvalid, d)
+		}
+	}
+
+	return nil
+}
+
+// validate checks the port settings can be rendered
+func (p PortSettings) validate() error {
+	if p.SocketPath != "" && !path.IsAbs(p.SocketPath) {
+		return ErrSocketPathNotAbsolute
+	}
+
+	if p.SocketPath != "" && (p.Interface != "" || p.Namespace != "") {
+		return ErrBindScopeWithSocket
+	}
+
+	if p.Interface != "" && !validBindName(p.Interface, maxInterfaceNameLength) {
+		return fmt.Errorf("%w: %q", ErrInterfaceInvalid, p.Interface)
+	}
+
+	if p.Namespace != "" && !validBindName(p.Namespace, maxNamespaceNameLength) {
+		return fmt.Errorf("%w: %q", ErrNamespaceInvalid, p.Namespace)
+	}
+
+	if err := p.validateAddressFamily(); err != nil {
+		return err
+	}
+
+	if err := p.validateExpectProxy(); err != nil {
+		return err
+	}
+
+	if err := p.validateResponseHeaders(); err != nil {
+		return err
+	}
+
+	if err := p.validateConnRateLimit(); err != nil {
+		return err
+	}
+
+	if err := p.UniqueID.validate(p.Mode); err != nil {
+		return err
+	}
+
+	return p.validateErrorFiles()
+}
+
+// validateResponseHeaders checks the mode is supported and the response header rules can be
+// rendered, which is only in http mode
+func (p PortSettings) validateResponseHeaders() error {
+	if p.Mode != "" && p.Mode != modeTCP && p.Mode != modeHTTP {
+		return fmt.Errorf("%w: %q", ErrModeInvalid, p.Mode)
+	}
+
+	if len(p.ResponseHeaders) > 0 && p.Mode != modeHTTP {
+		return ErrResponseHeadersRequireHTTP
+	}
+
+	for _, h := range p.ResponseHeaders {
+		if err := h.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate checks the header rule has a supported action, a single word name, and a value only
+// when setting the header
+func (h HeaderRule) validate() error {
+	switch h.Action {
+	case headerActionSet:
+		if h.Value == "" {
+			return fmt.Errorf("%w %q: set-header requires a value", ErrHeaderRuleInvalid, h.Name)
+		}
+	case headerActionDel:
+		if h.Value != "" {
+			return fmt.Errorf("%w %q: del-header does not accept a value", ErrHeaderRuleInvalid, h.Name)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrHeaderActionInvalid, h.Action)
+	}
+
+	if h.Name == "" || strings.ContainsAny(h.Name, " \t\r\n\":") {
+		return fmt.Errorf("%w: invalid name %q", ErrHeaderRuleInvalid, h.Name)
+	}
+
+	if strings.ContainsAny(h.Value, "\r\n\"") {
+		return fmt.Errorf("%w %q: invalid value %q", ErrHeaderRuleInvalid, h.Name, h.Value)
+	}
+
+	return nil
+}
+
+// responseHeaderRules returns the http-response rules for the response header settings
+func (p PortSettings) responseHeaderRules() []string {
+	rules := []string{}
+
+	for _, h := range p.ResponseHeaders {
+		rule := "http-response " + h.Action + " " + h.Name
+
+		if h.Value != "" {
+			rule += ` "` + h.Value + `"`
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// validateExpectProxy checks the PROXY protocol sources are cidrs, and only set when expecting it
+func (p PortSettings) validateExpectProxy() error {
+	if !p.ExpectProxy && len(p.ExpectProxySources) > 0 {
+		return ErrExpectProxySourcesUnused
+	}
+
+	for _, src := range p.ExpectProxySources {
+		if _, _, err := net.ParseCIDR(src); err != nil {
+			return fmt.Errorf("%w: %q", ErrExpectProxySourceInvalid, src)
+		}
+	}
+
+	return nil
+}
+
+// expectProxyRule returns the tcp-request rule expecting the PROXY protocol, or nothing when it isn't
+func (p PortSettings) expectProxyRule() []string {
+	if !p.ExpectProxy {
+		return nil
+	}
+
+	rule := "tcp-request connection expect-proxy layer4"
+
+	if len(p.ExpectProxySources) > 0 {
+		rule += " if { src " + strings.Join(p.ExpectProxySources, " ") + " }"
+	}
+
+	return []string{rule}
+}
+
+// validateThread checks the bind thread is a thread or ascending range of threads, within nbthread
+// when it is known
+func (p PortSettings) validateThread(nbThread int64) error {
+	if p.Thread == "" {
+		return nil
+	}
+
+	first, last, found := strings.Cut(p.Thread, "-")
+	if !found {
+		last = first
+	}
+
+	from, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || from < 1 {
+		return fmt.Errorf("%w: %q", ErrThreadInvalid, p.Thread)
+	}
+
+	to, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || to < from {
+		return fmt.Errorf("%w: %q", ErrThreadInvalid, p.Thread)
+	}
+
+	if nbThread > 0 && to > nbThread {
+		return fmt.Errorf("%w: %q exceeds nbthread %d", ErrThreadInvalid, p.Thread, nbThread)
+	}
+
+	return nil
+}
+
+// validBindName returns true when the interface or namespace name is a single word within the length limit
+func validBindName(name string, maxLength int) bool {
+	return len(name) <= maxLength && !strings.ContainsAny(name, " \t\r\n/")
+}
+
+// validate checks the pool settings can be rendered
+func (p PoolSettings) validate() error {
+	if err := p.Balance.validate(); err != nil {
+		return err
+	}
+
+	if err := p.Agent.validate(); err != nil {
+		return err
+	}
+
+	if err := p.validateRetries(); err != nil {
+		return err
+	}
+
+	if err := p.validateHTTPReuse(); err != nil {
+		return err
+	}
+
+	if err := p.validateQueue(); err != nil {
+		return err
+	}
+
+	if err := p.validateSlowStart(); err != nil {
+		return err
+	}
+
+	if err := p.Observe.validate(); err != nil {
+		return err
+	}
+
+	if p.SpliceAuto && (p.SpliceRequest || p.SpliceResponse) {
+		return ErrSpliceExclusive
+	}
+
+	if err := p.Redispatch.validate(p.Retries); err != nil {
+		return err
+	}
+
+	if err := p.validatePersist(); err != nil {
+		return err
+	}
+
+	if err := p.ExternalCheck.validate(); err != nil {
+		return err
+	}
+
+	if err := p.HTTPCheck.validate(); err != nil {
+		return err
+	}
+
+	if err := p.validateCheckTimeouts(); err != nil {
+		return err
+	}
+
+	if p.HTTPCheck.Path != "" && p.ExternalCheck.Command != "" {
+		return ErrHTTPCheckWithExternalCheck
+	}
+
+	if p.SourceAddress != "" && net.ParseIP(p.SourceAddress) == nil {
+		return fmt.Errorf("%w: %q", ErrSourceAddressInvalid, p.SourceAddress)
+	}
+
+	return p.validateResolution()
+}
+
+// validateResolution checks the init-addr methods and resolve-opts are ones haproxy accepts, and
+// are only set along with resolvers
+func (p PoolSettings) validateResolution() error {
+	if p.Resolvers == "" && (len(p.InitAddr) > 0 || len(p.ResolveOpts) > 0) {
+		return ErrResolversRequired
+	}
+
+	for _, method := range p.InitAddr {
+		if !initAddrMethods[method] && net.ParseIP(method) == nil {
+			return fmt.Errorf("%w: %q", ErrInitAddrInvalid, method)
+		}
+	}
+
+	for _, opt := range p.ResolveOpts {
+		if !resolveOpts[opt] {
+			return fmt.Errorf("%w: %q", ErrResolveOptInvalid, opt)
+		}
+	}
+
+	return nil
+}
+
+// resolution returns the server params resolving a hostname target, or nothing when the pool
+// doesn't use resolvers
+func (p PoolSettings) resolution() string {
+	if p.Resolvers == "" {
+		return ""
+	}
+
+	params := " resolvers " + p.Resolvers
+
+	if len(p.InitAddr) > 0 {
+		params += " init-addr " + strings.Join(p.InitAddr, ",")
+	}
+
+	if len(p.ResolveOpts) > 0 {
+		params += " resolve-opts " + strings.Join(p.ResolveOpts, ",")
+	}
+
+	return params
+}
+
+// validate checks the agent check has a port, and that its other settings are only set along with it
+func (a AgentSettings) validate() error {
+	if !a.Check {
+		if a.Addr != "" || a.Port != 0 || a.Inter != 0 {
+			return ErrAgentCheckRequired
+		}
+
+		return nil
+	}
+
+	if a.Port == 0 {
+		return ErrAgentPortRequired
+	}
+
+	if a.Port < 1 || a.Port > maxPortNumber {
+		return fmt.Errorf("%w: %d", ErrAgentPortInvalid, a.Port)
+	}
+
+	if a.Addr != "" && !validTarget(a.Addr) {
+		return fmt.Errorf("%w: %q", ErrAgentAddrInvalid, a.Addr)
+	}
+
+	if a.Inter < 0 {
+		return fmt.Errorf("%w: %s", ErrAgentInterInvalid, a.Inter)
+	}
+
+	return nil
+}
+
+// params returns the server params of the agent check, or nothing when it isn't enabled
+func (a AgentSettings) params() string {
+	if !a.Check {
+		return ""
+	}
+
+	params := fmt.Sprintf(" agent-check agent-port %d", a.Port)
+
+	if a.Addr != "" {
+		params += " agent-addr " + a.Addr
+	}
+
+	if a.Inter > 0 {
+		params += " agent-inter " + haproxyDuration(a.Inter)
+	}
+
+	return params
+}
+
+// validateBackups checks the backups are origins of the pool, and that there are backups when
+// all of them are to be used, either origins of the pool or pools of a port tiered for failover
+func (p PoolSettings) validateBackups(pool lbapi.Pool, tiered bool) error {
+	for _, id := range p.Backups {
+		if !p.hasOrigin(pool, id) {
+			return fmt.Errorf("%w: %q", ErrBackupOriginNotFound, id)
+		}
+	}
+
+	if p.AllBackups && len(p.Backups) == 0 && !tiered {
+		return ErrAllBackupsWithoutBackups
+	}
+
+	return nil
+}
+
+// hasOrigin returns true when the pool has an origin with the given ID
+func (p PoolSettings) hasOrigin(pool lbapi.Pool, id string) bool {
+	for _, origin := range pool.Origins.Edges {
+		if origin.Node.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backup returns true when the origin is one of the pool's backups
+func (p PoolSettings) backup(id string) bool {
+	for _, backup := range p.Backups {
+		if backup == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate checks the algorithm is supported and is given a parameter only when it accepts one
+func (b BalanceSettings) validate() error {
+	if b.Algorithm == "" {
+		if b.Param != "" {
+			return ErrBalanceParamNotSupported
+		}
+
+		return nil
+	}
+
+	param, ok := balanceAlgorithms[b.Algorithm]
+
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %q", ErrBalanceAlgorithmInvalid, b.Algorithm)
+	case param == paramNone && b.Param != "":
+		return fmt.Errorf("%w: %q", ErrBalanceParamNotSupported, b.Algorithm)
+	case param == paramRequired && b.Param == "":
+		return fmt.Errorf("%w: %q", ErrBalanceParamRequired, b.Algorithm)
+	}
+
+	return nil
+}
+
+// String formats the algorithm and parameter as the arguments of the balance directive
+func (b BalanceSettings) String() string {
+	switch {
+	case b.Param == "":
+		return b.Algorithm
+	case b.Algorithm == "url_param":
+		return b.Algorithm + " " + b.Param
+	default:
+		return fmt.Sprintf("%s(%s)", b.Algorithm, b.Param)
+	}
+}
+
+// port returns the settings for the port with the given ID, or empty settings when there are none
+func (s Settings) port(id string) PortSettings {
+	for _, p := range s.Ports {
+		if p.ID == id {
+			return p
+		}
+	}
+
+	return PortSettings{ID: id}
+}
+
+// pool returns the settings for the pool with the given ID, or empty settings when there are none
+func (s Settings) pool(id string) PoolSettings {
+	for _, p := range s.Pools {
+		if p.ID == id {
+			return p
+		}
+	}
+
+	return PoolSettings{ID: id}
+}
+
+// origin returns the settings for an origin, or empty settings when there are none
+func (s Settings) origin(id string) OriginSettings {
+	for _, o := range s.Origins {
+		if o.ID == id {
+			return o
+		}
+	}
+
+	return OriginSettings{ID: id}
+}
+
+// backendSetting returns the value the pools of a backend set for a backend-level setting. Pools
+// which don't set the value are ignored, the others must agree on it.
+func backendSetting[T comparable](s Settings, b backend, name string, get func(PoolSettings) T) (T, error) {
+	var zero, value T
+
+	for _, pool := range b.pools {
+		v := get(s.pool(pool.ID))
+
+		if v == zero {
+			continue
+		}
+
+		if value != zero && v != value {
+			return zero, fmt.Errorf("%w %q: pools disagree on %s", ErrPoolSettingsConflict, b.label, name)
+		}
+
+		value = v
+	}
+
+	return value, nil
+}
+
+// haproxyDuration formats a duration in the largest haproxy time unit that represents it exactly
+func haproxyDuration(d time.Duration) string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+		{"ms", time.Millisecond},
+	}
+
+	for _, u := range units {
+		if d%u.size == 0 {
+			return fmt.Sprintf("%d%s", d/u.size, u.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%dus", d.Microseconds())
+}
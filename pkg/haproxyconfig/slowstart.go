@@ -0,0 +1,25 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"time"
+)
+
+// validateSlowStart checks the slowstart period isn't negative and is whole milliseconds
+func (p PoolSettings) validateSlowStart() error {
+	if p.SlowStart < 0 || p.SlowStart%time.Millisecond != 0 {
+		return fmt.Errorf("%w: %s is not positive whole milliseconds", ErrSlowStartInvalid, p.SlowStart)
+	}
+
+	return nil
+}
+
+// slowStartParams returns the server params ramping up the weight of servers coming up, or nothing
+// when the pool doesn't ramp it up
+func (p PoolSettings) slowStartParams() string {
+	if p.SlowStart == 0 {
+		return ""
+	}
+
+	return " slowstart " + haproxyDuration(p.SlowStart)
+}
@@ -0,0 +1,112 @@
+package haproxyconfig
+
+import (
+	"fmt"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// splitVar is the variable holding the random number which picks the pool of a request or
+// connection to a port splitting its pools
+const splitVar = "txn.split"
+
+// validateSplit checks split weights are positive and set only for pools split by the port, and
+// that split ports don't also tier their pools for failover
+func (p PortSettings) validateSplit(port lbapi.PortNode, settings Settings) error {
+	if len(p.SplitWeights) > 0 && !p.SplitPools {
+		return fmt.Errorf("%w: weights set without splitting pools", ErrSplitInvalid)
+	}
+
+	if p.SplitPools && len(p.FailoverPools) > 0 {
+		return fmt.Errorf("%w: failover pools share a backend", ErrSplitInvalid)
+	}
+
+	for id, weight := range p.SplitWeights {
+		if weight <= 0 {
+			return fmt.Errorf("%w %q: weight %d is not positive", ErrSplitInvalid, id, weight)
+		}
+
+		found := false
+
+		for _, pool := range port.Pools {
+			if pool.ID == id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%w: %q", ErrSplitPoolNotFound, id)
+		}
+
+		// routed pools have a backend of their own, which connections are routed to by condition
+		if settings.pool(id).Condition != "" {
+			return fmt.Errorf("%w %q: routed by a condition", ErrSplitInvalid, id)
+		}
+	}
+
+	return nil
+}
+
+// splitWeight returns the weight of a pool in the port's split, 1 when it isn't set
+func (p PortSettings) splitWeight(id string) int64 {
+	if weight, ok := p.SplitWeights[id]; ok {
+		return weight
+	}
+
+	return 1
+}
+
+// splitBackends returns a backend for each of the pools split by a port, labeled by the port and
+// pool IDs. Each backend but the last is routed to when the port's random number is within the
+// range of its weight, and the last is the default backend, taking the rest.
+func splitBackends(port lbapi.PortNode, pools []lbapi.Pool, portSettings PortSettings) (backend, []backend) {
+	routed := []backend{}
+
+	var upper int64
+
+	for _, pool := range pools[:len(pools)-1] {
+		upper += portSettings.splitWeight(pool.ID)
+
+		routed = append(routed, backend{
+			label: fmt.Sprintf("%s-%s", port.ID, pool.ID),
+			cond:  fmt.Sprintf("{ var(%s) -m int lt %d }", splitVar, upper),
+			pools: []lbapi.Pool{pool},
+		})
+	}
+
+	last := pools[len(pools)-1]
+
+	return backend{label: fmt.Sprintf("%s-%s", port.ID, last.ID), pools: []lbapi.Pool{last}}, routed
+}
+
+// splitRule returns the rule drawing the random number which picks the pool of each request, in
+// http mode, or connection, in tcp mode, or nothing when the port doesn't split several pools
+func (p PortSettings) splitRule(mode string, port lbapi.PortNode, settings Settings) []string {
+	if !p.SplitPools {
+		return nil
+	}
+
+	var total int64
+
+	split := 0
+
+	for _, pool := range port.Pools {
+		if settings.pool(pool.ID).Condition == "" {
+			total += p.splitWeight(pool.ID)
+			split++
+		}
+	}
+
+	if split < 2 {
+		return nil
+	}
+
+	action := fmt.Sprintf("set-var(%s) rand(%d)", splitVar, total)
+
+	if mode == modeHTTP {
+		return []string{"http-request " + action}
+	}
+
+	return []string{"tcp-request content " + action}
+}
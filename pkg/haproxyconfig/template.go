@@ -0,0 +1,47 @@
+package haproxyconfig
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// ParseTemplate parses a config template. Templates only have the text/template builtins, so they
+// can't read files or reach anything beyond the loadbalancer they are rendered with.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, newAttrError(ErrConfigTemplateInvalid, err)
+	}
+
+	return tmpl, nil
+}
+
+// ApplyTemplate renders the template with the loadbalancer as its data, and appends the result to
+// the config. The combined config is parsed again, so the template can add whole sections.
+func ApplyTemplate(cfg parser.Parser, tmpl *template.Template, lb *lbapi.LoadBalancer) (parser.Parser, error) {
+	buf := &bytes.Buffer{}
+
+	if err := tmpl.Execute(buf, lb); err != nil {
+		return nil, newAttrError(ErrConfigTemplateFailure, err)
+	}
+
+	overlay := strings.TrimSpace(buf.String())
+	if overlay == "" {
+		return cfg, nil
+	}
+
+	combined := strings.TrimSpace(cfg.String()) + "\n\n" + overlay + "\n"
+
+	combinedCfg, err := parser.New(options.Reader(strings.NewReader(combined)), options.NoNamedDefaultsFrom)
+	if err != nil {
+		return nil, newAttrError(ErrConfigTemplateFailure, err)
+	}
+
+	return combinedCfg, nil
+}
@@ -0,0 +1,80 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	parser "github.com/haproxytech/config-parser/v4"
+	"github.com/haproxytech/config-parser/v4/options"
+	"github.com/haproxytech/config-parser/v4/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCustomBackendTemplate = `
+backend custom-{{ .ID }}
+  balance first
+{{- range .Ports.Edges }}{{ range .Node.Pools }}{{ range .Origins.Edges }}
+  server {{ .Node.ID }} {{ .Node.Target }}:{{ .Node.PortNumber }}
+{{- end }}{{ end }}{{ end }}
+`
+
+func TestApplyTemplate(t *testing.T) {
+	newCfg := func() parser.Parser {
+		cfg, err := parser.New(options.Path(testBaseCfgPath), options.NoNamedDefaultsFrom)
+		require.Nil(t, err)
+
+		merged, err := Merge(cfg, &mergeTestData1, Settings{})
+		require.NoError(t, err)
+
+		return merged
+	}
+
+	t.Run("custom backend", func(t *testing.T) {
+		tmpl, err := ParseTemplate("custom", testCustomBackendTemplate)
+		require.NoError(t, err)
+
+		cfg, err := ApplyTemplate(newCfg(), tmpl, &mergeTestData1)
+		require.NoError(t, err)
+
+		backends, err := cfg.SectionsGet(parser.Backends)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"loadprt-test", "custom-loadbal-test"}, backends)
+
+		servers, err := cfg.Get(parser.Backends, "custom-loadbal-test", "server")
+		require.NoError(t, err)
+		assert.Len(t, servers, 3)
+		assert.Equal(t, "loadogn-test1", servers.([]types.Server)[0].Name)
+
+		// the generated sections are unchanged
+		assert.Contains(t, cfg.String(), "backend loadprt-test\n")
+	})
+
+	t.Run("empty output", func(t *testing.T) {
+		tmpl, err := ParseTemplate("empty", "{{ if false }}backend unused{{ end }}")
+		require.NoError(t, err)
+
+		merged := newCfg()
+
+		cfg, err := ApplyTemplate(merged, tmpl, &mergeTestData1)
+		require.NoError(t, err)
+		assert.Equal(t, merged.String(), cfg.String())
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := ParseTemplate("invalid", "backend {{ .ID ")
+		assert.ErrorIs(t, err, ErrConfigTemplateInvalid)
+	})
+
+	t.Run("no filesystem funcs", func(t *testing.T) {
+		_, err := ParseTemplate("readfile", `{{ readFile "/etc/passwd" }}`)
+		assert.ErrorIs(t, err, ErrConfigTemplateInvalid)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		tmpl, err := ParseTemplate("unknown", "backend {{ .Unknown }}")
+		require.NoError(t, err)
+
+		_, err = ApplyTemplate(newCfg(), tmpl, &mergeTestData1)
+		assert.ErrorIs(t, err, ErrConfigTemplateFailure)
+	})
+}
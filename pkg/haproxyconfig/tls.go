@@ -0,0 +1,295 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+var (
+	// sniFilterRegex matches a crt-list sni filter, a hostname or wildcard optionally negated with !
+	sniFilterRegex = regexp.MustCompile(`^!?(\*\.)?[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+	// alpnProtocolRegex matches an alpn protocol id, e.g. h2 or http/1.1
+	alpnProtocolRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9./-]*$`)
+
+	// ciphersRegex matches an openssl cipher list, e.g. ECDHE+AESGCM:!aNULL
+	ciphersRegex = regexp.MustCompile(`^[a-zA-Z0-9_:!+@=.-]+$`)
+
+	// earlyDataPathRegex matches a path prefix of an early data rule, which must be a single word
+	earlyDataPathRegex = regexp.MustCompile(`^/[^\s"'#{}\\]*$`)
+)
+
+// tlsVersions are the protocol versions haproxy accepts for ssl-min-ver and ssl-max-ver, in order
+var tlsVersions = []string{"SSLv3", "TLSv1.0", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+
+// TLSSettings tune the tls terminated on a port's bind. Empty values keep haproxy's defaults.
+type TLSSettings struct {
+	// ALPN are the protocols advertised to clients, in order of preference, e.g. h2 and http/1.1
+	ALPN []string
+
+	// Ciphers is the openssl cipher list of TLSv1.2 and earlier
+	Ciphers string
+
+	// MinVersion and MaxVersion bound the protocol versions accepted, e.g. TLSv1.2
+	MinVersion string
+	MaxVersion string
+
+	// EarlyData accepts TLSv1.3 early data (0-RTT), which saves a round trip on resumed sessions
+	// but can be replayed by an attacker
+	EarlyData bool
+
+	// WaitForHandshake delays processing requests received as early data until the handshake
+	// completes, protecting them from replay. It requires http mode.
+	WaitForHandshake bool
+
+	// EarlyDataRejectPaths are path prefixes of requests rejected with a 425 Too Early when
+	// received as early data, so clients retry them once the handshake completes, e.g. for
+	// requests which aren't safe to replay. They require http mode.
+	EarlyDataRejectPaths []string
+}
+
+// CertRef is a certificate of a managed crt-list and the sni filters selecting it. A relative Cert
+// is in Settings.CertDir.
+type CertRef struct {
+	Cert       string
+	SNIFilters []string
+}
+
+// validateCrtList checks the crt-list is an absolute path, and that the certificates of a managed
+// one are clean paths, within the cert dir when relative, with valid sni filters
+func (p PortSettings) validateCrtList(certDir string) error {
+	if p.CrtList == "" {
+		if len(p.Certs) > 0 {
+			return ErrCrtListRequired
+		}
+
+		return nil
+	}
+
+	if !validTLSPath(p.CrtList) || !path.IsAbs(p.CrtList) {
+		return fmt.Errorf("%w: %q", ErrCrtListInvalid, p.CrtList)
+	}
+
+	for _, c := range p.Certs {
+		if !validTLSPath(c.Cert) || c.Cert == ".." || strings.HasPrefix(c.Cert, "../") {
+			return fmt.Errorf("%w: %q", ErrCertInvalid, c.Cert)
+		}
+
+		if !path.IsAbs(c.Cert) && certDir == "" {
+			return fmt.Errorf("%w: %q", ErrCertDirRequired, c.Cert)
+		}
+
+		for _, f := range c.SNIFilters {
+			if !sniFilterRegex.MatchString(f) {
+				return fmt.Errorf("%w: %q", ErrSNIFilterInvalid, f)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCrtDir checks the crt dir is an absolute path other than the root, which may end with a
+// slash, and isn't set with a crt-list
+func (p PortSettings) validateCrtDir() error {
+	if p.CrtDir == "" {
+		return nil
+	}
+
+	if p.CrtList != "" {
+		return ErrCrtDirConflict
+	}
+
+	dir := strings.TrimSuffix(p.CrtDir, "/")
+
+	if !validTLSPath(dir) || !path.IsAbs(dir) || dir == "/" {
+		return fmt.Errorf("%w: %q", ErrCrtDirInvalid, p.CrtDir)
+	}
+
+	return nil
+}
+
+// terminatesTLS returns true when the port's bind terminates tls with a crt-list or crt dir
+func (p PortSettings) terminatesTLS() bool {
+	return p.CrtList != "" || p.CrtDir != ""
+}
+
+// validateTLS checks tls options are only set when terminating tls, with alpn protocols, ciphers
+// and early data paths which can be rendered, early data rules in http mode, and supported
+// protocol versions in order
+func (p PortSettings) validateTLS() error {
+	t := p.TLS
+
+	if !p.terminatesTLS() && (len(t.ALPN) > 0 || t.Ciphers != "" || t.MinVersion != "" || t.MaxVersion != "" ||
+		t.EarlyData || t.WaitForHandshake || len(t.EarlyDataRejectPaths) > 0) {
+		return ErrTLSOptionsRequireTLS
+	}
+
+	if (t.WaitForHandshake || len(t.EarlyDataRejectPaths) > 0) && p.Mode != modeHTTP {
+		return ErrEarlyDataRulesRequireHTTP
+	}
+
+	for _, prefix := range t.EarlyDataRejectPaths {
+		if !earlyDataPathRegex.MatchString(prefix) {
+			return fmt.Errorf("%w: early data reject path %q", ErrTLSOptionInvalid, prefix)
+		}
+	}
+
+	for _, proto := range t.ALPN {
+		if !alpnProtocolRegex.MatchString(proto) {
+			return fmt.Errorf("%w: alpn protocol %q", ErrTLSOptionInvalid, proto)
+		}
+	}
+
+	if t.Ciphers != "" && !ciphersRegex.MatchString(t.Ciphers) {
+		return fmt.Errorf("%w: ciphers %q", ErrTLSOptionInvalid, t.Ciphers)
+	}
+
+	minVersion, maxVersion := tlsVersionIndex(t.MinVersion), tlsVersionIndex(t.MaxVersion)
+
+	if t.MinVersion != "" && minVersion < 0 {
+		return fmt.Errorf("%w: %q", ErrTLSVersionInvalid, t.MinVersion)
+	}
+
+	if t.MaxVersion != "" && maxVersion < 0 {
+		return fmt.Errorf("%w: %q", ErrTLSVersionInvalid, t.MaxVersion)
+	}
+
+	if t.MinVersion != "" && t.MaxVersion != "" && minVersion > maxVersion {
+		return fmt.Errorf("%w: minimum %s is above maximum %s", ErrTLSVersionInvalid, t.MinVersion, t.MaxVersion)
+	}
+
+	return nil
+}
+
+// tlsVersionIndex returns the position of a protocol version in tlsVersions, or -1 when it isn't one
+func tlsVersionIndex(version string) int {
+	for i, v := range tlsVersions {
+		if v == version {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// validTLSPath returns true when p is a clean path without whitespace, which would split its line
+func validTLSPath(p string) bool {
+	return p != "" && path.Clean(p) == p && !strings.ContainsAny(p, " \t\r\n\"'#")
+}
+
+// tlsBind returns the bind params terminating tls with the port's crt-list or crt dir and tls
+// options, or nothing without either. The crt dir is rendered with a trailing slash.
+func (p PortSettings) tlsBind() string {
+	var bind string
+
+	switch {
+	case p.CrtList != "":
+		bind = " ssl crt-list " + p.CrtList
+	case p.CrtDir != "":
+		bind = " ssl crt " + strings.TrimSuffix(p.CrtDir, "/") + "/"
+	default:
+		return ""
+	}
+
+	if len(p.TLS.ALPN) > 0 {
+		bind += " alpn " + strings.Join(p.TLS.ALPN, ",")
+	}
+
+	if p.TLS.Ciphers != "" {
+		bind += " ciphers " + p.TLS.Ciphers
+	}
+
+	if p.TLS.MinVersion != "" {
+		bind += " ssl-min-ver " + p.TLS.MinVersion
+	}
+
+	if p.TLS.MaxVersion != "" {
+		bind += " ssl-max-ver " + p.TLS.MaxVersion
+	}
+
+	if p.TLS.EarlyData {
+		bind += " allow-0rtt"
+	}
+
+	return bind
+}
+
+// earlyDataRules returns the http-request rules rejecting requests to the early data reject paths
+// and waiting for the handshake, in that order, as requests are no longer early data once the
+// handshake completes
+func (p PortSettings) earlyDataRules() []string {
+	if !p.terminatesTLS() {
+		return nil
+	}
+
+	rules := []string{}
+
+	if len(p.TLS.EarlyDataRejectPaths) > 0 {
+		rules = append(rules, "http-request deny deny_status 425 if { ssl_fc_has_early } { path_beg "+strings.Join(p.TLS.EarlyDataRejectPaths, " ")+" }")
+	}
+
+	if p.TLS.WaitForHandshake {
+		rules = append(rules, "http-request wait-for-handshake")
+	}
+
+	return rules
+}
+
+// CrtLists returns the contents of the crt-lists managed for the loadbalancer's ports, keyed by
+// their path. Each line is a certificate followed by its sni filters. Ports sharing a crt-list
+// path share its contents, their certificates are listed in port order.
+func CrtLists(lb *lbapi.LoadBalancer, settings Settings) map[string]string {
+	lines := map[string][]string{}
+
+	for _, p := range lb.Ports.Edges {
+		portSettings := settings.port(p.Node.ID)
+
+		for _, c := range portSettings.Certs {
+			cert := c.Cert
+			if !path.IsAbs(cert) {
+				cert = path.Join(settings.CertDir, cert)
+			}
+
+			lines[portSettings.CrtList] = append(lines[portSettings.CrtList], strings.Join(append([]string{cert}, c.SNIFilters...), " "))
+		}
+	}
+
+	lists := make(map[string]string, len(lines))
+
+	for crtList, l := range lines {
+		lists[crtList] = strings.Join(l, "\n") + "\n"
+	}
+
+	return lists
+}
+
+// ManagesCrtLists returns true when the settings list certificates for a port's crt-list, which
+// the manager writes
+func (s Settings) ManagesCrtLists() bool {
+	for _, p := range s.Ports {
+		if len(p.Certs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CrtListPaths returns the paths of the managed crt-lists, sorted
+func CrtListPaths(lists map[string]string) []string {
+	paths := make([]string, 0, len(lists))
+
+	for p := range lists {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
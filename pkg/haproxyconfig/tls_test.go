@@ -0,0 +1,40 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrtLists(t *testing.T) {
+	t.Run("unmanaged crt-lists aren't written", func(t *testing.T) {
+		settings := Settings{
+			Ports: []PortSettings{{ID: "loadprt-testhttps", CrtList: "/etc/haproxy/crt-list.txt"}},
+		}
+
+		assert.Empty(t, CrtLists(&mergeTestData3, settings))
+		assert.False(t, settings.ManagesCrtLists())
+	})
+
+	t.Run("managed crt-lists", func(t *testing.T) {
+		settings := Settings{
+			CertDir: "/etc/haproxy/certs",
+			Ports: []PortSettings{
+				{ID: "loadprt-testhttp", CrtList: "/etc/haproxy/shared.txt", Certs: []CertRef{
+					{Cert: "www.pem", SNIFilters: []string{"www.example.com", "*.example.com", "!admin.example.com"}},
+				}},
+				{ID: "loadprt-testhttps", CrtList: "/etc/haproxy/shared.txt", Certs: []CertRef{
+					{Cert: "/srv/certs/default.pem"},
+				}},
+			},
+		}
+
+		lists := CrtLists(&mergeTestData3, settings)
+
+		assert.Equal(t, map[string]string{
+			"/etc/haproxy/shared.txt": "/etc/haproxy/certs/www.pem www.example.com *.example.com !admin.example.com\n/srv/certs/default.pem\n",
+		}, lists)
+		assert.Equal(t, []string{"/etc/haproxy/shared.txt"}, CrtListPaths(lists))
+		assert.True(t, settings.ManagesCrtLists())
+	})
+}
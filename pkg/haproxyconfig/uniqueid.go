@@ -0,0 +1,75 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// headerNameChars are the characters of http header names, the tchar of rfc 7230 beside letters and digits
+const headerNameChars = "!#$%&'*+-.^_`|~"
+
+// UniqueIDSettings generate a unique id for each request on a frontend, e.g. for tracing requests
+// across services. Format is the log format of the id, e.g. `%{+X}o\ %ci:%cp_%fi:%fp_%Ts_%rt:%pid`,
+// which logs can include with %ID, and Header names the request header forwarding it to the
+// servers, e.g. X-Request-ID. They require http mode.
+type UniqueIDSettings struct {
+	Format string
+	Header string
+}
+
+// validate checks the unique id is only generated in http mode, its format is a valid log format,
+// and its header is a valid header name set along with a format
+func (u UniqueIDSettings) validate(mode string) error {
+	if u.Format == "" && u.Header == "" {
+		return nil
+	}
+
+	if mode != modeHTTP {
+		return ErrUniqueIDRequiresHTTP
+	}
+
+	if u.Format == "" {
+		return fmt.Errorf("%w %q: requires a format", ErrUniqueIDHeaderInvalid, u.Header)
+	}
+
+	if err := validateLogFormat("unique-id-format", u.Format); err != nil {
+		return err
+	}
+
+	if u.Header != "" && !validHeaderName(u.Header) {
+		return fmt.Errorf("%w: %q", ErrUniqueIDHeaderInvalid, u.Header)
+	}
+
+	return nil
+}
+
+// rules returns the directives generating the unique id and forwarding it, or nothing when no id
+// is generated. The format is quoted so spaces within it are kept.
+func (u UniqueIDSettings) rules() []string {
+	if u.Format == "" {
+		return nil
+	}
+
+	rules := []string{`unique-id-format "` + u.Format + `"`}
+
+	if u.Header != "" {
+		rules = append(rules, "unique-id-header "+u.Header)
+	}
+
+	return rules
+}
+
+// validHeaderName returns true when the name is an http header name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune(headerNameChars, c)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,53 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+const maxHostnameLength = 253
+
+var hostnameLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// validateOrigins checks every origin target of the loadbalancer is an ip address or hostname
+func validateOrigins(lb *lbapi.LoadBalancer) error {
+	for _, p := range lb.Ports.Edges {
+		for _, pool := range p.Node.Pools {
+			for _, origin := range pool.Origins.Edges {
+				if !validTarget(origin.Node.Target) {
+					return fmt.Errorf("%w %q: %q", ErrOriginTargetInvalid, origin.Node.ID, origin.Node.Target)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validTarget returns true when the target is an ip address or a dns-valid hostname
+func validTarget(target string) bool {
+	if net.ParseIP(target) != nil {
+		return true
+	}
+
+	target = strings.TrimSuffix(target, ".")
+
+	if target == "" || len(target) > maxHostnameLength {
+		return false
+	}
+
+	labels := strings.Split(target, ".")
+
+	for _, label := range labels {
+		if !hostnameLabelRegex.MatchString(label) {
+			return false
+		}
+	}
+
+	// an all-numeric top level label is a malformed ip address, not a hostname
+	return strings.Trim(labels[len(labels)-1], "0123456789") != ""
+}
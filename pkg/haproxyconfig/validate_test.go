@@ -0,0 +1,79 @@
+package haproxyconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	lbapi "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+func TestValidTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		valid  bool
+	}{
+		{"ipv4 address", "1.2.3.4", true},
+		{"ipv6 address", "2001:db8::1", true},
+		{"hostname", "origin-1.example.com", true},
+		{"fully qualified hostname", "origin.example.com.", true},
+		{"single label hostname", "localhost", true},
+		{"empty", "", false},
+		{"garbage", "not a host!", false},
+		{"malformed ip address", "1.2.3.999", false},
+		{"label starting with hyphen", "-origin.example.com", false},
+		{"empty label", "origin..example.com", false},
+	}
+
+	for _, tt := range tests {
+		// go vet
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.valid, validTarget(tt.target))
+		})
+	}
+}
+
+func TestValidateOrigins(t *testing.T) {
+	require.NoError(t, validateOrigins(&mergeTestData1))
+
+	lb := lbapi.LoadBalancer{
+		ID: "loadbal-test",
+		Ports: lbapi.Ports{
+			Edges: []lbapi.PortEdges{
+				{
+					Node: lbapi.PortNode{
+						ID:     "loadprt-test",
+						Number: 22,
+						Pools: []lbapi.Pool{
+							{
+								ID: "loadpol-test",
+								Origins: lbapi.Origins{
+									Edges: []lbapi.OriginEdges{
+										{
+											Node: lbapi.OriginNode{
+												ID:         "loadogn-garbage",
+												Target:     "1.2.3.4:22",
+												PortNumber: 22,
+												Active:     true,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := validateOrigins(&lb)
+	require.ErrorIs(t, err, ErrOriginTargetInvalid)
+	assert.ErrorContains(t, err, "loadogn-garbage")
+}
@@ -0,0 +1,159 @@
+package haproxyconfig
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionRegex matches the numeric release at the start of an haproxy version, e.g. 2.6.6 of
+// 2.6.6-274d1a4 2022/09/22
+var versionRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*`)
+
+// featureVersions are the oldest haproxy versions supporting the directives generated for
+// settings, for directives older versions reject
+var featureVersions = map[string]string{
+	"http-check send":     "2.2",
+	"retry-on":            "2.0",
+	"prometheus-exporter": "2.0",
+	"ssl-min-ver":         "1.8",
+	"ssl-max-ver":         "1.8",
+	"allow-0rtt":          "1.8",
+}
+
+// removedVersions are the haproxy versions which removed directives generated for settings, for
+// directives newer versions reject
+var removedVersions = map[string]string{
+	"nbproc":       "2.5",
+	"bind process": "2.5",
+}
+
+// VersionRequirement is a directive generated for the settings which the haproxy version doesn't
+// support: either it is older than MinVersion, or RemovedIn removed the directive
+type VersionRequirement struct {
+	Feature    string
+	MinVersion string
+	RemovedIn  string
+}
+
+// features returns the directives of featureVersions generated for the settings
+func (s Settings) features() map[string]bool {
+	used := map[string]bool{
+		"prometheus-exporter": s.MetricsPort != 0,
+		"nbproc":              s.Global.NbProc > 0,
+	}
+
+	for _, p := range s.Pools {
+		used["http-check send"] = used["http-check send"] || p.HTTPCheck.Path != ""
+		used["retry-on"] = used["retry-on"] || len(p.RetryOn) > 0
+	}
+
+	for _, p := range s.Ports {
+		used["ssl-min-ver"] = used["ssl-min-ver"] || p.TLS.MinVersion != ""
+		used["ssl-max-ver"] = used["ssl-max-ver"] || p.TLS.MaxVersion != ""
+		used["allow-0rtt"] = used["allow-0rtt"] || p.TLS.EarlyData
+		used["bind process"] = used["bind process"] || p.Process != ""
+	}
+
+	return used
+}
+
+// UnsupportedFeatures returns the directives generated for the settings which the haproxy version
+// doesn't support, because it is too old or removed them, sorted by directive.
+// ErrHAProxyVersionInvalid is returned when the version doesn't start with a numeric release.
+func UnsupportedFeatures(settings Settings, version string) ([]VersionRequirement, error) {
+	unsupported := []VersionRequirement{}
+
+	for feature, used := range settings.features() {
+		if !used {
+			continue
+		}
+
+		if minVersion, ok := featureVersions[feature]; ok {
+			cmp, err := CompareVersions(version, minVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			if cmp < 0 {
+				unsupported = append(unsupported, VersionRequirement{Feature: feature, MinVersion: minVersion})
+			}
+		}
+
+		if removed, ok := removedVersions[feature]; ok {
+			cmp, err := CompareVersions(version, removed)
+			if err != nil {
+				return nil, err
+			}
+
+			if cmp >= 0 {
+				unsupported = append(unsupported, VersionRequirement{Feature: feature, RemovedIn: removed})
+			}
+		}
+	}
+
+	sort.Slice(unsupported, func(i, j int) bool {
+		return unsupported[i].Feature < unsupported[j].Feature
+	})
+
+	return unsupported, nil
+}
+
+// CompareVersions compares the numeric releases of two haproxy versions, returning -1 when a is
+// older than b, 1 when it is newer and 0 when they are the same release. Missing components are
+// zero, so 2.2 is the same release as 2.2.0.
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var ca, cb int64
+
+		if i < len(va) {
+			ca = va[i]
+		}
+
+		if i < len(vb) {
+			cb = vb[i]
+		}
+
+		switch {
+		case ca < cb:
+			return -1, nil
+		case ca > cb:
+			return 1, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// parseVersion returns the components of the numeric release of an haproxy version
+func parseVersion(version string) ([]int64, error) {
+	release := versionRegex.FindString(strings.TrimSpace(version))
+	if release == "" {
+		return nil, fmt.Errorf("%w: %q", ErrHAProxyVersionInvalid, version)
+	}
+
+	components := []int64{}
+
+	for _, c := range strings.Split(release, ".") {
+		n, err := strconv.ParseInt(c, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrHAProxyVersionInvalid, version)
+		}
+
+		components = append(components, n)
+	}
+
+	return components, nil
+}
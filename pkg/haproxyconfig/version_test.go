@@ -0,0 +1,100 @@
+package haproxyconfig
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.6.6-274d1a4 2022/09/22", "2.2", 1},
+		{"2.0.33", "2.2", -1},
+		{"2.2", "2.2.0", 0},
+		{"2.10.1", "2.9", 1},
+		{"1.8.30", "1.8", 1},
+	}
+
+	for _, tt := range tests {
+		cmp, err := CompareVersions(tt.a, tt.b)
+		require.NoError(t, err)
+
+		assert.Equal(t, tt.expected, cmp, "%s compared to %s", tt.a, tt.b)
+	}
+
+	_, err := CompareVersions("dev", "2.2")
+	assert.ErrorIs(t, err, ErrHAProxyVersionInvalid)
+}
+
+func TestUnsupportedFeatures(t *testing.T) {
+	settings := Settings{
+		MetricsPort: 29783,
+		Ports:       []PortSettings{{ID: "loadprt-test", CrtList: "/etc/haproxy/crt-list.txt", TLS: TLSSettings{MinVersion: "TLSv1.2"}}},
+		Pools: []PoolSettings{
+			{ID: "loadpol-test", HTTPCheck: HTTPCheckSettings{Path: "/healthz"}},
+			{ID: "loadpol-test2", RetryOn: []string{"conn-failure"}, SlowStart: time.Second},
+		},
+	}
+
+	t.Run("supported", func(t *testing.T) {
+		unsupported, err := UnsupportedFeatures(settings, "2.6.6-274d1a4 2022/09/22")
+		require.NoError(t, err)
+
+		assert.Empty(t, unsupported)
+	})
+
+	t.Run("older version", func(t *testing.T) {
+		unsupported, err := UnsupportedFeatures(settings, "2.0.33")
+		require.NoError(t, err)
+
+		assert.Equal(t, []VersionRequirement{{Feature: "http-check send", MinVersion: "2.2"}}, unsupported)
+	})
+
+	t.Run("much older version", func(t *testing.T) {
+		unsupported, err := UnsupportedFeatures(settings, "1.8.30")
+		require.NoError(t, err)
+
+		assert.Equal(t, []VersionRequirement{
+			{Feature: "http-check send", MinVersion: "2.2"},
+			{Feature: "prometheus-exporter", MinVersion: "2.0"},
+			{Feature: "retry-on", MinVersion: "2.0"},
+		}, unsupported)
+	})
+
+	t.Run("removed features", func(t *testing.T) {
+		multiProcess := Settings{
+			Global: GlobalSettings{NbProc: 4},
+			Ports:  []PortSettings{{ID: "loadprt-test", Process: "odd"}},
+		}
+
+		unsupported, err := UnsupportedFeatures(multiProcess, "2.4.22")
+		require.NoError(t, err)
+
+		assert.Empty(t, unsupported)
+
+		unsupported, err = UnsupportedFeatures(multiProcess, "2.5.0")
+		require.NoError(t, err)
+
+		assert.Equal(t, []VersionRequirement{
+			{Feature: "bind process", RemovedIn: "2.5"},
+			{Feature: "nbproc", RemovedIn: "2.5"},
+		}, unsupported)
+	})
+
+	t.Run("unused features", func(t *testing.T) {
+		unsupported, err := UnsupportedFeatures(Settings{}, "1.8.30")
+		require.NoError(t, err)
+
+		assert.Empty(t, unsupported)
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		_, err := UnsupportedFeatures(settings, "unknown")
+		assert.ErrorIs(t, err, ErrHAProxyVersionInvalid)
+	})
+}
@@ -0,0 +1,231 @@
+package lbapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	graphql "github.com/hasura/go-graphql-client"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	client "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+)
+
+// responseStatusRegex matches the status the graphql client reports a non-200 response with, e.g. `503 Service Unavailable; body: ...`
+var responseStatusRegex = regexp.MustCompile(`^([1-9]\d{2}) `)
+
+type loadBalancerGetter interface {
+	GetLoadBalancer(ctx context.Context, id string) (*client.LoadBalancer, error)
+}
+
+// Client is the loadbalancer api client. After a number of consecutive failures it stops calling
+// the api for a cooldown period, failing fast so event messages are NAK'd without piling up.
+type Client struct {
+	client           loadBalancerGetter
+	httpClient       *http.Client
+	userAgent        string
+	logger           *zap.SugaredLogger
+	failureThreshold int
+	cooldown         time.Duration
+	lenient          bool
+	now              func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Option configures a client option.
+type Option func(c *Client)
+
+// NewClient returns a loadbalancer api client
+func NewClient(url string, options ...Option) *Client {
+	c := &Client{
+		logger:           zap.NewNop().Sugar(),
+		userAgent:        defaultUserAgent(),
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultCooldown,
+		now:              time.Now,
+	}
+
+	for _, opt := range options {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{}
+	}
+
+	httpClient := withHeaders(c.httpClient, c.userAgent)
+
+	if c.lenient {
+		c.client = &lenientGetter{gql: graphql.NewClient(url, httpClient), logger: c.logger}
+	} else {
+		c.client = client.NewClient(url, client.WithHTTPClient(httpClient))
+	}
+
+	return c
+}
+
+// WithHTTPClient sets the http client used to call the loadbalancer api
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the user agent sent with requests, by default the service name and version
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLogger sets the logger for the client
+func WithLogger(logger *zap.SugaredLogger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failures which open the circuit breaker.
+// A threshold of 0 disables the circuit breaker.
+func WithFailureThreshold(threshold int) Option {
+	return func(c *Client) {
+		c.failureThreshold = threshold
+	}
+}
+
+// WithCooldown sets how long the circuit breaker stays open before the api is called again
+func WithCooldown(cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.cooldown = cooldown
+	}
+}
+
+// WithLenient sets whether loadbalancers the api resolved only partially are returned, logging the
+// errors for the fields it could not resolve as a warning, instead of failing
+func WithLenient(lenient bool) Option {
+	return func(c *Client) {
+		c.lenient = lenient
+	}
+}
+
+// GetLoadBalancer returns the loadbalancer with the given id, or ErrCircuitOpen while the
+// circuit breaker is open
+func (c *Client) GetLoadBalancer(ctx context.Context, id string) (*client.LoadBalancer, error) {
+	if c.circuitOpen() {
+		return nil, ErrCircuitOpen
+	}
+
+	lb, err := c.client.GetLoadBalancer(ctx, id)
+
+	// errors of the request rather than the api leave the breaker as is
+	if err != nil && !apiFailure(ctx, err) {
+		return lb, err
+	}
+
+	c.record(err)
+
+	return lb, err
+}
+
+// circuitOpen returns true while the cooldown after reaching the failure threshold has not passed
+func (c *Client) circuitOpen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now().Before(c.openUntil)
+}
+
+// record tracks consecutive failures, opening the circuit breaker when the threshold is reached.
+// Once the cooldown passes a single failure opens it again, until a call succeeds.
+func (c *Client) record(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		if c.failures >= c.failureThreshold && c.failureThreshold > 0 {
+			c.logger.Info("loadbalancer api circuit breaker closed")
+		}
+
+		c.failures = 0
+
+		return
+	}
+
+	c.failures++
+
+	if c.failureThreshold > 0 && c.failures >= c.failureThreshold {
+		c.openUntil = c.now().Add(c.cooldown)
+
+		c.logger.Warnw("loadbalancer api circuit breaker opened",
+			"consecutiveFailures", c.failures,
+			"cooldown", c.cooldown,
+			"error", err)
+	}
+}
+
+// apiFailure returns true when the error shows the api is unavailable: transport errors, 5xx
+// responses and responses which aren't graphql. Errors of the request, such as an unknown,
+// invalid or forbidden loadbalancer id, and the caller's context ending are not api failures.
+func apiFailure(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var invalidID *gidx.ErrInvalidID
+
+	if errors.Is(err, client.ErrLBNotfound) ||
+		errors.Is(err, client.ErrUnauthorized) ||
+		errors.Is(err, client.ErrPermissionDenied) ||
+		errors.As(err, &invalidID) {
+		return false
+	}
+
+	var gqlErrs graphql.Errors
+
+	if !errors.As(err, &gqlErrs) {
+		return true
+	}
+
+	for _, gqlErr := range gqlErrs {
+		if graphqlAPIFailure(gqlErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// graphqlAPIFailure returns true for the errors the graphql client reports when the request
+// failed or the response couldn't be decoded, except 4xx responses. Errors in the response's
+// errors array were returned by the api, so it is available.
+func graphqlAPIFailure(gqlErr graphql.Error) bool {
+	code, _ := gqlErr.Extensions["code"].(string)
+
+	switch code {
+	case graphql.ErrRequestError:
+		if match := responseStatusRegex.FindStringSubmatch(gqlErr.Message); match != nil {
+			status, _ := strconv.Atoi(match[1])
+
+			return status >= http.StatusInternalServerError
+		}
+
+		return true
+	case graphql.ErrJsonDecode:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,210 @@
+package lbapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	client "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+var errTestLBAPI = errors.New("lbapi unavailable")
+
+type fakeGetter struct {
+	calls int
+	err   error
+}
+
+func (f *fakeGetter) GetLoadBalancer(ctx context.Context, id string) (*client.LoadBalancer, error) {
+	f.calls++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &client.LoadBalancer{ID: id}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	getter := &fakeGetter{err: errTestLBAPI}
+
+	c := &Client{
+		client:           getter,
+		logger:           zap.NewNop().Sugar(),
+		failureThreshold: 3,
+		cooldown:         time.Minute,
+		now:              func() time.Time { return now },
+	}
+
+	// failures below the threshold call the api
+	for i := 0; i < 3; i++ {
+		_, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+		require.ErrorIs(t, err, errTestLBAPI)
+	}
+
+	assert.Equal(t, 3, getter.calls)
+
+	// threshold reached, breaker is open
+	_, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, getter.calls)
+
+	// cooldown passed, a failure opens the breaker again
+	now = now.Add(time.Minute)
+
+	_, err = c.GetLoadBalancer(context.Background(), "loadbal-test")
+	require.ErrorIs(t, err, errTestLBAPI)
+	assert.Equal(t, 4, getter.calls)
+
+	_, err = c.GetLoadBalancer(context.Background(), "loadbal-test")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	// cooldown passed, a success closes the breaker
+	now = now.Add(time.Minute)
+	getter.err = nil
+
+	lb, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+	require.NoError(t, err)
+	assert.Equal(t, "loadbal-test", lb.ID)
+
+	getter.err = errTestLBAPI
+
+	_, err = c.GetLoadBalancer(context.Background(), "loadbal-test")
+	require.ErrorIs(t, err, errTestLBAPI)
+	assert.Equal(t, 6, getter.calls)
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	getter := &fakeGetter{err: errTestLBAPI}
+
+	c := &Client{
+		client:           getter,
+		logger:           zap.NewNop().Sugar(),
+		failureThreshold: 0,
+		cooldown:         time.Minute,
+		now:              time.Now,
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+		require.ErrorIs(t, err, errTestLBAPI)
+	}
+
+	assert.Equal(t, 10, getter.calls)
+}
+
+func TestCircuitBreakerRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    error
+		wantCalls  int
+		wantOpened bool
+	}{
+		{
+			name:      "not found",
+			status:    http.StatusOK,
+			body:      `{"errors":[{"message":"load_balancer not found"}]}`,
+			wantErr:   client.ErrLBNotfound,
+			wantCalls: 5,
+		},
+		{
+			name:      "permission denied",
+			status:    http.StatusOK,
+			body:      `{"errors":[{"message":"subject doesn't have access"}]}`,
+			wantErr:   client.ErrPermissionDenied,
+			wantCalls: 5,
+		},
+		{
+			name:      "client error response",
+			status:    http.StatusBadRequest,
+			body:      `bad request`,
+			wantCalls: 5,
+		},
+		{
+			name:       "server error response",
+			status:     http.StatusServiceUnavailable,
+			body:       `unavailable`,
+			wantCalls:  2,
+			wantOpened: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, WithFailureThreshold(2))
+
+			for i := 0; i < 5; i++ {
+				_, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+				require.Error(t, err)
+
+				if tt.wantErr != nil {
+					assert.ErrorIs(t, err, tt.wantErr)
+				}
+			}
+
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantOpened, c.circuitOpen())
+		})
+	}
+
+	t.Run("invalid id", func(t *testing.T) {
+		c := NewClient("http://127.0.0.1:0", WithFailureThreshold(2))
+
+		for i := 0; i < 5; i++ {
+			_, err := c.GetLoadBalancer(context.Background(), "invalid")
+			require.Error(t, err)
+		}
+
+		assert.False(t, c.circuitOpen())
+	})
+
+	t.Run("context ended", func(t *testing.T) {
+		getter := &fakeGetter{err: context.DeadlineExceeded}
+
+		c := &Client{
+			client:           getter,
+			logger:           zap.NewNop().Sugar(),
+			failureThreshold: 2,
+			cooldown:         time.Minute,
+			now:              time.Now,
+		}
+
+		for i := 0; i < 5; i++ {
+			_, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		getter.err = errTestLBAPI
+
+		for i := 0; i < 5; i++ {
+			_, err := c.GetLoadBalancer(ctx, "loadbal-test")
+			require.ErrorIs(t, err, errTestLBAPI)
+		}
+
+		assert.Equal(t, 10, getter.calls)
+		assert.False(t, c.circuitOpen())
+	})
+}
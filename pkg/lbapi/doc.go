@@ -0,0 +1,3 @@
+// Package lbapi provides the loadbalancer api client used by the manager, wrapping the
+// load-balancer-api graphql client with a circuit breaker and tracing headers
+package lbapi
@@ -0,0 +1,8 @@
+package lbapi
+
+import "errors"
+
+var (
+	// ErrCircuitOpen is returned without calling the loadbalancer api while the circuit breaker is open
+	ErrCircuitOpen = errors.New("loadbalancer api circuit breaker is open")
+)
@@ -0,0 +1,83 @@
+package lbapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	graphql "github.com/hasura/go-graphql-client"
+	"go.infratographer.com/x/gidx"
+	"go.uber.org/zap"
+
+	client "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+// graphqlQuerier runs graphql queries, decoding the response data into the query even when the
+// response also carries errors
+type graphqlQuerier interface {
+	Query(ctx context.Context, q interface{}, variables map[string]interface{}, options ...graphql.Option) error
+}
+
+// lenientGetter gets loadbalancers, returning those the api resolved only partially along with
+// graphql errors for the fields it could not resolve
+type lenientGetter struct {
+	gql    graphqlQuerier
+	logger *zap.SugaredLogger
+}
+
+// GetLoadBalancer returns the loadbalancer with the given id. A response with errors but a
+// resolved loadbalancer is logged as a warning and returned, any other error is returned as is.
+func (g *lenientGetter) GetLoadBalancer(ctx context.Context, id string) (*client.LoadBalancer, error) {
+	if _, err := gidx.Parse(id); err != nil {
+		return nil, err
+	}
+
+	vars := map[string]interface{}{
+		"id": graphql.ID(id),
+	}
+
+	var q client.GetLoadBalancer
+
+	err := g.gql.Query(ctx, &q, vars)
+	if err == nil {
+		return &q.LoadBalancer, nil
+	}
+
+	if !partial(&q.LoadBalancer, err) {
+		return nil, translateErr(err)
+	}
+
+	g.logger.Warnw("loadbalancer api returned partial data",
+		"loadBalancerID", id,
+		"error", err)
+
+	return &q.LoadBalancer, nil
+}
+
+// partial returns true when the query error only carries graphql errors for fields of a
+// loadbalancer which was itself resolved. Transport errors, or errors leaving the loadbalancer
+// unresolved, such as it not being found, are fatal.
+func partial(lb *client.LoadBalancer, err error) bool {
+	var gqlErrs graphql.Errors
+
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) == 0 {
+		return false
+	}
+
+	return lb.ID != ""
+}
+
+// translateErr maps fatal graphql errors to the load-balancer-api client errors, as the client
+// does for the queries it runs
+func translateErr(err error) error {
+	switch {
+	case strings.Contains(err.Error(), "load_balancer not found"):
+		return client.ErrLBNotfound
+	case strings.Contains(err.Error(), "invalid or expired jwt"):
+		return client.ErrUnauthorized
+	case strings.Contains(err.Error(), "subject doesn't have access"):
+		return client.ErrPermissionDenied
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package lbapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	graphql "github.com/hasura/go-graphql-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	client "go.infratographer.com/load-balancer-api/pkg/client"
+)
+
+func TestLenientGetLoadBalancer(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        string
+		lenient     bool
+		expectErr   bool
+		expectedErr error
+		expectedLB  bool
+	}{
+		{
+			name:       "complete",
+			resp:       `{"data":{"loadBalancer":{"id":"loadbal-test","name":"lb-test"}}}`,
+			lenient:    true,
+			expectedLB: true,
+		},
+		{
+			name:       "partial data",
+			resp:       `{"data":{"loadBalancer":{"id":"loadbal-test","name":"lb-test"}},"errors":[{"message":"location unavailable","path":["loadBalancer","location"]}]}`,
+			lenient:    true,
+			expectedLB: true,
+		},
+		{
+			name:        "not found",
+			resp:        `{"data":null,"errors":[{"message":"load_balancer not found","path":["loadBalancer"]}]}`,
+			lenient:     true,
+			expectErr:   true,
+			expectedErr: client.ErrLBNotfound,
+		},
+		{
+			name:      "partial data without lenient",
+			resp:      `{"data":{"loadBalancer":{"id":"loadbal-test","name":"lb-test"}},"errors":[{"message":"location unavailable","path":["loadBalancer","location"]}]}`,
+			lenient:   false,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.resp))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, WithLenient(tt.lenient))
+
+			lb, err := c.GetLoadBalancer(context.Background(), "loadbal-test")
+
+			if tt.expectErr {
+				require.Error(t, err)
+
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !tt.expectedLB {
+				assert.Nil(t, lb)
+				return
+			}
+
+			require.NotNil(t, lb)
+			assert.Equal(t, "loadbal-test", lb.ID)
+			assert.Equal(t, "lb-test", lb.Name)
+		})
+	}
+}
+
+func TestPartial(t *testing.T) {
+	gqlErrs := graphql.Errors{{Message: "location unavailable"}}
+
+	assert.True(t, partial(&client.LoadBalancer{ID: "loadbal-test"}, gqlErrs))
+	assert.False(t, partial(&client.LoadBalancer{}, gqlErrs))
+	assert.False(t, partial(&client.LoadBalancer{ID: "loadbal-test"}, errTestLBAPI))
+	assert.False(t, partial(&client.LoadBalancer{ID: "loadbal-test"}, graphql.Errors{}))
+}
@@ -0,0 +1,66 @@
+package lbapi
+
+import (
+	"context"
+	"net/http"
+
+	"go.infratographer.com/x/versionx"
+)
+
+const (
+	appName = "loadbalancer-manager-haproxy"
+
+	headerUserAgent = "User-Agent"
+	headerRequestID = "X-Request-ID"
+)
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context carrying the request id sent with loadbalancer api requests
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id carried by the context, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// defaultUserAgent returns the user agent identifying this service and its version
+func defaultUserAgent() string {
+	return appName + "/" + versionx.BuildDetails().Version
+}
+
+// headerTransport sets the user agent and request id headers on outgoing requests
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip sets the headers on a clone of the request and sends it with the base transport
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	req.Header.Set(headerUserAgent, t.userAgent)
+
+	if id := RequestIDFromContext(req.Context()); id != "" {
+		req.Header.Set(headerRequestID, id)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// withHeaders returns a copy of the http client which sets the user agent and request id headers
+func withHeaders(httpClient *http.Client, userAgent string) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	wrapped := *httpClient
+	wrapped.Transport = &headerTransport{base: base, userAgent: userAgent}
+
+	return &wrapped
+}
@@ -0,0 +1,55 @@
+package lbapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderTransport(t *testing.T) {
+	headers := make(chan http.Header, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"loadBalancer":{"id":"loadbal-test"}}}`))
+	}))
+	defer srv.Close()
+
+	t.Run("user agent and request id", func(t *testing.T) {
+		c := NewClient(srv.URL, WithUserAgent("loadbalancer-manager-haproxy/v1.2.3"))
+
+		_, _ = c.GetLoadBalancer(ContextWithRequestID(context.Background(), "msg-1234"), "loadbal-test")
+
+		h := <-headers
+		assert.Equal(t, "loadbalancer-manager-haproxy/v1.2.3", h.Get(headerUserAgent))
+		assert.Equal(t, "msg-1234", h.Get(headerRequestID))
+	})
+
+	t.Run("default user agent without request id", func(t *testing.T) {
+		c := NewClient(srv.URL)
+
+		_, _ = c.GetLoadBalancer(context.Background(), "loadbal-test")
+
+		h := <-headers
+		assert.Equal(t, defaultUserAgent(), h.Get(headerUserAgent))
+		assert.Empty(t, h.Get(headerRequestID))
+	})
+}
+
+func TestWithHeaders(t *testing.T) {
+	httpClient := &http.Client{}
+
+	wrapped := withHeaders(httpClient, "loadbalancer-manager-haproxy/v1.2.3")
+
+	require.IsType(t, &headerTransport{}, wrapped.Transport)
+	assert.Equal(t, http.DefaultTransport, wrapped.Transport.(*headerTransport).base)
+
+	// the original client is not modified
+	assert.Nil(t, httpClient.Transport)
+}